@@ -0,0 +1,638 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-form-app/internal/history"
+	"go-form-app/internal/i18n"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+// apiPrefix est le préfixe de la version courante de l'API REST
+const apiPrefix = "/api/v1"
+
+// Codes d'erreur stables retournés par l'API
+const (
+	ErrCodeInvalidJSON           = "invalid_json"
+	ErrCodeUnsupportedMediaType  = "unsupported_media_type"
+	ErrCodeMissingCSRFToken      = "missing_csrf_token"
+	ErrCodeInvalidUserID         = "invalid_user_id"
+	ErrCodeInvalidScript         = "invalid_script"
+	ErrCodeInvalidParameter      = "invalid_parameter"
+	ErrCodeExecutionFailed       = "execution_failed"
+	ErrCodeNotFound              = "not_found"
+	ErrCodeMethodNotAllowed      = "method_not_allowed"
+	ErrCodeInternal              = "internal_error"
+	ErrCodeInvalidToken          = "invalid_token"
+	ErrCodeForbiddenScript       = "forbidden_script"
+	ErrCodeInvalidCSV            = "invalid_csv"
+	ErrCodeBulkAlreadyStarted    = "bulk_already_started"
+	ErrCodeBulkNoValidRows       = "bulk_no_valid_rows"
+	ErrCodeQueueFull             = "queue_full"
+	ErrCodeUserBusy              = "user_busy"
+	ErrCodeInvalidIdempotencyKey = "invalid_idempotency_key"
+	ErrCodeIdempotencyKeyReused  = "idempotency_key_reused"
+	ErrCodeInvalidSchedule       = "invalid_schedule"
+	ErrCodeScheduleCompleted     = "schedule_completed"
+	ErrCodeDryRunUnsupported     = "dry_run_unsupported"
+	ErrCodeArtifactExpired       = "artifact_expired"
+)
+
+// APIError décrit une erreur de l'API avec un code exploitable par les machines
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIErrorResponse est l'enveloppe commune à toutes les erreurs de l'API
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// ScriptInfo décrit un script exécutable
+type ScriptInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// DryRun indique si le script accepte la simulation (dryRun)
+	DryRun bool `json:"dryRun"`
+}
+
+// ScriptListResponse liste les scripts autorisés
+type ScriptListResponse struct {
+	Scripts []ScriptInfo `json:"scripts"`
+}
+
+// ExecuteRequest est le corps JSON d'une demande d'exécution
+type ExecuteRequest struct {
+	Script string `json:"script"`
+	UserID string `json:"userId"`
+	Async  bool   `json:"async,omitempty"`
+	// DryRun demande une simulation, refusée si le script ne la déclare pas
+	DryRun bool `json:"dryRun,omitempty"`
+	// IdempotencyKey peut aussi être fournie dans l'en-tête Idempotency-Key
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// Execution décrit une exécution (job) et son résultat
+type Execution struct {
+	ID             string `json:"id"`
+	Script         string `json:"script"`
+	UserID         string `json:"userId"`
+	Operator       string `json:"operator"`
+	Source         string `json:"source"`
+	RequestID      string `json:"requestId,omitempty"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// DryRun marque une simulation : le script n'a appliqué aucune modification
+	DryRun bool   `json:"dryRun,omitempty"`
+	Status string `json:"status"`
+	// Events et Result sont émis par les scripts déclarant un protocole de résultat structuré
+	Events []scripts.Event `json:"events,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	// Redactions compte les valeurs masquées dans la sortie, les événements et le résultat
+	Redactions int `json:"redactions,omitempty"`
+	// Artifacts liste les fichiers déposés par le script et téléchargeables pendant la rétention
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// Workflow et Steps ne sont renseignés que pour une exécution de workflow
+	Workflow string          `json:"workflow,omitempty"`
+	Steps    []StepExecution `json:"steps,omitempty"`
+	// QueuePosition est la position dans la file d'attente tant que le statut vaut queued
+	QueuePosition int        `json:"queuePosition,omitempty"`
+	Success       bool       `json:"success"`
+	ExitCode      int        `json:"exitCode"`
+	Output        string     `json:"output,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+	DurationMs    int64      `json:"durationMs"`
+}
+
+// HistoryResponse liste les exécutions passées, de la plus récente à la plus ancienne
+type HistoryResponse struct {
+	Executions []Execution `json:"executions"`
+}
+
+// apiRoute décrit une route de l'API ; la même table sert au routage et à la
+// génération du document OpenAPI
+type apiRoute struct {
+	method      string
+	path        string
+	operationID string
+	summary     string
+	query       []apiParam
+	request     interface{}
+	response    interface{}
+	status      int
+	// requestMediaType et responseMediaType valent application/json si vides
+	requestMediaType  string
+	responseMediaType string
+	handle            func(w http.ResponseWriter, r *http.Request, params map[string]string)
+}
+
+// apiParam décrit un paramètre de requête documenté
+type apiParam struct {
+	name        string
+	kind        string
+	description string
+}
+
+// newAPIRoutes construit la table des routes de l'API v1, une seule fois dans NewHandlers
+func (h *Handlers) newAPIRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/scripts",
+			operationID: "listScripts",
+			summary:     "Liste les scripts autorisés",
+			response:    ScriptListResponse{},
+			status:      http.StatusOK,
+			handle:      h.apiListScripts,
+		},
+		{
+			method:      http.MethodPost,
+			path:        apiPrefix + "/executions",
+			operationID: "executeScript",
+			summary:     "Exécute un script pour un utilisateur (synchrone, ou asynchrone avec async=true) ; 409 si une opération est en cours pour l'utilisateur, 503 si la file d'attente est pleine",
+			request:     ExecuteRequest{},
+			response:    Execution{},
+			status:      http.StatusOK,
+			handle:      h.apiExecute,
+		},
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/jobs/{id}",
+			operationID: "getJob",
+			summary:     "Retourne l'état d'une exécution",
+			response:    Execution{},
+			status:      http.StatusOK,
+			handle:      h.apiGetJob,
+		},
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/jobs/{id}/artifacts",
+			operationID: "listArtifacts",
+			summary:     "Liste les artefacts déposés par le script d'une exécution",
+			response:    ArtifactListResponse{},
+			status:      http.StatusOK,
+			handle:      h.apiListArtifacts,
+		},
+		{
+			method:            http.MethodGet,
+			path:              apiPrefix + "/jobs/{id}/artifacts/{name}",
+			operationID:       "downloadArtifact",
+			summary:           "Télécharge un artefact d'une exécution ; 410 si la rétention a expiré",
+			responseMediaType: "application/octet-stream",
+			status:            http.StatusOK,
+			handle:            h.apiDownloadArtifact,
+		},
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/history",
+			operationID: "listHistory",
+			summary:     "Liste l'historique des exécutions",
+			query: []apiParam{
+				{name: "script", kind: "string", description: "Filtre sur le nom du script"},
+				{name: "userId", kind: "string", description: "Filtre sur l'utilisateur cible"},
+				{name: "idempotencyKey", kind: "string", description: "Filtre sur la clé d'idempotence de la demande"},
+				{name: "limit", kind: "integer", description: "Nombre maximal d'entrées (1-500, défaut 50)"},
+			},
+			response: HistoryResponse{},
+			status:   http.StatusOK,
+			handle:   h.apiListHistory,
+		},
+		{
+			method:           http.MethodPost,
+			path:             apiPrefix + "/bulk",
+			operationID:      "prepareBulk",
+			summary:          "Valide un fichier CSV (colonne userId et paramètres) et retourne la prévisualisation du lot",
+			request:          BulkUploadRequest{},
+			requestMediaType: "multipart/form-data",
+			response:         BulkBatch{},
+			status:           http.StatusCreated,
+			handle:           h.apiBulkPrepare,
+		},
+		{
+			method:      http.MethodPost,
+			path:        apiPrefix + "/bulk/{id}/start",
+			operationID: "startBulk",
+			summary:     "Lance l'exécution des lignes valides d'un lot prévisualisé",
+			response:    BulkBatch{},
+			status:      http.StatusAccepted,
+			handle:      h.apiBulkStart,
+		},
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/bulk/{id}",
+			operationID: "getBulk",
+			summary:     "Retourne l'état d'un lot et de chacune de ses lignes",
+			response:    BulkBatch{},
+			status:      http.StatusOK,
+			handle:      h.apiBulkGet,
+		},
+		{
+			method:            http.MethodGet,
+			path:              apiPrefix + "/bulk/{id}/report",
+			operationID:       "getBulkReport",
+			summary:           "Télécharge le rapport CSV par ligne d'un lot",
+			responseMediaType: "text/csv",
+			status:            http.StatusOK,
+			handle:            h.apiBulkReport,
+		},
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/schedules",
+			operationID: "listSchedules",
+			summary:     "Liste les planifications",
+			response:    ScheduleListResponse{},
+			status:      http.StatusOK,
+			handle:      h.apiListSchedules,
+		},
+		{
+			method:      http.MethodPost,
+			path:        apiPrefix + "/schedules",
+			operationID: "createSchedule",
+			summary:     "Planifie un script pour un utilisateur, à une date (runAt) ou selon une expression cron",
+			request:     ScheduleRequest{},
+			response:    Schedule{},
+			status:      http.StatusCreated,
+			handle:      h.apiCreateSchedule,
+		},
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/schedules/{id}",
+			operationID: "getSchedule",
+			summary:     "Retourne une planification et son dernier déclenchement",
+			response:    Schedule{},
+			status:      http.StatusOK,
+			handle:      h.apiGetSchedule,
+		},
+		{
+			method:      http.MethodDelete,
+			path:        apiPrefix + "/schedules/{id}",
+			operationID: "deleteSchedule",
+			summary:     "Supprime une planification (les exécutions passées restent dans l'historique)",
+			status:      http.StatusNoContent,
+			handle:      h.apiDeleteSchedule,
+		},
+		{
+			method:      http.MethodPost,
+			path:        apiPrefix + "/schedules/{id}/pause",
+			operationID: "pauseSchedule",
+			summary:     "Suspend une planification",
+			response:    Schedule{},
+			status:      http.StatusOK,
+			handle:      h.apiPauseSchedule,
+		},
+		{
+			method:      http.MethodPost,
+			path:        apiPrefix + "/schedules/{id}/resume",
+			operationID: "resumeSchedule",
+			summary:     "Réactive une planification suspendue",
+			response:    Schedule{},
+			status:      http.StatusOK,
+			handle:      h.apiResumeSchedule,
+		},
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/workflows",
+			operationID: "listWorkflows",
+			summary:     "Liste les workflows configurés et leurs étapes",
+			response:    WorkflowListResponse{},
+			status:      http.StatusOK,
+			handle:      h.apiListWorkflows,
+		},
+		{
+			method:      http.MethodPost,
+			path:        apiPrefix + "/workflows/{name}/executions",
+			operationID: "executeWorkflow",
+			summary:     "Démarre un workflow pour un utilisateur ; l'avancement des étapes se suit via /jobs/{id}",
+			request:     WorkflowExecuteRequest{},
+			response:    Execution{},
+			status:      http.StatusAccepted,
+			handle:      h.apiExecuteWorkflow,
+		},
+		{
+			method:      http.MethodGet,
+			path:        apiPrefix + "/openapi.json",
+			operationID: "getOpenAPI",
+			summary:     "Document OpenAPI 3 de l'API",
+			status:      http.StatusOK,
+			handle:      h.apiOpenAPI,
+		},
+	}
+}
+
+// APIHandler route les requêtes de l'API v1
+func (h *Handlers) APIHandler(w http.ResponseWriter, r *http.Request) {
+	pathMatched := false
+
+	for _, route := range h.apiRoutes {
+		params, ok := matchAPIPath(route.path, r.URL.Path)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+
+		if route.method == r.Method {
+			route.handle(w, r, params)
+			return
+		}
+	}
+
+	if pathMatched {
+		h.logSecurityEvent(r, "invalid_method", r.Method+" "+r.URL.Path)
+		h.sendAPIError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed)
+		return
+	}
+	h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+}
+
+// matchAPIPath compare un chemin à un modèle contenant des segments {param}
+func matchAPIPath(pattern, path string) (map[string]string, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(part, "{}")] = pathParts[i]
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// apiListScripts liste les scripts autorisés
+func (h *Handlers) apiListScripts(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	response := ScriptListResponse{Scripts: []ScriptInfo{}}
+	for _, name := range h.executor.AllowedScripts() {
+		response.Scripts = append(response.Scripts, ScriptInfo{
+			Name:   name,
+			Type:   string(h.executor.ScriptType(name)),
+			DryRun: h.executor.SupportsDryRun(name),
+		})
+	}
+	h.sendAPIJSON(w, http.StatusOK, response)
+}
+
+// apiExecute valide la demande JSON et lance l'exécution
+func (h *Handlers) apiExecute(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body ExecuteRequest
+	if !h.decodeAPIJSON(w, r, &body) {
+		return
+	}
+
+	body.UserID = strings.TrimSpace(body.UserID)
+	body.Script = strings.TrimSpace(body.Script)
+
+	if !h.validateUserID(body.UserID) {
+		h.logSecurityEvent(r, "invalid_user_id", body.UserID)
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidUserID)
+		return
+	}
+	if !h.validateScript(body.Script) {
+		h.logSecurityEvent(r, "invalid_script", body.Script)
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidScript)
+		return
+	}
+	if !h.authorizeExecution(w, r, body.Script) {
+		return
+	}
+	if body.DryRun && !h.executor.SupportsDryRun(body.Script) {
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeDryRunUnsupported)
+		return
+	}
+
+	idempotencyKey, ok := requestIdempotencyKey(r, body.IdempotencyKey)
+	if !ok {
+		h.logSecurityEvent(r, "invalid_idempotency_key", idempotencyKey)
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidIdempotencyKey)
+		return
+	}
+
+	h.logSecurityEvent(r, "script_execution_request",
+		fmt.Sprintf("user:%s script:%s async:%t dry_run:%t source:api", body.UserID, body.Script, body.Async, body.DryRun))
+
+	operator := getOperator(r)
+	req := scripts.ExecutionRequest{
+		UserID:           body.UserID,
+		Script:           body.Script,
+		Operator:         operator,
+		IdempotencyKey:   idempotencyKey,
+		IdempotencyScope: idempotencyScope(r, operator),
+		DryRun:           body.DryRun,
+	}
+
+	if body.Async {
+		rec, err := h.jobs.Start(r.Context(), req, jobs.SourceAPI)
+		if status, code, ok := executionRejection(w, err); ok {
+			h.sendAPIError(w, r, status, code)
+			return
+		}
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "script execution failed", logging.KeyCategory, logging.CategoryExecution, "error", err)
+			h.sendAPIError(w, r, http.StatusInternalServerError, ErrCodeExecutionFailed)
+			return
+		}
+		if rec.Replayed {
+			w.Header().Set(idempotentReplayedHeader, "true")
+		}
+		w.Header().Set("Location", apiPrefix+"/jobs/"+rec.ID)
+		h.sendAPIJSON(w, http.StatusAccepted, h.newJobExecution(rec))
+		return
+	}
+
+	rec, err := h.jobs.Run(r.Context(), req, jobs.SourceAPI)
+	if status, code, ok := executionRejection(w, err); ok {
+		h.sendAPIError(w, r, status, code)
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "script execution failed", logging.KeyCategory, logging.CategoryExecution, "error", err)
+		h.sendAPIError(w, r, http.StatusInternalServerError, ErrCodeExecutionFailed)
+		return
+	}
+
+	if rec.Replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+		if !rec.Status.Finished() {
+			// Demande répétée pendant l'exécution d'origine : le job en cours est retourné
+			w.Header().Set("Location", apiPrefix+"/jobs/"+rec.ID)
+			h.sendAPIJSON(w, http.StatusAccepted, h.newJobExecution(rec))
+			return
+		}
+	}
+
+	h.logSecurityEvent(r, "script_execution_completed",
+		fmt.Sprintf("user:%s script:%s success:%t duration:%v exit_code:%d",
+			rec.UserID, rec.Script, rec.Success, rec.Duration, rec.ExitCode))
+
+	h.sendAPIJSON(w, http.StatusOK, newExecution(rec))
+}
+
+// apiGetJob retourne l'état d'une exécution
+func (h *Handlers) apiGetJob(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	rec, ok := h.scopedExecution(w, r, params["id"])
+	if !ok {
+		return
+	}
+	h.sendAPIJSON(w, http.StatusOK, h.newJobExecution(rec))
+}
+
+// scopedExecution retrouve l'exécution et vérifie que le token d'API éventuel couvre
+// ses scripts ; en cas d'échec la réponse d'erreur est déjà envoyée
+func (h *Handlers) scopedExecution(w http.ResponseWriter, r *http.Request, id string) (history.Record, bool) {
+	rec, ok := h.jobs.Store().Get(id)
+	if !ok {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return history.Record{}, false
+	}
+	if !h.checkTokenScope(w, r, rec.Scripts()...) {
+		return history.Record{}, false
+	}
+	return rec, true
+}
+
+// apiListHistory liste l'historique filtré des exécutions
+func (h *Handlers) apiListHistory(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	filter := history.Filter{
+		Script:         r.URL.Query().Get("script"),
+		UserID:         r.URL.Query().Get("userId"),
+		IdempotencyKey: r.URL.Query().Get("idempotencyKey"),
+		Limit:          50,
+	}
+
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 500 {
+			h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidParameter, "limit", 1, 500)
+			return
+		}
+		filter.Limit = limit
+	}
+	// Un token d'API ne voit que les exécutions des scripts qu'il couvre
+	if token, hasToken := getAPIToken(r); hasToken {
+		filter.Allowed = func(rec history.Record) bool { return tokenCovers(token, rec.Scripts()...) }
+	}
+
+	response := HistoryResponse{Executions: []Execution{}}
+	for _, rec := range h.jobs.Store().List(filter) {
+		response.Executions = append(response.Executions, newExecution(rec))
+	}
+	h.sendAPIJSON(w, http.StatusOK, response)
+}
+
+// apiOpenAPI sert le document OpenAPI généré à partir de la table des routes
+func (h *Handlers) apiOpenAPI(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	h.openAPIOnce.Do(func() {
+		spec, err := json.MarshalIndent(buildOpenAPISpec(h.apiRoutes), "", "  ")
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "OpenAPI generation failed", "error", err)
+			return
+		}
+		h.openAPISpec = spec
+	})
+
+	if h.openAPISpec == nil {
+		h.sendAPIError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.openAPISpec)
+}
+
+// newExecution convertit un enregistrement d'historique en réponse d'API
+func newExecution(rec history.Record) Execution {
+	execution := Execution{
+		ID:             rec.ID,
+		Script:         rec.Script,
+		UserID:         rec.UserID,
+		Operator:       rec.Operator,
+		Source:         rec.Source,
+		RequestID:      rec.RequestID,
+		IdempotencyKey: rec.IdempotencyKey,
+		DryRun:         rec.DryRun,
+		Status:         string(rec.Status),
+		Events:         rec.Events,
+		Result:         rec.Result,
+		Redactions:     rec.Redactions,
+		Artifacts:      newArtifacts(rec),
+		Workflow:       rec.Workflow,
+		Steps:          newStepExecutions(rec.Steps),
+		Success:        rec.Success,
+		ExitCode:       rec.ExitCode,
+		Output:         rec.Output,
+		Error:          rec.Error,
+		CreatedAt:      rec.CreatedAt,
+		DurationMs:     rec.Duration.Milliseconds(),
+	}
+	if !rec.FinishedAt.IsZero() {
+		finishedAt := rec.FinishedAt
+		execution.FinishedAt = &finishedAt
+	}
+	return execution
+}
+
+// newJobExecution complète la réponse d'une exécution avec sa position actuelle dans la file
+func (h *Handlers) newJobExecution(rec history.Record) Execution {
+	execution := newExecution(rec)
+	if rec.Status == history.StatusQueued {
+		execution.QueuePosition = h.jobs.QueuePosition(rec.ID)
+	}
+	return execution
+}
+
+// decodeAPIJSON décode un corps JSON strict ; en cas d'échec la réponse d'erreur est déjà envoyée
+func (h *Handlers) decodeAPIJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		h.sendAPIError(w, r, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType)
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB max
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		h.logSecurityEvent(r, "json_parse_error", err.Error())
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidJSON)
+		return false
+	}
+	return true
+}
+
+// sendAPIJSON envoie une réponse JSON typée avec le code HTTP donné
+func (h *Handlers) sendAPIJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("JSON encoding failed", "error", err)
+	}
+}
+
+// sendAPIError envoie une erreur dans l'enveloppe commune de l'API, message traduit selon le code
+func (h *Handlers) sendAPIError(w http.ResponseWriter, r *http.Request, statusCode int, code string, args ...any) {
+	lang := requestLanguage(r)
+	setLanguageHeaders(w, lang)
+	h.sendAPIJSON(w, statusCode, APIErrorResponse{
+		Error: APIError{Code: code, Message: i18n.Default.T(lang, "error."+code, args...)},
+	})
+}
@@ -0,0 +1,439 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+// newTestAPIHandlers crée des handlers pointant vers un script bash temporaire
+func newTestAPIHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	return newTestAPIHandlersWith(t, nil)
+}
+
+// newTestAPIHandlersWith crée des handlers limités à grant.sh dans un répertoire de
+// scripts temporaire, après application de configure à la configuration
+func newTestAPIHandlersWith(t *testing.T, configure func(cfg *config.Config)) *Handlers {
+	t.Helper()
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "bash"), 0o755)
+	os.WriteFile(filepath.Join(dir, "bash", "grant.sh"), []byte("echo granted $1"), 0o755)
+
+	cfg := config.Default()
+	cfg.Scripts = config.ScriptsConfig{
+		Dir:              dir,
+		AllowedScripts:   []string{"grant.sh"},
+		MaxExecutionTime: 5 * time.Second,
+		UserIDPattern:    config.DefaultUserIDPattern,
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+	return newTestHandlers(t, cfg, logging.New(os.Stdout, slog.LevelDebug))
+}
+
+// doAPIRequest envoie une requête à l'API et retourne la réponse enregistrée
+func doAPIRequest(h *Handlers, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	h.APIHandler(w, req)
+	return w
+}
+
+func TestMatchAPIPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		ok      bool
+		id      string
+	}{
+		{"/api/v1/scripts", "/api/v1/scripts", true, ""},
+		{"/api/v1/scripts", "/api/v1/scripts/", true, ""},
+		{"/api/v1/jobs/{id}", "/api/v1/jobs/abc", true, "abc"},
+		{"/api/v1/jobs/{id}", "/api/v1/jobs/", false, ""},
+		{"/api/v1/jobs/{id}", "/api/v1/jobs/abc/extra", false, ""},
+		{"/api/v1/history", "/api/v1/scripts", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			params, ok := matchAPIPath(tt.pattern, tt.path)
+			if ok != tt.ok {
+				t.Fatalf("matchAPIPath(%s, %s) ok = %v, want %v", tt.pattern, tt.path, ok, tt.ok)
+			}
+			if ok && params["id"] != tt.id {
+				t.Errorf("matchAPIPath() id = %s, want %s", params["id"], tt.id)
+			}
+		})
+	}
+}
+
+func TestAPIListScripts(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	w := doAPIRequest(handlers, http.MethodGet, "/api/v1/scripts", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var response ScriptListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Scripts) != 1 || response.Scripts[0].Name != "grant.sh" || response.Scripts[0].Type != "bash" {
+		t.Errorf("scripts = %+v, want grant.sh (bash)", response.Scripts)
+	}
+}
+
+func TestAPIExecuteErrors(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	jsonHeaders := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"}
+
+	tests := []struct {
+		name         string
+		body         string
+		headers      map[string]string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"missing CSRF token", `{"script":"grant.sh","userId":"test123"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, ErrCodeMissingCSRFToken},
+		{"form body rejected", "script=grant.sh", map[string]string{"Content-Type": "application/x-www-form-urlencoded", "X-CSRF-Token": "token"}, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType},
+		{"malformed JSON", `{"script":`, jsonHeaders, http.StatusBadRequest, ErrCodeInvalidJSON},
+		{"unknown field", `{"script":"grant.sh","userId":"test123","shell":"rm"}`, jsonHeaders, http.StatusBadRequest, ErrCodeInvalidJSON},
+		{"invalid user ID", `{"script":"grant.sh","userId":"x"}`, jsonHeaders, http.StatusBadRequest, ErrCodeInvalidUserID},
+		{"script not allowed", `{"script":"evil.sh","userId":"test123"}`, jsonHeaders, http.StatusBadRequest, ErrCodeInvalidScript},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", tt.body, tt.headers)
+			if w.Code != tt.expectedCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.expectedCode)
+			}
+
+			var response APIErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode error envelope: %v", err)
+			}
+			if response.Error.Code != tt.expectedErr {
+				t.Errorf("error code = %s, want %s", response.Error.Code, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestAPIExecuteAndHistory(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	headers := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"}
+
+	w := doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123"}`, headers)
+	if w.Code != http.StatusOK {
+		t.Fatalf("sync execute status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var execution Execution
+	json.Unmarshal(w.Body.Bytes(), &execution)
+	if execution.Status != string(history.StatusSucceeded) || execution.Output != "granted test123\n" || execution.FinishedAt == nil {
+		t.Errorf("sync execution = %+v", execution)
+	}
+
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test456","async":true}`, headers)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("async execute status = %d, want %d", w.Code, http.StatusAccepted)
+	}
+	var job Execution
+	json.Unmarshal(w.Body.Bytes(), &job)
+	if w.Header().Get("Location") != "/api/v1/jobs/"+job.ID {
+		t.Errorf("Location = %s, want /api/v1/jobs/%s", w.Header().Get("Location"), job.ID)
+	}
+
+	handlers.jobs.Wait()
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/"+job.ID, "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("job status = %d, want %d", w.Code, http.StatusOK)
+	}
+	json.Unmarshal(w.Body.Bytes(), &job)
+	if job.Status != string(history.StatusSucceeded) {
+		t.Errorf("job status = %s, want succeeded", job.Status)
+	}
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/unknown", "", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown job status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/history?userId=test456", "", nil)
+	var historyResponse HistoryResponse
+	json.Unmarshal(w.Body.Bytes(), &historyResponse)
+	if len(historyResponse.Executions) != 1 || historyResponse.Executions[0].ID != job.ID {
+		t.Errorf("history = %+v, want only the async job", historyResponse.Executions)
+	}
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/history?limit=0", "", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAPIExecuteQueue(t *testing.T) {
+	handlers := newTestAPIHandlersWith(t, func(cfg *config.Config) {
+		cfg.Scripts.Workers = 1
+		cfg.Scripts.QueueSize = 1
+	})
+	os.WriteFile(filepath.Join(handlers.executor.ScriptsDir(), "bash", "grant.sh"), []byte("sleep 0.3; echo granted $1"), 0o755)
+	headers := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"}
+
+	var jobs [2]Execution
+	for i, want := range []string{"running", "queued"} {
+		body := fmt.Sprintf(`{"script":"grant.sh","userId":"user%04d","async":true}`, i)
+		w := doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", body, headers)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("execute %d status = %d, want %d", i, w.Code, http.StatusAccepted)
+		}
+		json.Unmarshal(w.Body.Bytes(), &jobs[i])
+		if jobs[i].Status != want {
+			t.Errorf("execute %d status = %s, want %s", i, jobs[i].Status, want)
+		}
+	}
+
+	w := doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/"+jobs[1].ID, "", nil)
+	var queued Execution
+	json.Unmarshal(w.Body.Bytes(), &queued)
+	if queued.Status != "queued" || queued.QueuePosition != 1 {
+		t.Errorf("queued job = %s at position %d, want queued at position 1", queued.Status, queued.QueuePosition)
+	}
+
+	for _, body := range []string{`{"script":"grant.sh","userId":"user0002","async":true}`, `{"script":"grant.sh","userId":"user0003"}`} {
+		w = doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", body, headers)
+		var response APIErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusServiceUnavailable || response.Error.Code != ErrCodeQueueFull || w.Header().Get("Retry-After") == "" {
+			t.Errorf("execute with a full queue = %d %s (Retry-After %q), want 503 queue_full",
+				w.Code, response.Error.Code, w.Header().Get("Retry-After"))
+		}
+	}
+
+	handlers.jobs.Wait()
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/"+jobs[1].ID, "", nil)
+	var finished Execution
+	json.Unmarshal(w.Body.Bytes(), &finished)
+	if finished.Status != string(history.StatusSucceeded) || finished.QueuePosition != 0 {
+		t.Errorf("queued job after Wait() = %+v, want succeeded", finished)
+	}
+}
+
+func TestAPIExecuteStructuredResult(t *testing.T) {
+	handlers := newTestAPIHandlersWith(t, func(cfg *config.Config) {
+		cfg.Scripts.Settings = map[string]config.ScriptSettings{"grant.sh": {Protocol: config.ProtocolPrefix}}
+	})
+	os.WriteFile(filepath.Join(handlers.executor.ScriptsDir(), "bash", "grant.sh"), []byte(
+		`echo '::event::{"type":"progress","pct":100}'; echo '::event::{"type":"result","granted":["read_access"]}'; echo granted $1`), 0o755)
+
+	w := doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123"}`,
+		map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"})
+	var execution Execution
+	json.Unmarshal(w.Body.Bytes(), &execution)
+	if w.Code != http.StatusOK || execution.Output != "granted test123\n" || string(execution.Result) != `{"granted":["read_access"]}` {
+		t.Errorf("execute = %d %+v (result %s)", w.Code, execution, execution.Result)
+	}
+	if len(execution.Events) != 1 || execution.Events[0].Type != scripts.EventProgress {
+		t.Errorf("events = %+v, want one progress event", execution.Events)
+	}
+}
+
+func TestAPIExecuteDryRun(t *testing.T) {
+	unsupported := newTestAPIHandlers(t)
+	headers := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"}
+
+	w := doAPIRequest(unsupported, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123","dryRun":true}`, headers)
+	var response APIErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusBadRequest || response.Error.Code != ErrCodeDryRunUnsupported {
+		t.Fatalf("dry run without support = %d %s, want 400 %s", w.Code, response.Error.Code, ErrCodeDryRunUnsupported)
+	}
+	if records := unsupported.jobs.Store().List(history.Filter{}); len(records) != 0 {
+		t.Errorf("refused dry run recorded %d executions, want none", len(records))
+	}
+
+	handlers := newTestAPIHandlersWith(t, func(cfg *config.Config) {
+		cfg.Scripts.Settings = map[string]config.ScriptSettings{"grant.sh": {DryRun: true}}
+	})
+	os.WriteFile(filepath.Join(handlers.executor.ScriptsDir(), "bash", "grant.sh"), []byte(
+		`if [ "$DRY_RUN" = 1 ]; then echo would grant $1; else echo granted $1; fi`), 0o755)
+
+	var list ScriptListResponse
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/scripts", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Scripts) != 1 || !list.Scripts[0].DryRun {
+		t.Errorf("scripts = %+v, want grant.sh declared with dry run", list.Scripts)
+	}
+
+	var execution Execution
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123","dryRun":true}`, headers)
+	json.Unmarshal(w.Body.Bytes(), &execution)
+	if w.Code != http.StatusOK || !execution.DryRun || execution.Output != "would grant test123\n" {
+		t.Errorf("dry run = %d %+v, want a simulated execution", w.Code, execution)
+	}
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/"+execution.ID, "", nil)
+	var stored Execution
+	json.Unmarshal(w.Body.Bytes(), &stored)
+	if !stored.DryRun {
+		t.Errorf("history = %+v, want the execution marked as a dry run", stored)
+	}
+}
+
+func TestAPIExecuteRedactions(t *testing.T) {
+	handlers := newTestAPIHandlersWith(t, func(cfg *config.Config) {
+		cfg.Scripts.Redact = config.RedactConfig{Patterns: []string{`password=(\S+)`}}
+	})
+	os.WriteFile(filepath.Join(handlers.executor.ScriptsDir(), "bash", "grant.sh"), []byte(`echo granted $1 with password=hunter22`), 0o755)
+
+	w := doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123"}`,
+		map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"})
+	var execution Execution
+	json.Unmarshal(w.Body.Bytes(), &execution)
+	if execution.Output != "granted test123 with password=[REDACTED]\n" || execution.Redactions != 1 {
+		t.Errorf("execute = %d %+v, want the password redacted", w.Code, execution)
+	}
+
+	rec, _ := handlers.jobs.Store().Get(execution.ID)
+	if strings.Contains(rec.Output, "hunter22") || rec.Redactions != 1 {
+		t.Errorf("history record = %+v, want the redacted output", rec)
+	}
+}
+
+func TestExecuteUserBusy(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	os.WriteFile(filepath.Join(handlers.executor.ScriptsDir(), "bash", "grant.sh"), []byte("sleep 0.3; echo granted $1"), 0o755)
+	headers := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"}
+
+	w := doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123","async":true}`, headers)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("first execute status = %d, want %d", w.Code, http.StatusAccepted)
+	}
+
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123"}`, headers)
+	var response APIErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusConflict || response.Error.Code != ErrCodeUserBusy {
+		t.Errorf("API execute for a busy user = %d %s, want 409 user_busy", w.Code, response.Error.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/run-script", strings.NewReader("userId=test123&script=grant.sh"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-CSRF-Token", "token")
+	req.Header.Set("Accept-Language", "en")
+	rec := httptest.NewRecorder()
+	handlers.RunScriptHandler(rec, req)
+	var formResponse map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &formResponse)
+	if rec.Code != http.StatusConflict || formResponse["code"] != ErrCodeUserBusy || formResponse["message"] != "Another operation is in progress for this user" {
+		t.Errorf("form execute for a busy user = %d %v, want 409 user_busy", rec.Code, formResponse)
+	}
+
+	handlers.jobs.Wait()
+
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123"}`, headers)
+	if w.Code != http.StatusOK {
+		t.Errorf("execute after the conflicting job finished status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestAPIErrorMessagesAreLocalized(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	tests := []struct {
+		acceptLanguage string
+		expectedMsg    string
+	}{
+		{"", "limit doit être compris entre 1 et 500"},
+		{"en-GB", "limit must be between 1 and 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			w := doAPIRequest(handlers, http.MethodGet, "/api/v1/history?limit=0", "", map[string]string{"Accept-Language": tt.acceptLanguage})
+
+			var response APIErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode error envelope: %v", err)
+			}
+			if response.Error.Code != ErrCodeInvalidParameter {
+				t.Errorf("error code = %s, want %s", response.Error.Code, ErrCodeInvalidParameter)
+			}
+			if response.Error.Message != tt.expectedMsg {
+				t.Errorf("error message = %q, want %q", response.Error.Message, tt.expectedMsg)
+			}
+		})
+	}
+}
+
+func TestAPIRouting(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	w := doAPIRequest(handlers, http.MethodDelete, "/api/v1/scripts", "", nil)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("wrong method status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/unknown", "", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown route status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPIOpenAPIDocument(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	w := doAPIRequest(handlers, http.MethodGet, "/api/v1/openapi.json", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var spec map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid OpenAPI JSON: %v", err)
+	}
+	if spec["openapi"] != "3.0.3" {
+		t.Errorf("openapi version = %v", spec["openapi"])
+	}
+
+	paths := spec["paths"].(map[string]interface{})
+	for _, route := range handlers.apiRoutes {
+		item, ok := paths[route.path].(map[string]interface{})
+		if !ok {
+			t.Errorf("path %s missing from OpenAPI document", route.path)
+			continue
+		}
+		if _, ok := item[strings.ToLower(route.method)]; !ok {
+			t.Errorf("operation %s %s missing from OpenAPI document", route.method, route.path)
+		}
+	}
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, name := range []string{"APIErrorResponse", "APIError", "ExecuteRequest", "Execution", "HistoryResponse", "ScriptListResponse", "ScriptInfo", "BulkBatch", "BulkRow", "BulkUploadRequest"} {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("schema %s missing from OpenAPI document", name)
+		}
+	}
+}
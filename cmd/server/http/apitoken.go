@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-form-app/internal/auth"
+)
+
+type apiTokenContextKey struct{}
+
+// bearerToken extrait le secret de l'en-tête Authorization: Bearer
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", false
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", true
+	}
+	return strings.TrimSpace(token), true
+}
+
+// withAPIToken mémorise le token authentifié dans le contexte de la requête
+func withAPIToken(r *http.Request, token auth.Token) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), apiTokenContextKey{}, token))
+}
+
+// getAPIToken retourne le token d'API authentifié pour la requête, s'il existe
+func getAPIToken(r *http.Request) (auth.Token, bool) {
+	token, ok := r.Context().Value(apiTokenContextKey{}).(auth.Token)
+	return token, ok
+}
+
+// tokenCovers indique si le token couvre tous les scripts donnés ; les noms vides
+// (étape sans compensation) sont ignorés
+func tokenCovers(token auth.Token, scripts ...string) bool {
+	for _, script := range scripts {
+		if script != "" && !token.Allows(script) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTokenScope vérifie que le token d'API éventuel couvre les scripts ; une requête
+// sans token n'est pas restreinte. En cas d'échec la réponse d'erreur est déjà envoyée.
+func (h *Handlers) checkTokenScope(w http.ResponseWriter, r *http.Request, scripts ...string) bool {
+	token, hasToken := getAPIToken(r)
+	if !hasToken {
+		return true
+	}
+	for _, script := range scripts {
+		if script != "" && !token.Allows(script) {
+			h.logSecurityEvent(r, "token_scope_denied", fmt.Sprintf("token:%s script:%s", token.ID, script))
+			h.sendAPIError(w, r, http.StatusForbidden, ErrCodeForbiddenScript)
+			return false
+		}
+	}
+	return true
+}
+
+// authorizeExecution vérifie qu'une demande d'exécution porte un jeton CSRF, ou un token
+// d'API couvrant tous ses scripts ; en cas d'échec la réponse d'erreur est déjà envoyée
+func (h *Handlers) authorizeExecution(w http.ResponseWriter, r *http.Request, scripts ...string) bool {
+	if _, hasToken := getAPIToken(r); !hasToken && strings.TrimSpace(r.Header.Get("X-CSRF-Token")) == "" {
+		h.logSecurityEvent(r, "missing_csrf_token", "no token in headers")
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeMissingCSRFToken)
+		return false
+	}
+	return h.checkTokenScope(w, r, scripts...)
+}
+
+// authenticateAPIToken valide l'en-tête Authorization éventuel. Une requête sans
+// en-tête est acceptée telle quelle ; un token présent mais invalide est refusé.
+func (s *Server) authenticateAPIToken(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	secret, present := bearerToken(r)
+	if !present {
+		return r, true
+	}
+
+	if s.tokens == nil || secret == "" {
+		s.handlers.logSecurityEvent(r, "invalid_api_token", "API tokens not configured or malformed header")
+		s.rejectAPIToken(w, r)
+		return r, false
+	}
+
+	token, err := s.tokens.Authenticate(secret)
+	if err != nil {
+		s.handlers.logSecurityEvent(r, "invalid_api_token", err.Error())
+		s.rejectAPIToken(w, r)
+		return r, false
+	}
+
+	return withAPIToken(r, token), true
+}
+
+// rejectAPIToken répond 401 avec l'enveloppe d'erreur de l'API
+func (s *Server) rejectAPIToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="go-form-app"`)
+	s.handlers.sendAPIError(w, r, http.StatusUnauthorized, ErrCodeInvalidToken)
+}
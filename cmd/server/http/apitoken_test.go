@@ -0,0 +1,223 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-form-app/internal/auth"
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/logging"
+)
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		secret  string
+		present bool
+	}{
+		{"no header", "", "", false},
+		{"bearer", "Bearer gfa_abc_def", "gfa_abc_def", true},
+		{"case insensitive scheme", "bearer gfa_abc_def", "gfa_abc_def", true},
+		{"basic auth", "Basic dXNlcjpwYXNz", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			secret, present := bearerToken(req)
+			if secret != tt.secret || present != tt.present {
+				t.Errorf("bearerToken() = (%q, %v), want (%q, %v)", secret, present, tt.secret, tt.present)
+			}
+		})
+	}
+}
+
+func TestAPITokenAuthentication(t *testing.T) {
+	tokensFile := filepath.Join(t.TempDir(), "tokens.json")
+	server, err := NewServer(config.Config{
+		Scripts:       config.Default().Scripts,
+		APITokensFile: tokensFile,
+		RateLimit:     config.RateLimitConfig{RequestsPerSecond: 0.001, Burst: 1},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	handlers := newTestAPIHandlers(t)
+	handlers.bans = server.bans
+	server.handlers = handlers
+
+	secret, _, err := server.tokens.Issue("ticketing", auth.KindService, "", []string{"grant.sh"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	otherSecret, _, _ := server.tokens.Issue("reporting", auth.KindService, "", []string{"other.sh"}, time.Hour)
+
+	handler := server.securityMiddleware(http.HandlerFunc(handlers.APIHandler))
+	execute := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/executions", strings.NewReader(`{"script":"grant.sh","userId":"test123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := execute("Bearer " + secret)
+	if w.Code != http.StatusOK {
+		t.Fatalf("token execution status = %d, want %d without CSRF: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var execution Execution
+	json.Unmarshal(w.Body.Bytes(), &execution)
+	if execution.Operator != "token:ticketing" {
+		t.Errorf("operator = %s, want token:ticketing", execution.Operator)
+	}
+
+	w = execute("Bearer " + otherSecret)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("out-of-scope token status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = execute("Bearer gfa_0000_bad")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("invalid token status = %d, want %d with WWW-Authenticate", w.Code, http.StatusUnauthorized)
+	}
+
+	// Le token valide a consommé son burst : la limitation s'applique par token
+	w = execute("Bearer " + secret)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("rate limited token status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestAPITokenScopeOnReads(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	store := handlers.jobs.Store()
+	now := time.Now()
+	store.Save(history.Record{ID: "granted", Script: "grant.sh", UserID: "test123", Status: history.StatusSucceeded, CreatedAt: now})
+	store.Save(history.Record{ID: "other", Script: "other.sh", UserID: "test456", Status: history.StatusSucceeded, CreatedAt: now})
+	store.Save(history.Record{ID: "workflow", Workflow: "onboarding", UserID: "test789", Status: history.StatusSucceeded, CreatedAt: now,
+		Steps: []history.StepResult{{Name: "grant", Script: "grant.sh", RollbackScript: "revoke.sh"}}})
+
+	csrf := map[string]string{"X-CSRF-Token": "token"}
+	w := doBulkUpload(handlers, "grant.sh", "userId\ntest123\n", csrf)
+	var batch BulkBatch
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/schedules", `{"script":"grant.sh","userId":"test123","cron":"@daily"}`,
+		map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"})
+	var created Schedule
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if batch.ID == "" || created.ID == "" {
+		t.Fatalf("setup: batch %q, schedule %q", batch.ID, created.ID)
+	}
+
+	scoped := auth.Token{ID: "t1", Name: "ticketing", Scripts: []string{"grant.sh"}}
+	other := auth.Token{ID: "t2", Name: "reporting", Scripts: []string{"other.sh"}}
+
+	tests := []struct {
+		name         string
+		token        auth.Token
+		path         string
+		expectedCode int
+	}{
+		{"job in scope", scoped, "/api/v1/jobs/granted", http.StatusOK},
+		{"job out of scope", scoped, "/api/v1/jobs/other", http.StatusForbidden},
+		{"workflow with a rollback out of scope", scoped, "/api/v1/jobs/workflow", http.StatusForbidden},
+		{"bulk batch out of scope", other, "/api/v1/bulk/" + batch.ID, http.StatusForbidden},
+		{"bulk report out of scope", other, "/api/v1/bulk/" + batch.ID + "/report", http.StatusForbidden},
+		{"bulk report in scope", scoped, "/api/v1/bulk/" + batch.ID + "/report", http.StatusOK},
+		{"schedule out of scope", other, "/api/v1/schedules/" + created.ID, http.StatusForbidden},
+		{"schedule in scope", scoped, "/api/v1/schedules/" + created.ID, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doAuthenticatedRequest(handlers, tt.token, http.MethodGet, tt.path, "", nil)
+			if w.Code != tt.expectedCode {
+				t.Errorf("GET %s status = %d, want %d", tt.path, w.Code, tt.expectedCode)
+			}
+		})
+	}
+
+	var list HistoryResponse
+	w = doAuthenticatedRequest(handlers, scoped, http.MethodGet, "/api/v1/history", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Executions) != 1 || list.Executions[0].ID != "granted" {
+		t.Errorf("scoped history = %+v, want only the grant.sh execution", list.Executions)
+	}
+	w = doAuthenticatedRequest(handlers, scoped, http.MethodGet, "/api/v1/history?limit=1", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Executions) != 1 || list.Executions[0].ID != "granted" {
+		t.Errorf("scoped history with limit = %+v, want the limit applied after the scope", list.Executions)
+	}
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/history", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Executions) != 3 {
+		t.Errorf("history without token has %d executions, want 3", len(list.Executions))
+	}
+
+	var schedules ScheduleListResponse
+	w = doAuthenticatedRequest(handlers, other, http.MethodGet, "/api/v1/schedules", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &schedules)
+	if len(schedules.Schedules) != 0 {
+		t.Errorf("schedules out of scope = %+v, want none", schedules.Schedules)
+	}
+	w = doAuthenticatedRequest(handlers, scoped, http.MethodGet, "/api/v1/schedules", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &schedules)
+	if len(schedules.Schedules) != 1 {
+		t.Errorf("schedules in scope = %+v, want the grant.sh schedule", schedules.Schedules)
+	}
+}
+
+func TestAuthorizeExecution(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	scoped := auth.Token{ID: "t1", Name: "ticketing", Scripts: []string{"grant.sh"}}
+
+	tests := []struct {
+		name         string
+		token        *auth.Token
+		csrf         string
+		scripts      []string
+		expected     bool
+		expectedCode int
+		expectedErr  string
+	}{
+		{"missing CSRF token", nil, "", []string{"grant.sh"}, false, http.StatusBadRequest, ErrCodeMissingCSRFToken},
+		{"CSRF token", nil, "token", []string{"other.sh"}, true, http.StatusOK, ""},
+		{"token in scope", &scoped, "", []string{"grant.sh", ""}, true, http.StatusOK, ""},
+		{"token out of scope", &scoped, "", []string{"grant.sh", "revoke.sh"}, false, http.StatusForbidden, ErrCodeForbiddenScript},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/executions", nil)
+			if tt.csrf != "" {
+				req.Header.Set("X-CSRF-Token", tt.csrf)
+			}
+			if tt.token != nil {
+				req = withAPIToken(req, *tt.token)
+			}
+			w := httptest.NewRecorder()
+			if got := handlers.authorizeExecution(w, req, tt.scripts...); got != tt.expected {
+				t.Errorf("authorizeExecution() = %t, want %t", got, tt.expected)
+			}
+			var response APIErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != tt.expectedCode || response.Error.Code != tt.expectedErr {
+				t.Errorf("response = %d %s, want %d %s", w.Code, response.Error.Code, tt.expectedCode, tt.expectedErr)
+			}
+		})
+	}
+}
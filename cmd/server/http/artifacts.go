@@ -0,0 +1,90 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+
+	"go-form-app/internal/history"
+	"go-form-app/internal/scripts"
+)
+
+// Artifact décrit un fichier déposé par un script dans ARTIFACTS_DIR
+type Artifact struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	URL  string `json:"url"`
+}
+
+// ArtifactListResponse liste les artefacts d'une exécution
+type ArtifactListResponse struct {
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// apiListArtifacts liste les artefacts d'une exécution
+func (h *Handlers) apiListArtifacts(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	rec, ok := h.scopedExecution(w, r, params["id"])
+	if !ok {
+		return
+	}
+	response := ArtifactListResponse{Artifacts: newArtifacts(rec)}
+	if response.Artifacts == nil {
+		response.Artifacts = []Artifact{}
+	}
+	h.sendAPIJSON(w, http.StatusOK, response)
+}
+
+// apiDownloadArtifact envoie un artefact ; seuls les fichiers listés dans l'exécution
+// sont servis, toujours en pièce jointe pour qu'un navigateur ne les interprète pas
+func (h *Handlers) apiDownloadArtifact(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	rec, ok := h.scopedExecution(w, r, params["id"])
+	if !ok {
+		return
+	}
+	if !hasArtifact(rec, params["name"]) {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	}
+
+	file, info, err := scripts.OpenArtifact(rec.ArtifactsDir, params["name"])
+	if err != nil {
+		h.sendAPIError(w, r, http.StatusGone, ErrCodeArtifactExpired)
+		return
+	}
+	defer file.Close()
+
+	h.logSecurityEvent(r, "artifact_download", "execution:"+rec.ID+" artifact:"+params["name"])
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": params["name"]})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", disposition)
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// newArtifacts convertit les artefacts d'une exécution en réponse d'API
+func newArtifacts(rec history.Record) []Artifact {
+	if len(rec.Artifacts) == 0 {
+		return nil
+	}
+	artifacts := make([]Artifact, 0, len(rec.Artifacts))
+	for _, artifact := range rec.Artifacts {
+		artifacts = append(artifacts, Artifact{
+			Name: artifact.Name,
+			Size: artifact.Size,
+			URL:  apiPrefix + "/jobs/" + rec.ID + "/artifacts/" + url.PathEscape(artifact.Name),
+		})
+	}
+	return artifacts
+}
+
+// hasArtifact indique si l'exécution a conservé un artefact de ce nom
+func hasArtifact(rec history.Record, name string) bool {
+	for _, artifact := range rec.Artifacts {
+		if artifact.Name == name {
+			return true
+		}
+	}
+	return false
+}
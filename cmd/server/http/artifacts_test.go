@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-form-app/internal/auth"
+	"go-form-app/internal/config"
+)
+
+func TestArtifactsAPI(t *testing.T) {
+	handlers := newTestAPIHandlersWith(t, func(cfg *config.Config) {
+		cfg.Scripts.WorkDir = t.TempDir()
+		cfg.Scripts.ArtifactRetention = time.Hour
+	})
+	os.WriteFile(filepath.Join(handlers.executor.ScriptsDir(), "bash", "grant.sh"), []byte(
+		`echo "user,status" > "$ARTIFACTS_DIR/report.csv"; echo granted $1`), 0o755)
+
+	var execution Execution
+	w := doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123"}`,
+		map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"})
+	json.Unmarshal(w.Body.Bytes(), &execution)
+	wantURL := "/api/v1/jobs/" + execution.ID + "/artifacts/report.csv"
+	if len(execution.Artifacts) != 1 || execution.Artifacts[0].URL != wantURL || execution.Artifacts[0].Size != 12 {
+		t.Fatalf("execution artifacts = %+v, want report.csv", execution.Artifacts)
+	}
+
+	var list ArtifactListResponse
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/"+execution.ID+"/artifacts", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Artifacts) != 1 || list.Artifacts[0].Name != "report.csv" {
+		t.Errorf("list = %d %+v", w.Code, list)
+	}
+
+	w = doAPIRequest(handlers, http.MethodGet, wantURL, "", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "user,status\n" ||
+		w.Header().Get("Content-Disposition") != "attachment; filename=report.csv" || w.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("download = %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	rec, _ := handlers.jobs.Store().Get(execution.ID)
+	otherScope := &auth.Token{ID: "reporting", Scripts: []string{"other.sh"}}
+	tests := []struct {
+		name         string
+		path         string
+		token        *auth.Token
+		expectedCode int
+		expectedErr  string
+	}{
+		{"unknown execution", "/api/v1/jobs/missing/artifacts/report.csv", nil, http.StatusNotFound, ErrCodeNotFound},
+		{"unlisted file", "/api/v1/jobs/" + execution.ID + "/artifacts/notes.txt", nil, http.StatusNotFound, ErrCodeNotFound},
+		{"list out of token scope", "/api/v1/jobs/" + execution.ID + "/artifacts", otherScope, http.StatusForbidden, ErrCodeForbiddenScript},
+		{"download out of token scope", wantURL, otherScope, http.StatusForbidden, ErrCodeForbiddenScript},
+		{"retention expired", wantURL, nil, http.StatusGone, ErrCodeArtifactExpired},
+	}
+
+	os.RemoveAll(rec.ArtifactsDir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != nil {
+				req = withAPIToken(req, *tt.token)
+			}
+			w := httptest.NewRecorder()
+			handlers.APIHandler(w, req)
+			var response APIErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != tt.expectedCode || response.Error.Code != tt.expectedErr {
+				t.Errorf("download = %d %s, want %d %s", w.Code, response.Error.Code, tt.expectedCode, tt.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-form-app/internal/bulk"
+	"go-form-app/internal/logging"
+)
+
+// maxBulkUploadBytes borne la taille d'un fichier CSV d'exécution en masse
+const maxBulkUploadBytes = 2 << 20 // 2MB
+
+// BulkUploadRequest décrit le formulaire multipart d'envoi d'un fichier CSV
+type BulkUploadRequest struct {
+	Script string `json:"script"`
+	File   string `json:"file"`
+}
+
+// BulkRow décrit une ligne du fichier et le résultat de son exécution
+type BulkRow struct {
+	Line        int      `json:"line"`
+	UserID      string   `json:"userId"`
+	Arguments   []string `json:"arguments,omitempty"`
+	Status      string   `json:"status"`
+	Error       string   `json:"error,omitempty"`
+	ExecutionID string   `json:"executionId,omitempty"`
+	ExitCode    int      `json:"exitCode"`
+	DurationMs  int64    `json:"durationMs"`
+}
+
+// BulkBatch décrit un lot d'exécutions en masse
+type BulkBatch struct {
+	ID         string         `json:"id"`
+	Script     string         `json:"script"`
+	Operator   string         `json:"operator"`
+	Status     string         `json:"status"`
+	Columns    []string       `json:"columns"`
+	Summary    map[string]int `json:"summary"`
+	Rows       []BulkRow      `json:"rows"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	StartedAt  *time.Time     `json:"startedAt,omitempty"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+}
+
+// csvErrorMessage traduit le motif de rejet d'un fichier CSV dans la langue de la requête
+func (h *Handlers) csvErrorMessage(r *http.Request, err error) string {
+	var tooLarge *http.MaxBytesError
+	var malformed *csv.ParseError
+	switch {
+	case errors.As(err, &tooLarge):
+		return translate(r, "csv.too_large", tooLarge.Limit>>20)
+	case errors.Is(err, http.ErrNotMultipart), errors.Is(err, http.ErrMissingFile):
+		return translate(r, "csv.missing_file")
+	case errors.Is(err, bulk.ErrEmptyFile):
+		return translate(r, "csv.empty_file")
+	case errors.Is(err, bulk.ErrMissingUserID):
+		return translate(r, "csv.missing_user_id", bulk.UserIDColumn)
+	case errors.Is(err, bulk.ErrTooManyRows):
+		return translate(r, "csv.too_many_rows", h.bulk.MaxRows())
+	case errors.Is(err, bulk.ErrNoRows):
+		return translate(r, "csv.no_rows")
+	case errors.As(err, &malformed):
+		return translate(r, "csv.malformed", malformed.Line)
+	default:
+		return translate(r, "csv.unreadable")
+	}
+}
+
+// apiBulkPrepare lit le fichier CSV envoyé et retourne la prévisualisation validée
+func (h *Handlers) apiBulkPrepare(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkUploadBytes)
+	if err := r.ParseMultipartForm(maxBulkUploadBytes); err != nil {
+		h.logSecurityEvent(r, "multipart_parse_error", err.Error())
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidCSV, h.csvErrorMessage(r, err))
+		return
+	}
+
+	script := strings.TrimSpace(r.FormValue("script"))
+	if !h.validateScript(script) {
+		h.logSecurityEvent(r, "invalid_script", script)
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidScript)
+		return
+	}
+	if !h.authorizeExecution(w, r, script) {
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidCSV, h.csvErrorMessage(r, err))
+		return
+	}
+	defer file.Close()
+
+	batch, err := h.bulk.Prepare(script, getOperator(r), file)
+	if err != nil {
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidCSV, h.csvErrorMessage(r, err))
+		return
+	}
+
+	h.logSecurityEvent(r, "bulk_upload",
+		fmt.Sprintf("batch:%s script:%s rows:%d invalid:%d", batch.ID, script, len(batch.Rows), batch.Summary()[bulk.RowInvalid]))
+	w.Header().Set("Location", apiPrefix+"/bulk/"+batch.ID)
+	h.sendAPIJSON(w, http.StatusCreated, newBulkBatch(batch))
+}
+
+// apiBulkStart lance l'exécution des lignes valides d'un lot prévisualisé
+func (h *Handlers) apiBulkStart(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	preview, ok := h.bulk.Get(params["id"])
+	if !ok {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	}
+	if !h.authorizeExecution(w, r, preview.Script) {
+		return
+	}
+
+	batch, err := h.bulk.Start(r.Context(), preview.ID)
+	switch {
+	case errors.Is(err, bulk.ErrNotFound):
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	case errors.Is(err, bulk.ErrAlreadyStarted):
+		h.sendAPIError(w, r, http.StatusConflict, ErrCodeBulkAlreadyStarted)
+		return
+	case errors.Is(err, bulk.ErrNoValidRows):
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeBulkNoValidRows)
+		return
+	case err != nil:
+		h.logger.ErrorContext(r.Context(), "bulk execution failed", logging.KeyCategory, logging.CategoryExecution, "error", err)
+		h.sendAPIError(w, r, http.StatusInternalServerError, ErrCodeExecutionFailed)
+		return
+	}
+
+	h.logSecurityEvent(r, "bulk_execution_request",
+		fmt.Sprintf("batch:%s script:%s rows:%d", batch.ID, batch.Script, batch.Summary()[bulk.RowPending]))
+	w.Header().Set("Location", apiPrefix+"/bulk/"+batch.ID)
+	h.sendAPIJSON(w, http.StatusAccepted, newBulkBatch(batch))
+}
+
+// apiBulkGet retourne l'état d'un lot
+func (h *Handlers) apiBulkGet(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	batch, ok := h.bulk.Get(params["id"])
+	if !ok {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	}
+	if !h.checkTokenScope(w, r, batch.Script) {
+		return
+	}
+	h.sendAPIJSON(w, http.StatusOK, newBulkBatch(batch))
+}
+
+// apiBulkReport télécharge le rapport CSV par ligne d'un lot
+func (h *Handlers) apiBulkReport(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	batch, ok := h.bulk.Get(params["id"])
+	if !ok {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	}
+	if !h.checkTokenScope(w, r, batch.Script) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="bulk-%s.csv"`, batch.ID))
+	w.WriteHeader(http.StatusOK)
+	if err := bulk.WriteReport(w, batch); err != nil {
+		h.logger.ErrorContext(r.Context(), "bulk report writing failed", logging.KeyCategory, logging.CategoryHTTP, "error", err)
+	}
+}
+
+// newBulkBatch convertit un lot en réponse d'API
+func newBulkBatch(batch bulk.Batch) BulkBatch {
+	response := BulkBatch{
+		ID:        batch.ID,
+		Script:    batch.Script,
+		Operator:  batch.Operator,
+		Status:    string(batch.Status),
+		Columns:   append([]string{}, batch.Columns...),
+		Summary:   make(map[string]int),
+		Rows:      make([]BulkRow, 0, len(batch.Rows)),
+		CreatedAt: batch.CreatedAt,
+	}
+	for status, count := range batch.Summary() {
+		response.Summary[string(status)] = count
+	}
+	for _, row := range batch.Rows {
+		response.Rows = append(response.Rows, BulkRow{
+			Line:        row.Line,
+			UserID:      row.UserID,
+			Arguments:   row.Arguments,
+			Status:      string(row.Status),
+			Error:       row.Error,
+			ExecutionID: row.ExecutionID,
+			ExitCode:    row.ExitCode,
+			DurationMs:  row.Duration.Milliseconds(),
+		})
+	}
+	if !batch.StartedAt.IsZero() {
+		startedAt := batch.StartedAt
+		response.StartedAt = &startedAt
+	}
+	if !batch.FinishedAt.IsZero() {
+		finishedAt := batch.FinishedAt
+		response.FinishedAt = &finishedAt
+	}
+	return response
+}
@@ -0,0 +1,164 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// doBulkUpload envoie un fichier CSV en multipart à l'API d'exécution en masse
+func doBulkUpload(h *Handlers, script, content string, headers map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("script", script)
+	part, _ := writer.CreateFormFile("file", "users.csv")
+	part.Write([]byte(content))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/bulk", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	h.APIHandler(w, req)
+	return w
+}
+
+func TestAPIBulkWorkflow(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	csrf := map[string]string{"X-CSRF-Token": "token"}
+
+	w := doBulkUpload(handlers, "grant.sh", "userId,role\ntest123,reader\ntest456,writer\nbad,reader\n", csrf)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	var preview BulkBatch
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("failed to decode preview: %v", err)
+	}
+	if preview.Status != "preview" || preview.Summary["pending"] != 2 || preview.Summary["invalid"] != 1 {
+		t.Errorf("preview = %+v, want 2 pending and 1 invalid", preview)
+	}
+	if len(preview.Columns) != 1 || preview.Columns[0] != "role" {
+		t.Errorf("preview columns = %v, want [role]", preview.Columns)
+	}
+
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/bulk/"+preview.ID+"/start", "", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("start without CSRF status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/bulk/"+preview.ID+"/start", "", csrf)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("start status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/bulk/"+preview.ID+"/start", "", csrf)
+	if w.Code != http.StatusConflict {
+		t.Errorf("second start status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	handlers.bulk.Wait()
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/bulk/"+preview.ID, "", nil)
+	var done BulkBatch
+	json.Unmarshal(w.Body.Bytes(), &done)
+	if done.Status != "completed" || done.Summary["succeeded"] != 2 || done.FinishedAt == nil {
+		t.Errorf("batch = %+v, want completed with 2 succeeded", done)
+	}
+
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/bulk/"+preview.ID+"/report", "", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("report status = %d, Content-Type = %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("report Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil || len(records) != 4 {
+		t.Fatalf("report = %v (%v), want header and 3 rows", records, err)
+	}
+	if records[1][1] != "test123" || records[1][3] != "succeeded" || records[3][3] != "invalid" {
+		t.Errorf("report rows = %v", records[1:])
+	}
+}
+
+func TestAPIBulkErrors(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	csrf := map[string]string{"X-CSRF-Token": "token"}
+
+	tests := []struct {
+		name         string
+		script       string
+		content      string
+		headers      map[string]string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"missing CSRF token", "grant.sh", "userId\ntest123\n", nil, http.StatusBadRequest, ErrCodeMissingCSRFToken},
+		{"script not allowed", "evil.sh", "userId\ntest123\n", csrf, http.StatusBadRequest, ErrCodeInvalidScript},
+		{"missing userId column", "grant.sh", "user\ntest123\n", csrf, http.StatusBadRequest, ErrCodeInvalidCSV},
+		{"empty file", "grant.sh", "", csrf, http.StatusBadRequest, ErrCodeInvalidCSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doBulkUpload(handlers, tt.script, tt.content, tt.headers)
+			if w.Code != tt.expectedCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.expectedCode)
+			}
+			var response APIErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.Error.Code != tt.expectedErr {
+				t.Errorf("error code = %s, want %s", response.Error.Code, tt.expectedErr)
+			}
+		})
+	}
+
+	w := doBulkUpload(handlers, "grant.sh", "userId\nbad\n", csrf)
+	var preview BulkBatch
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/bulk/"+preview.ID+"/start", "", csrf)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("start without valid rows status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	for _, path := range []string{"/api/v1/bulk/unknown", "/api/v1/bulk/unknown/report"} {
+		if w := doAPIRequest(handlers, http.MethodGet, path, "", nil); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s status = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestAPIBulkErrorMessages(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	tests := []struct {
+		name     string
+		content  string
+		lang     string
+		expected string
+	}{
+		{"missing userId column", "user\ntest123\n", "fr", "Fichier CSV invalide : l'en-tête doit contenir une colonne userId"},
+		{"missing userId column", "user\ntest123\n", "en", "Invalid CSV file: the header must contain a userId column"},
+		{"empty file", "", "fr", "Fichier CSV invalide : le fichier est vide"},
+		{"no data rows", "userId\n", "en", "Invalid CSV file: the file has no data rows"},
+		{"malformed", "userId\n\"test123\n", "fr", "Fichier CSV invalide : CSV mal formé à la ligne 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.lang, func(t *testing.T) {
+			w := doBulkUpload(handlers, "grant.sh", tt.content, map[string]string{"X-CSRF-Token": "token", "Accept-Language": tt.lang})
+			var response APIErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if response.Error.Message != tt.expected {
+				t.Errorf("message = %q, want %q", response.Error.Message, tt.expected)
+			}
+		})
+	}
+}
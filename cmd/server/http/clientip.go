@@ -0,0 +1,188 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"go-form-app/internal/config"
+)
+
+type clientIPContextKey struct{}
+
+// clientIPResolver détermine l'IP réelle du client en ne faisant confiance
+// qu'aux en-têtes posés par des proxies explicitement autorisés
+type clientIPResolver struct {
+	trustedProxies []*net.IPNet
+	// header est le seul en-tête lu : celui que les proxies de confiance réécrivent.
+	// L'autre est transmis tel quel par le proxy et donc choisi par le client.
+	header string
+}
+
+// newClientIPResolver crée un resolver à partir d'une liste de CIDR (ou d'IP seules)
+// et de l'en-tête posé par ces proxies (config.ForwardedHeaderXFF par défaut)
+func newClientIPResolver(trustedProxies []string, header string) (*clientIPResolver, error) {
+	if header == "" {
+		header = config.ForwardedHeaderXFF
+	}
+	if header != config.ForwardedHeaderXFF && header != config.ForwardedHeaderRFC7239 {
+		return nil, fmt.Errorf("invalid forwarded header %q", header)
+	}
+	resolver := &clientIPResolver{header: header}
+
+	for _, entry := range trustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		network, err := parseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		resolver.trustedProxies = append(resolver.trustedProxies, network)
+	}
+
+	return resolver, nil
+}
+
+// parseCIDR accepte un CIDR ou une IP seule (convertie en /32 ou /128)
+func parseCIDR(entry string) (*net.IPNet, error) {
+	if !strings.Contains(entry, "/") {
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("not an IP address or CIDR")
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(entry)
+	return network, err
+}
+
+// isTrusted indique si l'adresse appartient à un proxy de confiance
+func (c *clientIPResolver) isTrusted(ip net.IP) bool {
+	for _, network := range c.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve parcourt la chaîne de proxies de droite à gauche et s'arrête au
+// premier saut non fiable, qui est considéré comme l'IP du client
+func (c *clientIPResolver) resolve(r *http.Request) string {
+	remote := remoteHost(r)
+	remoteIP := net.ParseIP(remote)
+	if remoteIP == nil || !c.isTrusted(remoteIP) {
+		return remote
+	}
+
+	hops := c.forwardedHops(r)
+	if len(hops) == 0 && c.header == config.ForwardedHeaderXFF {
+		if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); realIP != nil {
+			return realIP.String()
+		}
+	}
+	if len(hops) == 0 {
+		return remote
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// Saut illisible ou obfusqué : on s'arrête au dernier saut connu
+			return client
+		}
+
+		client = ip.String()
+		if !c.isTrusted(ip) {
+			return client
+		}
+	}
+
+	return client
+}
+
+// forwardedHops extrait la liste des adresses annoncées par les proxies, depuis
+// X-Forwarded-For ou l'en-tête standard Forwarded (RFC 7239) selon la configuration
+func (c *clientIPResolver) forwardedHops(r *http.Request) []string {
+	if c.header == config.ForwardedHeaderRFC7239 {
+		return parseForwardedHeader(r.Header.Values("Forwarded"))
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseForwardedHeader extrait les paramètres for= de l'en-tête Forwarded
+func parseForwardedHeader(values []string) []string {
+	var hops []string
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			forValue := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					forValue = val
+				}
+			}
+			hops = append(hops, normalizeForwardedNode(forValue))
+		}
+	}
+
+	return hops
+}
+
+// normalizeForwardedNode retire guillemets, crochets IPv6 et port d'un nœud Forwarded
+func normalizeForwardedNode(node string) string {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+		return ""
+	}
+
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+// remoteHost retourne l'IP de la connexion TCP, sans le port
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// withClientIP mémorise l'IP résolue dans le contexte de la requête
+func withClientIP(r *http.Request, ip string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientIPContextKey{}, ip))
+}
+
+// getClientIP récupère l'IP réelle du client résolue par le middleware de sécurité.
+// Sans résolution préalable, seule l'adresse de connexion est utilisée.
+func getClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	return remoteHost(r)
+}
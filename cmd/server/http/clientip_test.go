@@ -0,0 +1,225 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewClientIPResolver(t *testing.T) {
+	tests := []struct {
+		name        string
+		proxies     []string
+		header      string
+		expectError bool
+	}{
+		{"no proxies", nil, "", false},
+		{"empty entries are ignored", []string{"", " "}, "", false},
+		{"CIDR and single IPs", []string{"10.0.0.0/8", "192.168.1.10", "::1"}, "xff", false},
+		{"Forwarded header", []string{"10.0.0.0/8"}, "forwarded", false},
+		{"invalid CIDR", []string{"10.0.0.0/33"}, "", true},
+		{"invalid IP", []string{"not-an-ip"}, "", true},
+		{"invalid header", []string{"10.0.0.0/8"}, "x-real-ip", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newClientIPResolver(tt.proxies, tt.header)
+			if tt.expectError && err == nil {
+				t.Error("newClientIPResolver() expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("newClientIPResolver() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientIPResolverResolve(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		remoteAddr   string
+		forwardedFor []string
+		forwarded    string
+		realIP       string
+		expected     string
+	}{
+		{
+			name:       "direct connection",
+			remoteAddr: "192.168.1.1:8080",
+			expected:   "192.168.1.1",
+		},
+		{
+			name:         "spoofed XFF from untrusted client is ignored",
+			remoteAddr:   "198.51.100.7:5000",
+			forwardedFor: []string{"1.2.3.4"},
+			expected:     "198.51.100.7",
+		},
+		{
+			name:       "spoofed X-Real-IP from untrusted client is ignored",
+			remoteAddr: "198.51.100.7:5000",
+			realIP:     "1.2.3.4",
+			expected:   "198.51.100.7",
+		},
+		{
+			name:         "single trusted proxy",
+			remoteAddr:   "127.0.0.1:8080",
+			forwardedFor: []string{"203.0.113.1"},
+			expected:     "203.0.113.1",
+		},
+		{
+			name:         "spoofed leftmost entry is skipped",
+			remoteAddr:   "127.0.0.1:8080",
+			forwardedFor: []string{"1.2.3.4, 203.0.113.1"},
+			expected:     "203.0.113.1",
+		},
+		{
+			name:         "chain of trusted proxies",
+			remoteAddr:   "10.0.0.2:8080",
+			forwardedFor: []string{"203.0.113.1, 10.0.0.5, 10.0.0.1"},
+			expected:     "203.0.113.1",
+		},
+		{
+			name:         "multiple XFF headers are concatenated",
+			remoteAddr:   "127.0.0.1:8080",
+			forwardedFor: []string{"1.2.3.4", "203.0.113.9"},
+			expected:     "203.0.113.9",
+		},
+		{
+			name:         "all hops trusted returns leftmost",
+			remoteAddr:   "127.0.0.1:8080",
+			forwardedFor: []string{"10.1.1.1, 10.2.2.2"},
+			expected:     "10.1.1.1",
+		},
+		{
+			name:         "garbage hop stops at last known address",
+			remoteAddr:   "127.0.0.1:8080",
+			forwardedFor: []string{"203.0.113.1, evil, 10.0.0.1"},
+			expected:     "10.0.0.1",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			remoteAddr: "127.0.0.1:8080",
+			realIP:     "203.0.113.1",
+			expected:   "203.0.113.1",
+		},
+		{
+			name:         "XFF takes precedence over X-Real-IP",
+			remoteAddr:   "127.0.0.1:8080",
+			forwardedFor: []string{"203.0.113.1"},
+			realIP:       "198.51.100.1",
+			expected:     "203.0.113.1",
+		},
+		{
+			name:         "client Forwarded header is ignored when the proxy sets XFF",
+			remoteAddr:   "172.18.0.5:41000",
+			forwardedFor: []string{"203.0.113.9"},
+			forwarded:    "for=10.0.0.5",
+			expected:     "203.0.113.9",
+		},
+		{
+			name:       "client Forwarded header alone is ignored when the proxy sets XFF",
+			remoteAddr: "127.0.0.1:8080",
+			forwarded:  "for=192.0.2.60",
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "Forwarded header",
+			header:     "forwarded",
+			remoteAddr: "127.0.0.1:8080",
+			forwarded:  `for=1.2.3.4, for=192.0.2.60;proto=http;by=203.0.113.43`,
+			expected:   "192.0.2.60",
+		},
+		{
+			name:       "Forwarded header with quoted IPv6 and port",
+			header:     "forwarded",
+			remoteAddr: "127.0.0.1:8080",
+			forwarded:  `for="[2001:db8:cafe::17]:4711"`,
+			expected:   "2001:db8:cafe::17",
+		},
+		{
+			name:       "Forwarded header with IPv4 and port",
+			header:     "forwarded",
+			remoteAddr: "127.0.0.1:8080",
+			forwarded:  `For="192.0.2.43:47011"`,
+			expected:   "192.0.2.43",
+		},
+		{
+			name:         "client XFF and X-Real-IP are ignored when the proxy sets Forwarded",
+			header:       "forwarded",
+			remoteAddr:   "127.0.0.1:8080",
+			forwarded:    "for=192.0.2.60",
+			forwardedFor: []string{"1.2.3.4"},
+			realIP:       "1.2.3.4",
+			expected:     "192.0.2.60",
+		},
+		{
+			name:       "missing Forwarded header ignores X-Real-IP",
+			header:     "forwarded",
+			remoteAddr: "127.0.0.1:8080",
+			realIP:     "1.2.3.4",
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "obfuscated Forwarded node stops at proxy",
+			header:     "forwarded",
+			remoteAddr: "127.0.0.1:8080",
+			forwarded:  "for=_hidden",
+			expected:   "127.0.0.1",
+		},
+		{
+			name:       "spoofed Forwarded from untrusted client is ignored",
+			header:     "forwarded",
+			remoteAddr: "198.51.100.7:5000",
+			forwarded:  "for=1.2.3.4",
+			expected:   "198.51.100.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := newClientIPResolver([]string{"10.0.0.0/8", "127.0.0.1", "172.16.0.0/12"}, tt.header)
+			if err != nil {
+				t.Fatalf("newClientIPResolver() error = %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("Forwarded", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if result := resolver.resolve(req); result != tt.expected {
+				t.Errorf("resolve() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetClientIP(t *testing.T) {
+	t.Run("without resolution headers are ignored", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.168.1.1:8080"
+		req.Header.Set("X-Forwarded-For", "203.0.113.1")
+
+		if result := getClientIP(req); result != "192.168.1.1" {
+			t.Errorf("getClientIP() = %s, want 192.168.1.1", result)
+		}
+	})
+
+	t.Run("resolved IP from context", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = withClientIP(req, "203.0.113.1")
+
+		if result := getClientIP(req); result != "203.0.113.1" {
+			t.Errorf("getClientIP() = %s, want 203.0.113.1", result)
+		}
+	})
+}
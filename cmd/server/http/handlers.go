@@ -1,19 +1,29 @@
 package http
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"go-form-app/internal/bulk"
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/i18n"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/schedule"
 	"go-form-app/internal/scripts"
+	"go-form-app/internal/secrets"
+	"go-form-app/internal/workflow"
 )
 
 // SecurityConfig contient les configurations de sécurité
@@ -24,39 +34,95 @@ type SecurityConfig struct {
 	ScriptsDir       string
 }
 
+// defaultHistorySize borne le nombre d'exécutions conservées dans l'historique
+const defaultHistorySize = 1000
+
+// queueRetryAfter est le délai suggéré, en secondes, avant de réessayer quand la file d'attente est pleine
+const queueRetryAfter = "5"
+
 // Handlers contient les handlers HTTP avec les configurations de sécurité
 type Handlers struct {
 	security SecurityConfig
-	logger   *log.Logger
+	logger   *slog.Logger
 	executor *scripts.Executor
+	jobs     *jobs.Manager
+	bans     *banTracker
+	web      *webAssets
+	bulk     *bulk.Runner
+
+	schedules *schedule.Store
+	scheduler *schedule.Scheduler
+	workflows *workflow.Runner
+
+	// apiRoutes est la table des routes de l'API v1 (routage, OpenAPI, labels des métriques)
+	apiRoutes []apiRoute
+
+	openAPIOnce sync.Once
+	openAPISpec []byte
 }
 
-// NewHandlers crée une nouvelle instance des handlers avec sécurité
-func NewHandlers(logger *log.Logger) *Handlers {
+// NewHandlers crée les handlers et les services d'exécution à partir de la configuration
+// finale : exécuteur, historique, planifications, exécutions en masse et workflows
+func NewHandlers(cfg config.Config, logger *slog.Logger) (*Handlers, error) {
 	security := SecurityConfig{
-		AllowedScripts: []string{
-			"script1.py",
-			"script2.py",
-			"script1.sh",
-			"script1.zsh",
-		},
-		MaxExecutionTime: 30 * time.Second,
-		UserIDPattern:    regexp.MustCompile(`^[a-zA-Z0-9]{7,12}$`),
-		ScriptsDir:       "internal/scripts",
-	}
-
-	executor := scripts.NewExecutor(
-		security.ScriptsDir,
-		security.MaxExecutionTime,
-		security.AllowedScripts,
-		logger,
-	)
+		AllowedScripts:   cfg.Scripts.AllowedScripts,
+		MaxExecutionTime: cfg.Scripts.MaxExecutionTime,
+		UserIDPattern:    regexp.MustCompile(cfg.Scripts.UserIDPattern),
+		ScriptsDir:       cfg.Scripts.Dir,
+	}
+
+	executor := scripts.NewExecutor(cfg.Scripts, logger)
+	if cfg.SecretsFile != "" {
+		secretStore, err := secrets.NewStore(cfg.SecretsFile, cfg.SecretsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("secrets store: %w", err)
+		}
+		executor.UseSecretStore(secretStore)
+	}
+	// Un secret manquant est signalé au démarrage plutôt qu'à la première exécution
+	for _, script := range cfg.Scripts.AllowedScripts {
+		if err := executor.CheckSecrets(script); err != nil {
+			return nil, err
+		}
+	}
+
+	store, err := history.NewStore(cfg.HistoryFile, defaultHistorySize)
+	if err != nil {
+		return nil, fmt.Errorf("history store: %w", err)
+	}
+	schedules, err := schedule.NewStore(cfg.SchedulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("schedule store: %w", err)
+	}
 
-	return &Handlers{
-		security: security,
-		logger:   logger,
-		executor: executor,
+	var web *webAssets
+	if cfg.Web.DevDir != "" {
+		web, err = newDevWebAssets(cfg.Web.DevDir, logger)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("serving web assets from development directory", logging.KeyCategory, logging.CategoryHTTP, "dir", cfg.Web.DevDir)
+	} else if web, err = newEmbeddedWebAssets(logger); err != nil {
+		return nil, fmt.Errorf("embedded web assets: %w", err)
 	}
+
+	manager := jobs.NewManager(executor, store, logger)
+	manager.SetIdempotencyWindow(cfg.IdempotencyWindow)
+
+	h := &Handlers{
+		security:  security,
+		logger:    logger,
+		executor:  executor,
+		jobs:      manager,
+		bans:      newBanTracker(cfg.BanPolicy),
+		web:       web,
+		bulk:      bulk.NewRunner(manager, cfg.Bulk, logger),
+		schedules: schedules,
+		scheduler: schedule.NewScheduler(manager, schedules, logger),
+		workflows: workflow.NewRunner(manager, cfg.Workflows, logger),
+	}
+	h.apiRoutes = h.newAPIRoutes()
+	return h, nil
 }
 
 // FormHandler affiche le formulaire avec protection CSRF
@@ -69,20 +135,37 @@ func (h *Handlers) FormHandler(w http.ResponseWriter, r *http.Request) {
 
 	csrfToken, err := generateSecureCSRFToken()
 	if err != nil {
-		h.logger.Printf("CSRF token generation failed: %v", err)
+		h.logger.ErrorContext(r.Context(), "CSRF token generation failed", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	rememberLanguage(w, r)
+	lang := requestLanguage(r)
+	setLanguageHeaders(w, lang)
+
+	dryRunScripts := make(map[string]bool)
+	for _, script := range h.security.AllowedScripts {
+		dryRunScripts[script] = h.executor.SupportsDryRun(script)
+	}
+
 	data := struct {
 		CSRFToken      string
 		AllowedScripts []string
+		DryRunScripts  map[string]bool
+		Lang           string
+		Languages      []string
+		Messages       map[string]string
 	}{
 		CSRFToken:      csrfToken,
 		AllowedScripts: h.security.AllowedScripts,
+		DryRunScripts:  dryRunScripts,
+		Lang:           lang,
+		Languages:      i18n.Default.Languages(),
+		Messages:       i18n.Default.Prefixed(lang, "js."),
 	}
 
-	h.executeTemplate(w, "cmd/server/http/web/templates/form.html", data)
+	h.executeTemplate(w, r, formTemplate, data)
 }
 
 // RunScriptHandler traite l'exécution des scripts avec validation stricte
@@ -109,14 +192,16 @@ func (h *Handlers) RunScriptHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	token, hasToken := getAPIToken(r)
+
 	csrfToken := strings.TrimSpace(r.Header.Get("X-CSRF-Token"))
 	if csrfToken == "" {
 		csrfToken = strings.TrimSpace(r.FormValue("csrf_token"))
 	}
 
-	if csrfToken == "" {
+	if csrfToken == "" && !hasToken {
 		h.logSecurityEvent(r, "missing_csrf_token", "no token in headers or form")
-		h.sendJSONError(w, "Token CSRF manquant", http.StatusBadRequest)
+		h.sendJSONError(w, r, ErrCodeMissingCSRFToken, http.StatusBadRequest)
 		return
 	}
 	userID := strings.TrimSpace(r.FormValue("userId"))
@@ -124,41 +209,85 @@ func (h *Handlers) RunScriptHandler(w http.ResponseWriter, r *http.Request) {
 
 	if !h.validateUserID(userID) {
 		h.logSecurityEvent(r, "invalid_user_id", userID)
-		h.sendJSONError(w, "Format d'ID utilisateur invalide", http.StatusBadRequest)
+		h.sendJSONError(w, r, ErrCodeInvalidUserID, http.StatusBadRequest)
 		return
 	}
 
 	if !h.validateScript(script) {
 		h.logSecurityEvent(r, "invalid_script", script)
-		h.sendJSONError(w, "Script non autorisé", http.StatusBadRequest)
+		h.sendJSONError(w, r, ErrCodeInvalidScript, http.StatusBadRequest)
+		return
+	}
+
+	if hasToken && !token.Allows(script) {
+		h.logSecurityEvent(r, "token_scope_denied", fmt.Sprintf("token:%s script:%s", token.ID, script))
+		h.sendJSONError(w, r, ErrCodeForbiddenScript, http.StatusForbidden)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.FormValue("dry_run"))
+	if dryRun && !h.executor.SupportsDryRun(script) {
+		h.sendJSONError(w, r, ErrCodeDryRunUnsupported, http.StatusBadRequest)
+		return
+	}
+
+	idempotencyKey, ok := requestIdempotencyKey(r, r.FormValue("idempotency_key"))
+	if !ok {
+		h.logSecurityEvent(r, "invalid_idempotency_key", idempotencyKey)
+		h.sendJSONError(w, r, ErrCodeInvalidIdempotencyKey, http.StatusBadRequest)
 		return
 	}
 
 	h.logSecurityEvent(r, "script_execution_request",
-		fmt.Sprintf("user:%s script:%s", userID, script))
-	ctx := context.Background()
+		fmt.Sprintf("user:%s script:%s dry_run:%t", userID, script, dryRun))
+	operator := getOperator(r)
 	req := scripts.ExecutionRequest{
-		UserID: userID,
-		Script: script,
+		UserID:           userID,
+		Script:           script,
+		Operator:         operator,
+		IdempotencyKey:   idempotencyKey,
+		IdempotencyScope: idempotencyScope(r, operator),
+		DryRun:           dryRun,
 	}
 
-	result, err := h.executor.Execute(ctx, req)
+	// La politique de déconnexion du script décide si la fermeture du navigateur l'interrompt
+	result, err := h.jobs.Run(r.Context(), req, jobs.SourceForm)
+	if status, code, ok := executionRejection(w, err); ok {
+		h.sendJSONError(w, r, code, status)
+		return
+	}
 	if err != nil {
-		h.logger.Printf("Script execution failed: %v", err)
-		h.sendJSONError(w, "Erreur lors de l'exécution du script", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "script execution failed", logging.KeyCategory, logging.CategoryExecution, "error", err)
+		h.sendJSONError(w, r, ErrCodeExecutionFailed, http.StatusInternalServerError)
 		return
 	}
 
+	if result.Replayed {
+		w.Header().Set(idempotentReplayedHeader, "true")
+		if !result.Status.Finished() {
+			// Demande répétée pendant l'exécution d'origine : le script n'est pas relancé
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":      "running",
+				"message":     translate(r, "run.in_progress"),
+				"executionId": result.ID,
+			})
+			return
+		}
+	}
+
 	response := map[string]interface{}{
-		"status":   "success",
-		"message":  "Script exécuté avec succès",
-		"success":  result.Success,
-		"duration": result.Duration.String(),
+		"status":      "success",
+		"message":     translate(r, "run.succeeded"),
+		"success":     result.Success,
+		"duration":    result.Duration.String(),
+		"executionId": result.ID,
 	}
 
 	if !result.Success {
 		response["status"] = "error"
-		response["message"] = "Échec de l'exécution du script"
+		response["message"] = translate(r, "run.failed")
 		if result.Error != "" {
 			response["error"] = result.Error
 		}
@@ -170,14 +299,40 @@ func (h *Handlers) RunScriptHandler(w http.ResponseWriter, r *http.Request) {
 			response["output"] = result.Output
 		}
 	}
+	if result.Result != nil {
+		response["result"] = result.Result
+	}
+	if result.DryRun {
+		response["dryRun"] = true
+	}
+	if result.Redactions > 0 {
+		response["redactions"] = result.Redactions
+	}
 
 	h.logSecurityEvent(r, "script_execution_completed",
-		fmt.Sprintf("user:%s script:%s success:%t duration:%v exit_code:%d",
-			userID, script, result.Success, result.Duration, result.ExitCode))
+		fmt.Sprintf("user:%s script:%s success:%t duration:%v exit_code:%d redactions:%d",
+			userID, script, result.Success, result.Duration, result.ExitCode, result.Redactions))
 
 	h.sendJSONResponse(w, response)
 }
 
+// executionRejection associe les refus d'exécution (conflit sur l'utilisateur, clé
+// d'idempotence réutilisée, file pleine) à un statut HTTP et à un code d'erreur stable
+func executionRejection(w http.ResponseWriter, err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, scripts.ErrUserBusy):
+		return http.StatusConflict, ErrCodeUserBusy, true
+	case errors.Is(err, jobs.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity, ErrCodeIdempotencyKeyReused, true
+	case errors.Is(err, scripts.ErrDryRunUnsupported):
+		return http.StatusBadRequest, ErrCodeDryRunUnsupported, true
+	case errors.Is(err, scripts.ErrQueueFull):
+		w.Header().Set("Retry-After", queueRetryAfter)
+		return http.StatusServiceUnavailable, ErrCodeQueueFull, true
+	}
+	return 0, "", false
+}
+
 // validateUserID valide le format de l'ID utilisateur
 func (h *Handlers) validateUserID(userID string) bool {
 	if userID == "" {
@@ -209,44 +364,67 @@ func (h *Handlers) sendJSONResponse(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.logger.Printf("JSON encoding error: %v", err)
+		h.logger.Error("JSON encoding failed", "error", err)
 	}
 }
 
-// sendJSONError envoie une erreur en JSON
-func (h *Handlers) sendJSONError(w http.ResponseWriter, message string, statusCode int) {
+// sendJSONError envoie une erreur en JSON : code stable et message dans la langue du client
+func (h *Handlers) sendJSONError(w http.ResponseWriter, r *http.Request, code string, statusCode int) {
+	lang := requestLanguage(r)
+	setLanguageHeaders(w, lang)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	response := map[string]string{
 		"status":  "error",
-		"message": message,
+		"code":    code,
+		"message": i18n.Default.T(lang, "error."+code),
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
 // logSecurityEvent enregistre les événements de sécurité
 func (h *Handlers) logSecurityEvent(r *http.Request, eventType, details string) {
-	h.logger.Printf("SECURITY_EVENT: %s | IP: %s | UserAgent: %s | Details: %s",
-		eventType,
-		getClientIP(r),
-		r.UserAgent(),
-		details,
+	securityEventsTotal.Inc(eventType)
+
+	level := slog.LevelInfo
+	if validationFailureEvents[eventType] || eventType == "ip_not_allowed" {
+		level = slog.LevelWarn
+	}
+	h.logger.Log(r.Context(), level, "security event",
+		logging.KeyCategory, logging.CategorySecurity,
+		"event", eventType,
+		logging.KeyOperator, getOperator(r),
+		"user_agent", r.UserAgent(),
+		"details", details,
 	)
+
+	if validationFailureEvents[eventType] && h.bans.recordFailure(getClientIP(r)) {
+		securityEventsTotal.Inc("ip_banned")
+		h.logger.WarnContext(r.Context(), "security event",
+			logging.KeyCategory, logging.CategorySecurity,
+			"event", "ip_banned",
+			"max_failures", h.bans.policy.MaxFailures,
+			"window", h.bans.policy.Window.String(),
+			"ban_duration", h.bans.policy.Duration.String(),
+		)
+	}
 }
 
-// getClientIP récupère l'IP réelle du client
-func getClientIP(r *http.Request) string {
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		return strings.Split(forwarded, ",")[0]
+// getOperator retourne l'identité de l'opérateur : token d'API, sinon certificat client vérifié
+func getOperator(r *http.Request) string {
+	if token, ok := getAPIToken(r); ok {
+		return token.Subject()
 	}
 
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.PeerCertificates) == 0 {
+		return jobs.AnonymousOperator
 	}
 
-	return r.RemoteAddr
+	subject := r.TLS.PeerCertificates[0].Subject
+	if subject.CommonName != "" {
+		return subject.CommonName
+	}
+	return subject.String()
 }
 
 // generateSecureCSRFToken génère un token CSRF sécurisé
@@ -259,16 +437,10 @@ func generateSecureCSRFToken() (string, error) {
 }
 
 // executeTemplate exécute un template de manière sécurisée
-func (h *Handlers) executeTemplate(w http.ResponseWriter, templatePath string, data interface{}) {
-	tmpl, err := template.ParseFiles(templatePath)
-	if err != nil {
-		h.logger.Printf("Template parsing error: %v", err)
+func (h *Handlers) executeTemplate(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.web.Render(w, name, data); err != nil {
+		h.logger.ErrorContext(r.Context(), "template rendering failed", logging.KeyCategory, logging.CategoryHTTP, "template", name, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	if err := tmpl.Execute(w, data); err != nil {
-		h.logger.Printf("Template execution error: %v", err)
-		return
 	}
 }
@@ -2,26 +2,39 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
 	"go-form-app/internal/scripts"
 )
 
+// newTestHandlers crée les handlers de test en échouant sur une erreur de construction
+func newTestHandlers(t testing.TB, cfg config.Config, logger *slog.Logger) *Handlers {
+	t.Helper()
+	handlers, err := NewHandlers(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewHandlers() error = %v", err)
+	}
+	return handlers
+}
+
 func TestNewHandlers(t *testing.T) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(t, config.Default(), logger)
 
-	if handlers == nil {
-		t.Error("NewHandlers() returned nil")
-	}
 	if handlers.logger != logger {
 		t.Error("NewHandlers() logger not set correctly")
 	}
@@ -33,9 +46,37 @@ func TestNewHandlers(t *testing.T) {
 	}
 }
 
+func TestNewHandlersErrors(t *testing.T) {
+	notADir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notADir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		wantErr   string
+	}{
+		{"history file", func(cfg *config.Config) { cfg.HistoryFile = filepath.Join(notADir, "history.jsonl") }, "history store"},
+		{"schedules file", func(cfg *config.Config) { cfg.SchedulesFile = filepath.Join(notADir, "schedules.json") }, "schedule store"},
+		{"web dev directory", func(cfg *config.Config) { cfg.Web.DevDir = filepath.Join(notADir, "web") }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			tt.configure(&cfg)
+			_, err := NewHandlers(cfg, logging.New(os.Stdout, slog.LevelDebug))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewHandlers() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestFormHandler(t *testing.T) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(t, config.Default(), logger)
 
 	tests := []struct {
 		name           string
@@ -44,10 +85,10 @@ func TestFormHandler(t *testing.T) {
 		expectBody     bool
 	}{
 		{
-			name:           "GET request should return form (may fail due to missing template)",
+			name:           "GET request should return form from embedded template",
 			method:         http.MethodGet,
-			expectedStatus: http.StatusInternalServerError, // Template not found in test environment
-			expectBody:     false,
+			expectedStatus: http.StatusOK,
+			expectBody:     true,
 		},
 		{
 			name:           "POST request should be rejected",
@@ -82,8 +123,8 @@ func TestFormHandler(t *testing.T) {
 }
 
 func TestValidateUserID(t *testing.T) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(t, config.Default(), logger)
 
 	tests := []struct {
 		name     string
@@ -113,8 +154,8 @@ func TestValidateUserID(t *testing.T) {
 }
 
 func TestValidateScript(t *testing.T) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(t, config.Default(), logger)
 
 	tests := []struct {
 		name     string
@@ -143,8 +184,8 @@ func TestValidateScript(t *testing.T) {
 }
 
 func TestRunScriptHandler_MethodValidation(t *testing.T) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(t, config.Default(), logger)
 
 	tests := []struct {
 		name           string
@@ -172,8 +213,8 @@ func TestRunScriptHandler_MethodValidation(t *testing.T) {
 }
 
 func TestRunScriptHandler_CSRFValidation(t *testing.T) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(t, config.Default(), logger)
 
 	tests := []struct {
 		name           string
@@ -239,59 +280,6 @@ func TestRunScriptHandler_CSRFValidation(t *testing.T) {
 	}
 }
 
-func TestGetClientIP(t *testing.T) {
-	tests := []struct {
-		name           string
-		remoteAddr     string
-		forwardedFor   string
-		realIP         string
-		expectedResult string
-	}{
-		{
-			name:           "direct connection",
-			remoteAddr:     "192.168.1.1:8080",
-			expectedResult: "192.168.1.1:8080",
-		},
-		{
-			name:           "forwarded for header",
-			remoteAddr:     "127.0.0.1:8080",
-			forwardedFor:   "203.0.113.1, 198.51.100.1",
-			expectedResult: "203.0.113.1",
-		},
-		{
-			name:           "real IP header",
-			remoteAddr:     "127.0.0.1:8080",
-			realIP:         "203.0.113.1",
-			expectedResult: "203.0.113.1",
-		},
-		{
-			name:           "both headers - forwarded takes precedence",
-			remoteAddr:     "127.0.0.1:8080",
-			forwardedFor:   "203.0.113.1",
-			realIP:         "198.51.100.1",
-			expectedResult: "203.0.113.1",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, "/", nil)
-			req.RemoteAddr = tt.remoteAddr
-			if tt.forwardedFor != "" {
-				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
-			}
-			if tt.realIP != "" {
-				req.Header.Set("X-Real-IP", tt.realIP)
-			}
-
-			result := getClientIP(req)
-			if result != tt.expectedResult {
-				t.Errorf("getClientIP() = %s, want %s", result, tt.expectedResult)
-			}
-		})
-	}
-}
-
 func TestGenerateSecureCSRFToken(t *testing.T) {
 	// Test multiple generations to ensure uniqueness
 	tokens := make(map[string]bool)
@@ -323,8 +311,8 @@ func TestGenerateSecureCSRFToken(t *testing.T) {
 }
 
 func TestSendJSONResponse(t *testing.T) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(t, config.Default(), logger)
 
 	data := map[string]interface{}{
 		"status":  "success",
@@ -358,37 +346,92 @@ func TestSendJSONResponse(t *testing.T) {
 }
 
 func TestSendJSONError(t *testing.T) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(t, config.Default(), logger)
 
-	message := "Test error message"
-	statusCode := http.StatusBadRequest
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		cookie         string
+		query          string
+		expectedLang   string
+		expectedMsg    string
+	}{
+		{"default language", "", "", "", "fr", "Format d'ID utilisateur invalide"},
+		{"Accept-Language", "en-US,en;q=0.9", "", "", "en", "Invalid user ID format"},
+		{"cookie overrides Accept-Language", "en", "fr", "", "fr", "Format d'ID utilisateur invalide"},
+		{"UI switch overrides cookie", "", "fr", "en", "en", "Invalid user ID format"},
+		{"unsupported cookie is ignored", "en", "xx", "", "en", "Invalid user ID format"},
+	}
 
-	w := httptest.NewRecorder()
-	handlers.sendJSONError(w, message, statusCode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/run-script?lang="+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: languageCookie, Value: tt.cookie})
+			}
 
-	// Check status code
-	if w.Code != statusCode {
-		t.Errorf("sendJSONError() status = %d, want %d", w.Code, statusCode)
-	}
+			w := httptest.NewRecorder()
+			handlers.sendJSONError(w, req, ErrCodeInvalidUserID, http.StatusBadRequest)
 
-	// Check content type
-	expectedContentType := "application/json"
-	if contentType := w.Header().Get("Content-Type"); contentType != expectedContentType {
-		t.Errorf("sendJSONError() Content-Type = %s, want %s", contentType, expectedContentType)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("sendJSONError() status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("sendJSONError() Content-Type = %s, want application/json", contentType)
+			}
+			if lang := w.Header().Get("Content-Language"); lang != tt.expectedLang {
+				t.Errorf("sendJSONError() Content-Language = %s, want %s", lang, tt.expectedLang)
+			}
+
+			var response map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("sendJSONError() failed to unmarshal response: %v", err)
+			}
+			if response["status"] != "error" {
+				t.Errorf("sendJSONError() status = %s, want error", response["status"])
+			}
+			if response["code"] != ErrCodeInvalidUserID {
+				t.Errorf("sendJSONError() code = %s, want %s", response["code"], ErrCodeInvalidUserID)
+			}
+			if response["message"] != tt.expectedMsg {
+				t.Errorf("sendJSONError() message = %s, want %s", response["message"], tt.expectedMsg)
+			}
+		})
 	}
+}
 
-	// Check JSON content
-	var response map[string]string
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
-		t.Errorf("sendJSONError() failed to unmarshal response: %v", err)
+func TestFormHandlerLanguage(t *testing.T) {
+	handlers := newTestHandlers(t, config.Default(), logging.New(os.Stdout, slog.LevelDebug))
+
+	req := httptest.NewRequest(http.MethodGet, "/?lang=en", nil)
+	w := httptest.NewRecorder()
+	handlers.FormHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("FormHandler() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{`<html lang="en">`, "Run script", `"validation_failed":"Validation failed"`, `id="bulkFile"`, `id="scheduleList"`, `"schedule_pause":"Pause"`, `id="progressSteps"`, `"progress_state.running":"Running"`, `id="dryRun"`, `id="artifactList"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("FormHandler() body missing %s", want)
+		}
 	}
 
-	if response["status"] != "error" {
-		t.Errorf("sendJSONError() status = %s, want error", response["status"])
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != languageCookie || cookies[0].Value != "en" {
+		t.Errorf("FormHandler() cookies = %v, want lang=en", cookies)
 	}
-	if response["message"] != message {
-		t.Errorf("sendJSONError() message = %s, want %s", response["message"], message)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "fr-FR")
+	w = httptest.NewRecorder()
+	handlers.FormHandler(w, req)
+	if !strings.Contains(w.Body.String(), "Exécuter le script") || len(w.Result().Cookies()) != 0 {
+		t.Error("FormHandler() should render French without setting a cookie")
 	}
 }
 
@@ -410,8 +453,8 @@ func (m *mockExecutor) Execute(ctx context.Context, req scripts.ExecutionRequest
 }
 
 func BenchmarkValidateUserID(b *testing.B) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(b, config.Default(), logger)
 	userID := "test1234"
 
 	for i := 0; i < b.N; i++ {
@@ -420,8 +463,8 @@ func BenchmarkValidateUserID(b *testing.B) {
 }
 
 func BenchmarkValidateScript(b *testing.B) {
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	handlers := NewHandlers(logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	handlers := newTestHandlers(b, config.Default(), logger)
 	script := "script1.py"
 
 	for i := 0; i < b.N; i++ {
@@ -434,3 +477,56 @@ func BenchmarkGenerateCSRFToken(b *testing.B) {
 		generateSecureCSRFToken()
 	}
 }
+
+func TestGetOperator(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    *tls.ConnectionState
+		expected string
+	}{
+		{
+			name:     "plain HTTP",
+			state:    nil,
+			expected: "anonymous",
+		},
+		{
+			name:     "TLS without client certificate",
+			state:    &tls.ConnectionState{},
+			expected: "anonymous",
+		},
+		{
+			name: "verified client certificate with common name",
+			state: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "jdupont"}}},
+				VerifiedChains:   [][]*x509.Certificate{{}},
+			},
+			expected: "jdupont",
+		},
+		{
+			name: "verified client certificate without common name",
+			state: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{Organization: []string{"Ops"}}}},
+				VerifiedChains:   [][]*x509.Certificate{{}},
+			},
+			expected: "O=Ops",
+		},
+		{
+			name: "unverified client certificate",
+			state: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "intruder"}}},
+			},
+			expected: "anonymous",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.TLS = tt.state
+
+			if result := getOperator(req); result != tt.expected {
+				t.Errorf("getOperator() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
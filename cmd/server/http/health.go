@@ -0,0 +1,153 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+)
+
+// Statuts retournés par les sondes de santé
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// HealthCheck décrit le résultat d'une vérification individuelle
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HealthResponse est le corps JSON des sondes /healthz et /readyz
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthzHandler indique que le processus est vivant (liveness)
+func (h *Handlers) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.sendHealthResponse(w, HealthResponse{Status: healthOK})
+}
+
+// ReadyzHandler vérifie que toutes les dépendances nécessaires à l'exécution sont disponibles (readiness)
+func (h *Handlers) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	checks := []HealthCheck{
+		runHealthCheck("template", h.checkTemplate),
+		runHealthCheck("static", h.checkStaticDir),
+		runHealthCheck("scripts", h.checkScripts),
+		runHealthCheck("history", h.jobs.Store().Check),
+	}
+	checks = append(checks, h.checkInterpreters()...)
+
+	response := HealthResponse{Status: healthOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != healthOK {
+			response.Status = healthFail
+		}
+	}
+
+	h.sendHealthResponse(w, response)
+}
+
+// runHealthCheck exécute une vérification et convertit son erreur éventuelle
+func runHealthCheck(name string, check func() error) HealthCheck {
+	if err := check(); err != nil {
+		return HealthCheck{Name: name, Status: healthFail, Detail: err.Error()}
+	}
+	return HealthCheck{Name: name, Status: healthOK}
+}
+
+// checkTemplate vérifie que le template du formulaire se charge
+func (h *Handlers) checkTemplate() error {
+	tmpl, err := h.web.Templates()
+	if err != nil {
+		return err
+	}
+	if tmpl.Lookup(formTemplate) == nil {
+		return fmt.Errorf("template %s not found", formTemplate)
+	}
+	return nil
+}
+
+// checkStaticDir vérifie la présence du répertoire des assets statiques
+func (h *Handlers) checkStaticDir() error {
+	return h.web.CheckStatic()
+}
+
+// checkScripts vérifie le répertoire des scripts et la présence de chaque script autorisé
+func (h *Handlers) checkScripts() error {
+	if err := checkDirectory(h.executor.ScriptsDir()); err != nil {
+		return err
+	}
+
+	for _, script := range h.executor.AllowedScripts() {
+		if _, err := os.Stat(h.executor.ScriptPath(script)); err != nil {
+			return fmt.Errorf("script %s unavailable: %w", script, err)
+		}
+	}
+	return nil
+}
+
+// checkInterpreters vérifie que chaque interpréteur requis est présent dans le PATH
+func (h *Handlers) checkInterpreters() []HealthCheck {
+	interpreters := make(map[string]bool)
+	for _, script := range h.executor.AllowedScripts() {
+		interpreters[h.executor.Interpreter(script)] = true
+	}
+
+	names := make([]string, 0, len(interpreters))
+	for name := range interpreters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]HealthCheck, 0, len(names))
+	for _, name := range names {
+		checks = append(checks, runHealthCheck("interpreter:"+name, func() error {
+			_, err := exec.LookPath(name)
+			return err
+		}))
+	}
+	return checks
+}
+
+// checkDirectory vérifie qu'un chemin existe et est un répertoire
+func checkDirectory(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	return nil
+}
+
+// sendHealthResponse envoie la réponse de santé, en 503 si une vérification échoue
+func (h *Handlers) sendHealthResponse(w http.ResponseWriter, response HealthResponse) {
+	statusCode := http.StatusOK
+	if response.Status != healthOK {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("JSON encoding failed", "error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+func TestHealthzHandler(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		w := httptest.NewRecorder()
+		handlers.HealthzHandler(w, httptest.NewRequest(method, "/healthz", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s /healthz status = %d, want %d", method, w.Code, http.StatusOK)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handlers.HealthzHandler(w, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /healthz status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	w := httptest.NewRecorder()
+	handlers.ReadyzHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// Le template et les assets sont embarqués : ils ne dépendent pas du répertoire de travail
+	if w.Code != http.StatusOK {
+		t.Errorf("/readyz status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var response HealthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	statuses := make(map[string]string)
+	for _, check := range response.Checks {
+		statuses[check.Name] = check.Status
+	}
+
+	expected := map[string]string{
+		"template":         healthOK,
+		"static":           healthOK,
+		"scripts":          healthOK,
+		"history":          healthOK,
+		"interpreter:bash": healthOK,
+	}
+	for name, status := range expected {
+		if statuses[name] != status {
+			t.Errorf("check %s = %q, want %q", name, statuses[name], status)
+		}
+	}
+}
+
+func TestReadyzHandlerMissingScript(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	os.Remove(filepath.Join(handlers.executor.ScriptsDir(), "bash", "grant.sh"))
+
+	check := runHealthCheck("scripts", handlers.checkScripts)
+	if check.Status != healthFail || check.Detail == "" {
+		t.Errorf("scripts check = %+v, want failure with detail", check)
+	}
+}
+
+func TestReadyzHandlerBrokenDevTemplate(t *testing.T) {
+	dir := newTestWebDir(t)
+	handlers := newTestAPIHandlersWith(t, func(cfg *config.Config) { cfg.Web.DevDir = dir })
+
+	writeTestFile(t, filepath.Join(dir, "templates", "form.html"), "{{ .Broken ")
+	check := runHealthCheck("template", handlers.checkTemplate)
+	if check.Status != healthFail || check.Detail == "" {
+		t.Errorf("template check = %+v, want failure with detail", check)
+	}
+}
+
+func TestHealthProbesAccessRules(t *testing.T) {
+	server, err := NewServer(config.Config{
+		Scripts:     config.Default().Scripts,
+		AccessRules: []config.AccessRule{{PathPrefix: "/", Allow: []string{"10.0.0.0/8"}}},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server.handlers = newTestAPIHandlers(t)
+	routes := server.routes()
+
+	tests := []struct {
+		path         string
+		remoteAddr   string
+		expectedCode int
+	}{
+		{"/healthz", "10.1.2.3:5000", http.StatusOK},
+		{"/healthz", "198.51.100.7:5000", http.StatusForbidden},
+		{"/readyz", "198.51.100.7:5000", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" from "+tt.remoteAddr, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, req)
+			if w.Code != tt.expectedCode {
+				t.Errorf("%s status = %d, want %d", tt.path, w.Code, tt.expectedCode)
+			}
+		})
+	}
+}
@@ -1,72 +1,210 @@
 package http
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
+	"sync"
 	"time"
+
+	"go-form-app/internal/auth"
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/metrics"
 )
 
+// requestIDHeader transporte l'identifiant de corrélation des requêtes
+const requestIDHeader = "X-Request-ID"
+
 // Server représente le serveur HTTP avec ses configurations
 type Server struct {
-	handlers *Handlers
-	logger   *log.Logger
+	handlers   *Handlers
+	logger     *slog.Logger
+	config     config.Config
+	ipResolver *clientIPResolver
+	ipFilter   *ipFilter
+	bans       *banTracker
+	limiter    *rateLimiter
+	tokens     *auth.TokenStore
+
+	mu         sync.Mutex
+	httpServer *http.Server
 }
 
 // NewServer crée une nouvelle instance du serveur HTTP
-func NewServer() *Server {
-	logger := log.New(os.Stdout, "[HTTP-SERVER] ", log.LstdFlags|log.Lshortfile)
+func NewServer(cfg config.Config, logger *slog.Logger) (*Server, error) {
+	ipResolver, err := newClientIPResolver(cfg.TrustedProxies, cfg.ForwardedHeader)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Server{
-		handlers: NewHandlers(logger),
-		logger:   logger,
+	filter, err := newIPFilter(cfg.AccessRules)
+	if err != nil {
+		return nil, err
 	}
+
+	var tokens *auth.TokenStore
+	if cfg.APITokensFile != "" {
+		tokens, err = auth.NewTokenStore(cfg.APITokensFile)
+		if err != nil {
+			return nil, fmt.Errorf("API token store: %w", err)
+		}
+	}
+
+	handlers, err := NewHandlers(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		handlers:   handlers,
+		logger:     logger,
+		config:     cfg,
+		ipResolver: ipResolver,
+		ipFilter:   filter,
+		bans:       handlers.bans,
+		limiter:    newRateLimiter(cfg.RateLimit),
+		tokens:     tokens,
+	}, nil
 }
 
-// Start démarre le serveur HTTP avec toutes les protections
-func (s *Server) Start(port string) error {
+// routes associe chaque chemin à son handler et à ses protections
+func (s *Server) routes() http.Handler {
 	mux := http.NewServeMux()
 
+	// Les sondes de santé contournent volontairement le rate limiting et les logs de
+	// requêtes, mais restent soumises aux listes d'accès IP
+	mux.Handle("/healthz", s.accessMiddleware(http.HandlerFunc(s.handlers.HealthzHandler)))
+	mux.Handle("/readyz", s.accessMiddleware(http.HandlerFunc(s.handlers.ReadyzHandler)))
+	mux.Handle("/metrics", s.accessMiddleware(metrics.Default.Handler()))
+
 	mux.Handle("/", s.securityMiddleware(http.HandlerFunc(s.handlers.FormHandler)))
 	mux.Handle("/run-script", s.securityMiddleware(http.HandlerFunc(s.handlers.RunScriptHandler)))
+	mux.Handle(apiPrefix+"/", s.securityMiddleware(http.HandlerFunc(s.handlers.APIHandler)))
+
+	mux.Handle("/static/", s.securityMiddleware(s.handlers.web.StaticHandler()))
+	return mux
+}
 
-	staticHandler := http.StripPrefix("/static/",
-		http.FileServer(http.Dir("cmd/server/http/web/static/")))
-	mux.Handle("/static/", s.securityMiddleware(staticHandler))
+// Start démarre le serveur HTTP avec toutes les protections
+func (s *Server) Start(port string) error {
 	server := &http.Server{
 		Addr:           ":" + port,
-		Handler:        mux,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   30 * time.Second,
-		IdleTimeout:    120 * time.Second,
-		MaxHeaderBytes: 1 << 20, // 1 MB
+		Handler:        s.routes(),
+		ReadTimeout:    s.config.Server.ReadTimeout,
+		WriteTimeout:   s.config.Server.WriteTimeout,
+		IdleTimeout:    s.config.Server.IdleTimeout,
+		MaxHeaderBytes: s.config.Server.MaxHeaderBytes,
 	}
 
-	s.logger.Printf("Starting secure HTTP server on port %s", port)
-	return server.ListenAndServe()
+	s.mu.Lock()
+	s.httpServer = server
+	s.mu.Unlock()
+
+	s.handlers.scheduler.Start()
+	s.handlers.executor.StartPurge()
+
+	if !s.config.TLS.Enabled() {
+		s.logger.Info("starting HTTP server", logging.KeyCategory, logging.CategoryHTTP, "port", port)
+		return server.ListenAndServe()
+	}
+
+	tlsConfig, err := buildTLSConfig(s.config.TLS, s.logger)
+	if err != nil {
+		return fmt.Errorf("TLS configuration: %w", err)
+	}
+	server.TLSConfig = tlsConfig
+
+	s.logger.Info("starting HTTPS server", logging.KeyCategory, logging.CategoryHTTP, "port", port, "mutual_tls", s.config.TLS.MutualTLS())
+	return server.ListenAndServeTLS("", "")
 }
 
 // securityMiddleware applique les protections de sécurité de base
 func (s *Server) securityMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		w := &statusRecorder{ResponseWriter: rw}
+		defer func() { s.observeRequest(r, w.status, started) }()
+
+		r = withClientIP(r, s.ipResolver.resolve(r))
+		r = withRequestID(w, r)
+
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-Frame-Options", "DENY")
 		w.Header().Set("X-XSS-Protection", "1; mode=block")
 		w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' cdn.jsdelivr.net; style-src 'self' 'unsafe-inline' cdn.jsdelivr.net; font-src 'self'; img-src 'self' data: cdn.jsdelivr.net")
 
+		clientIP := getClientIP(r)
+		if s.bans.isBanned(clientIP) {
+			s.logger.WarnContext(r.Context(), "request from banned IP rejected", logging.KeyCategory, logging.CategorySecurity)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if !s.ipFilter.allowed(r.URL.Path, clientIP) {
+			s.handlers.logSecurityEvent(r, "ip_not_allowed", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		r, ok := s.authenticateAPIToken(w, r)
+		if !ok {
+			return
+		}
+
 		if !s.checkRateLimit(r) {
-			s.logger.Printf("Rate limit exceeded for IP: %s", getClientIP(r))
+			if _, ok := getAPIToken(r); ok {
+				rateLimitRejectionsTotal.Inc("token")
+			} else {
+				rateLimitRejectionsTotal.Inc("ip")
+			}
+			s.logger.WarnContext(r.Context(), "rate limit exceeded", logging.KeyCategory, logging.CategorySecurity,
+				logging.KeyOperator, getOperator(r))
 			http.Error(w, "Too many requests", http.StatusTooManyRequests)
 			return
 		}
 
-		s.logger.Printf("%s %s from %s", r.Method, r.URL.Path, getClientIP(r))
+		s.logger.InfoContext(r.Context(), "request", logging.KeyCategory, logging.CategoryHTTP,
+			"method", r.Method, "path", r.URL.Path, logging.KeyOperator, getOperator(r))
 
 		next.ServeHTTP(w, r)
 	})
 }
 
-// checkRateLimit vérifie les limites de taux (implémentation basique)
+// accessMiddleware applique uniquement les listes d'accès IP, pour les endpoints
+// techniques qui ne doivent être ni limités ni journalisés à chaque appel
+func (s *Server) accessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = withClientIP(r, s.ipResolver.resolve(r))
+
+		if !s.ipFilter.allowed(r.URL.Path, getClientIP(r)) {
+			s.handlers.logSecurityEvent(r, "ip_not_allowed", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkRateLimit vérifie les limites de taux par token d'API, sinon par IP
 func (s *Server) checkRateLimit(r *http.Request) bool {
-	return true
+	if token, ok := getAPIToken(r); ok {
+		return s.limiter.allow("token:" + token.ID)
+	}
+	return s.limiter.allow("ip:" + getClientIP(r))
+}
+
+// withRequestID propage l'identifiant X-Request-ID reçu, ou en génère un, et
+// l'associe à la réponse et aux logs de la requête
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	requestID := r.Header.Get(requestIDHeader)
+	if !logging.ValidRequestID(requestID) {
+		requestID = logging.NewRequestID()
+	}
+	w.Header().Set(requestIDHeader, requestID)
+
+	ctx := logging.WithRequestID(r.Context(), requestID)
+	ctx = logging.WithAttrs(ctx, logging.KeyClientIP, getClientIP(r))
+	return r.WithContext(ctx)
 }
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+func TestSecurityMiddlewareRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	server, err := NewServer(config.Config{Scripts: config.Default().Scripts}, logging.New(&logs, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	var seen string
+	handler := server.securityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated when missing", "", false},
+		{"propagated when valid", "edge-4f2a9c", true},
+		{"replaced when invalid", "bad id\r\nX-Injected: 1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			header := w.Header().Get(requestIDHeader)
+			if header == "" || header != seen {
+				t.Fatalf("response header = %q, context = %q, want the same non-empty ID", header, seen)
+			}
+			if tt.keep && header != tt.incoming {
+				t.Errorf("request ID = %q, want propagated %q", header, tt.incoming)
+			}
+			if !tt.keep && header == tt.incoming {
+				t.Errorf("request ID %q was propagated, want a generated one", header)
+			}
+			if !strings.Contains(logs.String(), `"request_id":"`+header+`"`) {
+				t.Errorf("request log does not carry the request ID: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestNewServerChecksScriptSecrets(t *testing.T) {
+	cfg := config.Config{Scripts: config.Default().Scripts}
+	cfg.Scripts.Settings = map[string]config.ScriptSettings{
+		"script1.sh": {Secrets: map[string]string{"IDM_TOKEN": config.SecretFilePrefix + filepath.Join(t.TempDir(), "missing")}},
+	}
+
+	_, err := NewServer(cfg, logging.New(&bytes.Buffer{}, slog.LevelDebug))
+	if err == nil || !strings.Contains(err.Error(), "IDM_TOKEN") {
+		t.Errorf("NewServer() error = %v, want the missing secret reported", err)
+	}
+}
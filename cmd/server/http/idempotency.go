@@ -0,0 +1,40 @@
+package http
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go-form-app/internal/jobs"
+)
+
+// idempotencyKeyHeader porte la clé d'idempotence d'une demande d'exécution
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayedHeader signale une réponse rejouée à partir d'une exécution existante
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// idempotencyKeyPattern borne le format des clés d'idempotence (UUID, identifiants de ticket, ...)
+var idempotencyKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+
+// requestIdempotencyKey retourne la clé de l'en-tête Idempotency-Key, sinon celle du
+// champ fourni ; false si la clé n'a pas un format valide
+func requestIdempotencyKey(r *http.Request, field string) (string, bool) {
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if key == "" {
+		key = strings.TrimSpace(field)
+	}
+	if key == "" {
+		return "", true
+	}
+	return key, idempotencyKeyPattern.MatchString(key)
+}
+
+// idempotencyScope retourne la portée des clés d'idempotence de l'opérateur : un client
+// anonyme n'a pas d'identité, ses clés sont donc propres à son IP
+func idempotencyScope(r *http.Request, operator string) string {
+	if operator != jobs.AnonymousOperator {
+		return ""
+	}
+	return "ip:" + getClientIP(r)
+}
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-form-app/internal/auth"
+	"go-form-app/internal/history"
+)
+
+func TestRequestIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		field    string
+		expected string
+		valid    bool
+	}{
+		{"no key", "", "", "", true},
+		{"header", "ticket-42", "", "ticket-42", true},
+		{"field", "", " 0f3a:retry.1 ", "0f3a:retry.1", true},
+		{"header takes precedence", "from-header", "from-field", "from-header", true},
+		{"invalid characters", "key with spaces", "", "key with spaces", false},
+		{"too long", strings.Repeat("k", 129), "", strings.Repeat("k", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/executions", nil)
+			if tt.header != "" {
+				req.Header.Set(idempotencyKeyHeader, tt.header)
+			}
+			key, valid := requestIdempotencyKey(req, tt.field)
+			if key != tt.expected || valid != tt.valid {
+				t.Errorf("requestIdempotencyKey() = %q, %t; want %q, %t", key, valid, tt.expected, tt.valid)
+			}
+		})
+	}
+}
+
+// doAuthenticatedRequest envoie une requête à l'API au nom d'un token d'API
+func doAuthenticatedRequest(h *Handlers, token auth.Token, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	h.APIHandler(w, withAPIToken(req, token))
+	return w
+}
+
+func TestExecuteIdempotencyKey(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	if err := os.WriteFile(filepath.Join(handlers.executor.ScriptsDir(), "bash", "grant.sh"), []byte("sleep 0.2; echo granted $1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	token := auth.Token{ID: "t1", Name: "ticketing", Scripts: []string{"grant.sh"}}
+	headers := map[string]string{"Content-Type": "application/json", idempotencyKeyHeader: "ticket-42"}
+	body := `{"script":"grant.sh","userId":"test123"}`
+
+	w := doAuthenticatedRequest(handlers, token, http.MethodPost, "/api/v1/executions", `{"script":"grant.sh","userId":"test123","async":true}`, headers)
+	if w.Code != http.StatusAccepted || w.Header().Get(idempotentReplayedHeader) != "" {
+		t.Fatalf("first execute status = %d, replayed = %q", w.Code, w.Header().Get(idempotentReplayedHeader))
+	}
+	var job Execution
+	json.Unmarshal(w.Body.Bytes(), &job)
+	if job.IdempotencyKey != "ticket-42" {
+		t.Errorf("job idempotency key = %q, want ticket-42", job.IdempotencyKey)
+	}
+
+	w = doAuthenticatedRequest(handlers, token, http.MethodPost, "/api/v1/executions", body, headers)
+	var inProgress Execution
+	json.Unmarshal(w.Body.Bytes(), &inProgress)
+	if w.Code != http.StatusAccepted || inProgress.ID != job.ID || w.Header().Get(idempotentReplayedHeader) != "true" {
+		t.Errorf("repeated execute during the job = %d %s, want 202 with the original job %s", w.Code, inProgress.ID, job.ID)
+	}
+
+	handlers.jobs.Wait()
+
+	w = doAuthenticatedRequest(handlers, token, http.MethodPost, "/api/v1/executions", body, headers)
+	var replayed Execution
+	json.Unmarshal(w.Body.Bytes(), &replayed)
+	if w.Code != http.StatusOK || replayed.ID != job.ID || replayed.Output != "granted test123\n" {
+		t.Errorf("repeated execute after the job = %d %+v, want the original result", w.Code, replayed)
+	}
+
+	form := httptest.NewRequest(http.MethodPost, "/run-script", strings.NewReader("userId=test123&script=grant.sh&idempotency_key=ticket-42"))
+	form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handlers.RunScriptHandler(rec, withAPIToken(form, token))
+	var formResponse map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &formResponse)
+	if rec.Code != http.StatusOK || formResponse["output"] != "granted test123\n" || rec.Header().Get(idempotentReplayedHeader) != "true" {
+		t.Errorf("form with a used key = %d %v, want the original result replayed", rec.Code, formResponse)
+	}
+
+	if executions := handlers.jobs.Store().List(history.Filter{}); len(executions) != 1 {
+		t.Errorf("history has %d executions, want 1", len(executions))
+	}
+
+	// Sans identité authentifiée, la clé est propre à l'IP du client : elle ne rejoue pas
+	// l'exécution du token, mais rejoue la demande répétée du même client
+	anonymous := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token", idempotencyKeyHeader: "ticket-42"}
+	var first Execution
+	for i := 0; i < 2; i++ {
+		w = doAPIRequest(handlers, http.MethodPost, "/api/v1/executions", body, anonymous)
+		var execution Execution
+		json.Unmarshal(w.Body.Bytes(), &execution)
+		if i == 0 {
+			first = execution
+		}
+		if w.Code != http.StatusOK || execution.ID == job.ID || execution.ID != first.ID || (w.Header().Get(idempotentReplayedHeader) == "true") != (i == 1) {
+			t.Errorf("anonymous execute %d = %d %+v, want one new execution then its replay", i, w.Code, execution)
+		}
+	}
+	if executions := handlers.jobs.Store().List(history.Filter{IdempotencyKey: "ticket-42"}); len(executions) != 2 {
+		t.Errorf("history has %d executions with the key, want 2", len(executions))
+	}
+
+	tests := []struct {
+		name         string
+		key          string
+		body         string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"key reused for another user", "ticket-42", `{"script":"grant.sh","userId":"test456"}`, http.StatusUnprocessableEntity, ErrCodeIdempotencyKeyReused},
+		{"invalid key", "ticket 42", body, http.StatusBadRequest, ErrCodeInvalidIdempotencyKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers[idempotencyKeyHeader] = tt.key
+			w := doAuthenticatedRequest(handlers, token, http.MethodPost, "/api/v1/executions", tt.body, headers)
+			var response APIErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != tt.expectedCode || response.Error.Code != tt.expectedErr {
+				t.Errorf("execute = %d %s, want %d %s", w.Code, response.Error.Code, tt.expectedCode, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRunScriptIdempotencyKeyAnonymous(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	submit := func(remoteAddr string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/run-script", strings.NewReader("userId=test123&script=grant.sh&idempotency_key=form-1"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-CSRF-Token", "token")
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handlers.RunScriptHandler(w, req)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	w, first := submit("192.0.2.1:1234")
+	if w.Code != http.StatusOK || w.Header().Get(idempotentReplayedHeader) != "" {
+		t.Fatalf("first submission = %d %v, replayed = %q", w.Code, first, w.Header().Get(idempotentReplayedHeader))
+	}
+
+	w, second := submit("192.0.2.1:5678")
+	if w.Code != http.StatusOK || w.Header().Get(idempotentReplayedHeader) != "true" || second["output"] != first["output"] {
+		t.Errorf("repeated submission = %d %v, want the original result replayed", w.Code, second)
+	}
+	if executions := handlers.jobs.Store().List(history.Filter{}); len(executions) != 1 {
+		t.Errorf("history has %d executions, want 1", len(executions))
+	}
+
+	if w, _ := submit("198.51.100.7:1234"); w.Code != http.StatusOK || w.Header().Get(idempotentReplayedHeader) != "" {
+		t.Errorf("submission from another client = %d, replayed = %q; want a new execution", w.Code, w.Header().Get(idempotentReplayedHeader))
+	}
+	if executions := handlers.jobs.Store().List(history.Filter{}); len(executions) != 2 {
+		t.Errorf("history has %d executions, want 2", len(executions))
+	}
+}
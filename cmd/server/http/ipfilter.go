@@ -0,0 +1,211 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go-form-app/internal/config"
+)
+
+// validationFailureEvents liste les événements de sécurité comptés pour le bannissement
+var validationFailureEvents = map[string]bool{
+	"invalid_method":        true,
+	"multipart_parse_error": true,
+	"form_parse_error":      true,
+	"json_parse_error":      true,
+	"missing_csrf_token":    true,
+	"invalid_user_id":       true,
+	"invalid_script":        true,
+	"invalid_api_token":     true,
+}
+
+type compiledAccessRule struct {
+	pathPrefix string
+	allow      []*net.IPNet
+	deny       []*net.IPNet
+}
+
+// ipFilter applique les listes d'accès par route
+type ipFilter struct {
+	rules []compiledAccessRule
+}
+
+// newIPFilter compile les règles d'accès et valide les CIDR
+func newIPFilter(rules []config.AccessRule) (*ipFilter, error) {
+	filter := &ipFilter{}
+
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule.PathPrefix, "/") {
+			return nil, fmt.Errorf("access rule path %q must start with /", rule.PathPrefix)
+		}
+
+		compiled := compiledAccessRule{pathPrefix: rule.PathPrefix}
+		for _, entry := range rule.Allow {
+			network, err := parseCIDR(strings.TrimSpace(entry))
+			if err != nil {
+				return nil, fmt.Errorf("invalid allow entry %q for %s: %w", entry, rule.PathPrefix, err)
+			}
+			compiled.allow = append(compiled.allow, network)
+		}
+		for _, entry := range rule.Deny {
+			network, err := parseCIDR(strings.TrimSpace(entry))
+			if err != nil {
+				return nil, fmt.Errorf("invalid deny entry %q for %s: %w", entry, rule.PathPrefix, err)
+			}
+			compiled.deny = append(compiled.deny, network)
+		}
+		filter.rules = append(filter.rules, compiled)
+	}
+
+	return filter, nil
+}
+
+// allowed vérifie l'IP contre toutes les règles dont le préfixe correspond au chemin :
+// une route plus spécifique ne peut donc que restreindre l'accès
+func (f *ipFilter) allowed(path, clientIP string) bool {
+	if len(f.rules) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(clientIP)
+
+	for _, rule := range f.rules {
+		if !matchesPathPrefix(path, rule.pathPrefix) {
+			continue
+		}
+		if ip == nil {
+			return false
+		}
+		if containsIP(rule.deny, ip) {
+			return false
+		}
+		if len(rule.allow) > 0 && !containsIP(rule.allow, ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesPathPrefix indique si le chemin est le préfixe lui-même ou l'un de ses
+// sous-chemins : /api couvre /api et /api/v1, mais ni /apifoo ni /api-docs
+func matchesPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// containsIP indique si l'IP appartient à l'un des réseaux
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// banTracker compte les échecs de validation par IP et bannit temporairement les récidivistes
+type banTracker struct {
+	policy config.BanPolicy
+	now    func() time.Time
+
+	mu          sync.Mutex
+	failures    map[string][]time.Time
+	bannedUntil map[string]time.Time
+	lastSweep   time.Time
+}
+
+// newBanTracker crée un tracker de bannissement selon la politique donnée
+func newBanTracker(policy config.BanPolicy) *banTracker {
+	return &banTracker{
+		policy:      policy,
+		now:         time.Now,
+		failures:    make(map[string][]time.Time),
+		bannedUntil: make(map[string]time.Time),
+	}
+}
+
+// recordFailure enregistre un échec et retourne true si l'IP vient d'être bannie
+func (b *banTracker) recordFailure(ip string) bool {
+	if b == nil || !b.policy.Enabled() {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+
+	if until, ok := b.bannedUntil[ip]; ok && now.Before(until) {
+		return false
+	}
+
+	recent := pruneBefore(b.failures[ip], now.Add(-b.policy.Window))
+	recent = append(recent, now)
+
+	if len(recent) >= b.policy.MaxFailures {
+		delete(b.failures, ip)
+		b.bannedUntil[ip] = now.Add(b.policy.Duration)
+		return true
+	}
+
+	b.failures[ip] = recent
+	return false
+}
+
+// isBanned indique si l'IP est actuellement bannie
+func (b *banTracker) isBanned(ip string) bool {
+	if b == nil || !b.policy.Enabled() {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until, ok := b.bannedUntil[ip]
+	if !ok {
+		return false
+	}
+	if b.now().Before(until) {
+		return true
+	}
+
+	delete(b.bannedUntil, ip)
+	return false
+}
+
+// sweep purge périodiquement les entrées expirées pour borner la mémoire
+func (b *banTracker) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < b.policy.Window {
+		return
+	}
+	b.lastSweep = now
+
+	cutoff := now.Add(-b.policy.Window)
+	for ip, times := range b.failures {
+		if recent := pruneBefore(times, cutoff); len(recent) == 0 {
+			delete(b.failures, ip)
+		} else {
+			b.failures[ip] = recent
+		}
+	}
+	for ip, until := range b.bannedUntil {
+		if !now.Before(until) {
+			delete(b.bannedUntil, ip)
+		}
+	}
+}
+
+// pruneBefore retire les horodatages antérieurs à cutoff
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	kept := times[:0]
+	for _, t := range times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+func TestIPFilterAllowed(t *testing.T) {
+	filter, err := newIPFilter([]config.AccessRule{
+		{PathPrefix: "/", Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.66.0.0/16"}},
+		{PathPrefix: "/run-script", Allow: []string{"10.20.0.0/16"}},
+		{PathPrefix: "/api", Allow: []string{"10.30.0.0/16"}},
+		{PathPrefix: "/static/", Deny: []string{"10.40.0.0/16"}},
+	})
+	if err != nil {
+		t.Fatalf("newIPFilter() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		ip       string
+		expected bool
+	}{
+		{"admin VLAN on form", "/", "10.1.2.3", true},
+		{"outside allowlist on form", "/", "192.168.1.1", false},
+		{"denied subnet inside allowlist", "/", "10.66.1.1", false},
+		{"strict route from allowed subnet", "/run-script", "10.20.1.1", true},
+		{"strict route from general admin VLAN", "/run-script", "10.1.2.3", false},
+		{"static assets follow root rule", "/static/style.css", "10.1.2.3", true},
+		{"unparseable IP", "/", "not-an-ip", false},
+		{"prefix rule on a subpath", "/api/v1/jobs", "10.1.2.3", false},
+		{"prefix rule on the exact path", "/api", "10.30.1.1", true},
+		{"prefix rule ignores a longer segment", "/apifoo", "10.1.2.3", true},
+		{"prefix rule ignores a hyphenated segment", "/api-docs", "10.1.2.3", true},
+		{"prefix with a trailing slash", "/static/app.js", "10.40.1.1", false},
+		{"prefix with a trailing slash without it", "/static", "10.40.1.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := filter.allowed(tt.path, tt.ip); result != tt.expected {
+				t.Errorf("allowed(%s, %s) = %v, want %v", tt.path, tt.ip, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIPFilterWithoutRules(t *testing.T) {
+	filter, err := newIPFilter(nil)
+	if err != nil {
+		t.Fatalf("newIPFilter() error = %v", err)
+	}
+	if !filter.allowed("/run-script", "203.0.113.1") {
+		t.Error("allowed() = false, want true when no rule is configured")
+	}
+}
+
+func TestNewIPFilterInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.AccessRule
+	}{
+		{"relative path", config.AccessRule{PathPrefix: "run-script", Allow: []string{"10.0.0.0/8"}}},
+		{"invalid allow CIDR", config.AccessRule{PathPrefix: "/", Allow: []string{"10.0.0.0/40"}}},
+		{"invalid deny entry", config.AccessRule{PathPrefix: "/", Deny: []string{"nope"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newIPFilter([]config.AccessRule{tt.rule}); err == nil {
+				t.Error("newIPFilter() expected error but got none")
+			}
+		})
+	}
+}
+
+func TestBanTracker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newBanTracker(config.BanPolicy{MaxFailures: 3, Window: time.Minute, Duration: 10 * time.Minute})
+	tracker.now = func() time.Time { return now }
+
+	ip := "203.0.113.1"
+	if tracker.recordFailure(ip) || tracker.recordFailure(ip) {
+		t.Fatal("recordFailure() banned before reaching MaxFailures")
+	}
+	if tracker.isBanned(ip) {
+		t.Fatal("isBanned() = true before reaching MaxFailures")
+	}
+
+	if !tracker.recordFailure(ip) {
+		t.Fatal("recordFailure() did not ban on MaxFailures")
+	}
+	if !tracker.isBanned(ip) {
+		t.Fatal("isBanned() = false after ban")
+	}
+	if tracker.isBanned("203.0.113.2") {
+		t.Error("isBanned() = true for unrelated IP")
+	}
+
+	now = now.Add(11 * time.Minute)
+	if tracker.isBanned(ip) {
+		t.Error("isBanned() = true after ban expiry")
+	}
+
+	// Les échecs hors de la fenêtre ne s'accumulent pas
+	tracker.recordFailure(ip)
+	tracker.recordFailure(ip)
+	now = now.Add(2 * time.Minute)
+	if tracker.recordFailure(ip) {
+		t.Error("recordFailure() banned with failures outside the window")
+	}
+}
+
+func TestBanTrackerDisabled(t *testing.T) {
+	var nilTracker *banTracker
+	if nilTracker.recordFailure("203.0.113.1") || nilTracker.isBanned("203.0.113.1") {
+		t.Error("nil banTracker should never ban")
+	}
+
+	tracker := newBanTracker(config.BanPolicy{})
+	for i := 0; i < 100; i++ {
+		if tracker.recordFailure("203.0.113.1") {
+			t.Fatal("disabled banTracker banned an IP")
+		}
+	}
+}
+
+func TestSecurityMiddlewareIPEnforcement(t *testing.T) {
+	server, err := NewServer(config.Config{
+		Scripts:     config.Default().Scripts,
+		AccessRules: []config.AccessRule{{PathPrefix: "/run-script", Allow: []string{"10.20.0.0/16"}}},
+		BanPolicy:   config.BanPolicy{MaxFailures: 2, Window: time.Minute, Duration: time.Minute},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server.logger = logging.New(os.Stdout, slog.LevelDebug)
+
+	handler := server.securityMiddleware(http.HandlerFunc(server.handlers.RunScriptHandler))
+
+	t.Run("IP outside route allowlist is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/run-script", nil)
+		req.RemoteAddr = "192.168.1.1:5000"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("repeated validation failures ban the IP", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/run-script", nil)
+			req.RemoteAddr = "10.20.0.5:5000"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("attempt %d status = %d, want %d", i, w.Code, http.StatusMethodNotAllowed)
+			}
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/run-script", nil)
+		req.RemoteAddr = "10.20.0.5:5000"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("status after ban = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+}
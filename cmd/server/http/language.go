@@ -0,0 +1,55 @@
+package http
+
+import (
+	"net/http"
+
+	"go-form-app/internal/i18n"
+)
+
+// La langue vient du sélecteur de l'interface (?lang=), puis du cookie, puis d'Accept-Language
+const (
+	languageParam        = "lang"
+	languageCookie       = "lang"
+	languageCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// requestLanguage détermine la langue des messages destinés au client
+func requestLanguage(r *http.Request) string {
+	if lang, ok := i18n.Default.Supported(r.URL.Query().Get(languageParam)); ok {
+		return lang
+	}
+	if cookie, err := r.Cookie(languageCookie); err == nil {
+		if lang, ok := i18n.Default.Supported(cookie.Value); ok {
+			return lang
+		}
+	}
+	return i18n.Default.Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// rememberLanguage mémorise dans un cookie la langue choisie via le sélecteur de l'interface
+func rememberLanguage(w http.ResponseWriter, r *http.Request) {
+	lang, ok := i18n.Default.Supported(r.URL.Query().Get(languageParam))
+	if !ok {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     languageCookie,
+		Value:    lang,
+		Path:     "/",
+		MaxAge:   languageCookieMaxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// setLanguageHeaders annonce la langue de la réponse et les en-têtes qui la font varier
+func setLanguageHeaders(w http.ResponseWriter, lang string) {
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language, Cookie")
+}
+
+// translate retourne le message traduit dans la langue de la requête
+func translate(r *http.Request, key string, args ...any) string {
+	return i18n.Default.T(requestLanguage(r), key, args...)
+}
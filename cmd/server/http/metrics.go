@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-form-app/internal/metrics"
+)
+
+var (
+	httpRequestsTotal = metrics.Default.NewCounterVec(
+		"goformapp_http_requests_total",
+		"Nombre de requêtes HTTP par route, méthode et code de statut.",
+		"route", "method", "status")
+
+	httpRequestDuration = metrics.Default.NewHistogramVec(
+		"goformapp_http_request_duration_seconds",
+		"Latence des requêtes HTTP en secondes par route et code de statut.",
+		metrics.DefaultDurationBuckets,
+		"route", "status")
+
+	rateLimitRejectionsTotal = metrics.Default.NewCounterVec(
+		"goformapp_rate_limit_rejections_total",
+		"Nombre de requêtes rejetées par le rate limiting, par type de client.",
+		"client")
+
+	securityEventsTotal = metrics.Default.NewCounterVec(
+		"goformapp_security_events_total",
+		"Nombre d'événements de sécurité par type.",
+		"type")
+)
+
+// statusRecorder capture le code de statut écrit par le handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader mémorise le code de statut avant de le transmettre
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write fixe le statut implicite 200 si aucun en-tête n'a été écrit
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush transmet le flush au ResponseWriter sous-jacent lorsqu'il le supporte
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap expose le ResponseWriter sous-jacent à http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// observeRequest enregistre les métriques d'une requête terminée
+func (s *Server) observeRequest(r *http.Request, status int, started time.Time) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	route := s.routeLabel(r.URL.Path)
+	statusLabel := strconv.Itoa(status)
+
+	httpRequestsTotal.Inc(route, r.Method, statusLabel)
+	httpRequestDuration.Observe(time.Since(started).Seconds(), route, statusLabel)
+}
+
+// routeLabel ramène un chemin à sa route déclarée pour borner la cardinalité des labels
+func (s *Server) routeLabel(path string) string {
+	switch {
+	case path == "/" || path == "/run-script":
+		return path
+	case strings.HasPrefix(path, "/static/"):
+		return "/static/"
+	case strings.HasPrefix(path, apiPrefix+"/"):
+		for _, route := range s.handlers.apiRoutes {
+			if _, ok := matchAPIPath(route.path, path); ok {
+				return route.path
+			}
+		}
+		return apiPrefix + "/*"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+func TestRouteLabel(t *testing.T) {
+	server, err := NewServer(config.Config{Scripts: config.Default().Scripts}, logging.New(os.Stdout, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/", "/"},
+		{"/run-script", "/run-script"},
+		{"/static/style.css", "/static/"},
+		{"/api/v1/jobs/abc123", "/api/v1/jobs/{id}"},
+		{"/api/v1/nope/nope", "/api/v1/*"},
+		{"/wp-admin.php", "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := server.routeLabel(tt.path); got != tt.expected {
+				t.Errorf("routeLabel(%s) = %s, want %s", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSecurityMiddlewareMetrics(t *testing.T) {
+	server, err := NewServer(config.Config{
+		Scripts:   config.Default().Scripts,
+		RateLimit: config.RateLimitConfig{RequestsPerSecond: 0.001, Burst: 1},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	handler := server.securityMiddleware(http.HandlerFunc(server.handlers.RunScriptHandler))
+
+	requestsBefore := httpRequestsTotal.Value("/run-script", http.MethodGet, "405")
+	eventsBefore := securityEventsTotal.Value("invalid_method")
+	rejectionsBefore := rateLimitRejectionsTotal.Value("ip")
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/run-script", nil)
+		req.RemoteAddr = "203.0.113.77:5000"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := httpRequestsTotal.Value("/run-script", http.MethodGet, "405") - requestsBefore; got != 1 {
+		t.Errorf("405 requests counted = %v, want 1", got)
+	}
+	if got := securityEventsTotal.Value("invalid_method") - eventsBefore; got != 1 {
+		t.Errorf("invalid_method events counted = %v, want 1", got)
+	}
+	if got := rateLimitRejectionsTotal.Value("ip") - rejectionsBefore; got != 1 {
+		t.Errorf("rate limit rejections counted = %v, want 1", got)
+	}
+}
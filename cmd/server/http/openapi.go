@@ -0,0 +1,203 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPIVersion est la version de l'API documentée
+const openAPIVersion = "1.0.0"
+
+// buildOpenAPISpec génère le document OpenAPI 3 à partir de la table des routes
+// et des structures de requête/réponse
+func buildOpenAPISpec(routes []apiRoute) map[string]interface{} {
+	schemas := make(map[string]interface{})
+	errorRef := schemaRef(reflect.TypeOf(APIErrorResponse{}), schemas)
+
+	paths := make(map[string]interface{})
+	for _, route := range routes {
+		operation := map[string]interface{}{
+			"operationId": route.operationID,
+			"summary":     route.summary,
+		}
+
+		var parameters []interface{}
+		for _, segment := range strings.Split(route.path, "/") {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+				parameters = append(parameters, map[string]interface{}{
+					"name":     strings.Trim(segment, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		for _, param := range route.query {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        param.name,
+				"in":          "query",
+				"description": param.description,
+				"schema":      map[string]interface{}{"type": param.kind},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		if route.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					mediaTypeOrJSON(route.requestMediaType): map[string]interface{}{
+						"schema": schemaRef(reflect.TypeOf(route.request), schemas),
+					},
+				},
+			}
+		}
+
+		success := map[string]interface{}{"description": http.StatusText(route.status)}
+		if route.responseMediaType != "" && route.response == nil {
+			success["content"] = map[string]interface{}{
+				route.responseMediaType: map[string]interface{}{
+					"schema": map[string]interface{}{"type": "string"},
+				},
+			}
+		} else if route.response != nil {
+			success["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": schemaRef(reflect.TypeOf(route.response), schemas),
+				},
+			}
+		} else if route.status != http.StatusNoContent {
+			success["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "object"},
+				},
+			}
+		}
+
+		operation["responses"] = map[string]interface{}{
+			strconv.Itoa(route.status): success,
+			"default": map[string]interface{}{
+				"description": "Erreur",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": errorRef},
+				},
+			},
+		}
+
+		item, ok := paths[route.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[route.path] = item
+		}
+		item[strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Go Form App API",
+			"version":     openAPIVersion,
+			"description": "API d'exécution sécurisée des scripts d'attribution de droits",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
+
+// mediaTypeOrJSON retourne le type de contenu déclaré, application/json par défaut
+func mediaTypeOrJSON(mediaType string) string {
+	if mediaType == "" {
+		return "application/json"
+	}
+	return mediaType
+}
+
+// schemaRef enregistre le schéma d'une structure dans components et retourne sa référence
+func schemaRef(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return schemaFor(t, schemas)
+	}
+
+	if _, exists := schemas[t.Name()]; !exists {
+		// Réserver le nom avant la récursion pour supporter les types récursifs
+		schemas[t.Name()] = nil
+		schemas[t.Name()] = structSchema(t, schemas)
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+}
+
+// structSchema décrit une structure à partir de ses tags json
+func structSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaRef(field.Type, schemas)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schemaFor décrit un type non structuré
+func schemaFor(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		// Objet JSON libre, par exemple le résultat structuré d'un script
+		return map[string]interface{}{"type": "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaRef(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaRef(t.Elem(), schemas)}
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"sync"
+	"time"
+
+	"go-form-app/internal/config"
+)
+
+// rateLimitIdleTTL est la durée après laquelle un compteur inactif est oublié
+const rateLimitIdleTTL = 10 * time.Minute
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter applique un seau à jetons par clé client
+type rateLimiter struct {
+	config config.RateLimitConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// newRateLimiter crée un limiteur de débit selon la configuration
+func newRateLimiter(cfg config.RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		config:  cfg,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow consomme un jeton pour la clé et indique si la requête est autorisée
+func (l *rateLimiter) allow(key string) bool {
+	if l == nil || !l.config.Enabled() {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.config.Burst), lastSeen: now}
+		l.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens += elapsed * l.config.RequestsPerSecond
+	if bucket.tokens > float64(l.config.Burst) {
+		bucket.tokens = float64(l.config.Burst)
+	}
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// sweep oublie les clients inactifs pour borner la mémoire
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitIdleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.lastSeen) > rateLimitIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
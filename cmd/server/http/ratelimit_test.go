@@ -0,0 +1,48 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(config.RateLimitConfig{RequestsPerSecond: 1, Burst: 3})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !limiter.allow("ip:203.0.113.1") {
+			t.Fatalf("allow() rejected request %d within burst", i)
+		}
+	}
+	if limiter.allow("ip:203.0.113.1") {
+		t.Error("allow() accepted request beyond burst")
+	}
+	if !limiter.allow("ip:203.0.113.2") {
+		t.Error("allow() rejected an unrelated client")
+	}
+
+	now = now.Add(time.Second)
+	if !limiter.allow("ip:203.0.113.1") {
+		t.Error("allow() did not refill after one second")
+	}
+	if limiter.allow("ip:203.0.113.1") {
+		t.Error("allow() refilled more than the configured rate")
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	var nilLimiter *rateLimiter
+	if !nilLimiter.allow("ip:203.0.113.1") {
+		t.Error("nil rateLimiter should allow every request")
+	}
+
+	limiter := newRateLimiter(config.RateLimitConfig{})
+	for i := 0; i < 100; i++ {
+		if !limiter.allow("ip:203.0.113.1") {
+			t.Fatal("disabled rateLimiter rejected a request")
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-form-app/internal/logging"
+	"go-form-app/internal/schedule"
+)
+
+// ScheduleRequest est le corps JSON d'une demande de planification : runAt pour une
+// exécution unique, cron pour une exécution récurrente
+type ScheduleRequest struct {
+	Script string     `json:"script"`
+	UserID string     `json:"userId"`
+	RunAt  *time.Time `json:"runAt,omitempty"`
+	Cron   string     `json:"cron,omitempty"`
+}
+
+// Schedule décrit une planification et son dernier déclenchement
+type Schedule struct {
+	ID       string     `json:"id"`
+	Script   string     `json:"script"`
+	UserID   string     `json:"userId"`
+	Operator string     `json:"operator"`
+	Status   string     `json:"status"`
+	RunAt    *time.Time `json:"runAt,omitempty"`
+	Cron     string     `json:"cron,omitempty"`
+	NextRun  *time.Time `json:"nextRun,omitempty"`
+	LastRun  *time.Time `json:"lastRun,omitempty"`
+	// LastExecutionID référence la dernière exécution déclenchée dans l'historique
+	LastExecutionID string    `json:"lastExecutionId,omitempty"`
+	LastError       string    `json:"lastError,omitempty"`
+	Runs            int       `json:"runs"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// ScheduleListResponse liste les planifications, de la plus ancienne à la plus récente
+type ScheduleListResponse struct {
+	Schedules []Schedule `json:"schedules"`
+}
+
+// apiListSchedules liste les planifications
+func (h *Handlers) apiListSchedules(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	token, hasToken := getAPIToken(r)
+	response := ScheduleListResponse{Schedules: []Schedule{}}
+	for _, s := range h.scheduler.List() {
+		// Un token d'API ne voit que les planifications des scripts qu'il couvre
+		if hasToken && !token.Allows(s.Script) {
+			continue
+		}
+		response.Schedules = append(response.Schedules, newSchedule(s))
+	}
+	h.sendAPIJSON(w, http.StatusOK, response)
+}
+
+// scheduleErrorMessage traduit le motif de rejet d'une planification dans la langue de la requête
+func scheduleErrorMessage(r *http.Request, err error) string {
+	switch {
+	case errors.Is(err, schedule.ErrInvalidCron):
+		return translate(r, "schedule.invalid_cron")
+	case errors.Is(err, schedule.ErrCronNeverMatches):
+		return translate(r, "schedule.cron_never_matches")
+	case errors.Is(err, schedule.ErrTimingConflict):
+		return translate(r, "schedule.timing_conflict")
+	case errors.Is(err, schedule.ErrTimingRequired):
+		return translate(r, "schedule.timing_required")
+	case errors.Is(err, schedule.ErrRunAtPast):
+		return translate(r, "schedule.run_at_past")
+	default:
+		return translate(r, "schedule.request_rejected")
+	}
+}
+
+// apiCreateSchedule valide et enregistre une planification
+func (h *Handlers) apiCreateSchedule(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var body ScheduleRequest
+	if !h.decodeAPIJSON(w, r, &body) {
+		return
+	}
+
+	body.UserID = strings.TrimSpace(body.UserID)
+	body.Script = strings.TrimSpace(body.Script)
+
+	if !h.validateUserID(body.UserID) {
+		h.logSecurityEvent(r, "invalid_user_id", body.UserID)
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidUserID)
+		return
+	}
+	if !h.validateScript(body.Script) {
+		h.logSecurityEvent(r, "invalid_script", body.Script)
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidScript)
+		return
+	}
+	if !h.authorizeExecution(w, r, body.Script) {
+		return
+	}
+
+	req := schedule.Request{
+		Script:   body.Script,
+		UserID:   body.UserID,
+		Operator: getOperator(r),
+		Cron:     body.Cron,
+	}
+	if body.RunAt != nil {
+		req.RunAt = *body.RunAt
+	}
+
+	created, err := h.scheduler.Create(r.Context(), req)
+	if errors.Is(err, schedule.ErrInvalid) {
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidSchedule, scheduleErrorMessage(r, err))
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "schedule creation failed", logging.KeyCategory, logging.CategorySchedule, "error", err)
+		h.sendAPIError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return
+	}
+
+	h.logSecurityEvent(r, "schedule_created",
+		fmt.Sprintf("schedule:%s user:%s script:%s run_at:%s cron:%q", created.ID, created.UserID, created.Script, created.NextRun.Format(time.RFC3339), created.Cron))
+	w.Header().Set("Location", apiPrefix+"/schedules/"+created.ID)
+	h.sendAPIJSON(w, http.StatusCreated, newSchedule(created))
+}
+
+// apiGetSchedule retourne une planification
+func (h *Handlers) apiGetSchedule(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	s, ok := h.scheduler.Get(params["id"])
+	if !ok {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	}
+	if !h.checkTokenScope(w, r, s.Script) {
+		return
+	}
+	h.sendAPIJSON(w, http.StatusOK, newSchedule(s))
+}
+
+// apiPauseSchedule suspend une planification
+func (h *Handlers) apiPauseSchedule(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	h.updateSchedule(w, r, params["id"], "schedule_paused", h.scheduler.Pause)
+}
+
+// apiResumeSchedule réactive une planification suspendue
+func (h *Handlers) apiResumeSchedule(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	h.updateSchedule(w, r, params["id"], "schedule_resumed", h.scheduler.Resume)
+}
+
+// apiDeleteSchedule supprime une planification
+func (h *Handlers) apiDeleteSchedule(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	s, ok := h.authorizeSchedule(w, r, params["id"])
+	if !ok {
+		return
+	}
+
+	err := h.scheduler.Delete(s.ID)
+	if errors.Is(err, schedule.ErrNotFound) {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "schedule deletion failed", logging.KeyCategory, logging.CategorySchedule, "error", err)
+		h.sendAPIError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return
+	}
+
+	h.logSecurityEvent(r, "schedule_deleted", fmt.Sprintf("schedule:%s user:%s script:%s", s.ID, s.UserID, s.Script))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateSchedule applique une suspension ou une reprise et retourne la planification modifiée
+func (h *Handlers) updateSchedule(w http.ResponseWriter, r *http.Request, id, event string, update func(string) (schedule.Schedule, error)) {
+	if _, ok := h.authorizeSchedule(w, r, id); !ok {
+		return
+	}
+
+	updated, err := update(id)
+	switch {
+	case errors.Is(err, schedule.ErrNotFound):
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	case errors.Is(err, schedule.ErrCompleted):
+		h.sendAPIError(w, r, http.StatusConflict, ErrCodeScheduleCompleted)
+		return
+	case err != nil:
+		h.logger.ErrorContext(r.Context(), "schedule update failed", logging.KeyCategory, logging.CategorySchedule, "error", err)
+		h.sendAPIError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return
+	}
+
+	h.logSecurityEvent(r, event, fmt.Sprintf("schedule:%s user:%s script:%s", updated.ID, updated.UserID, updated.Script))
+	h.sendAPIJSON(w, http.StatusOK, newSchedule(updated))
+}
+
+// authorizeSchedule vérifie le jeton CSRF ou la portée du token d'API avant de modifier
+// une planification ; en cas d'échec la réponse d'erreur est déjà envoyée
+func (h *Handlers) authorizeSchedule(w http.ResponseWriter, r *http.Request, id string) (schedule.Schedule, bool) {
+	s, ok := h.scheduler.Get(id)
+	if !ok {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return schedule.Schedule{}, false
+	}
+	if !h.authorizeExecution(w, r, s.Script) {
+		return schedule.Schedule{}, false
+	}
+	return s, true
+}
+
+// newSchedule convertit une planification en réponse d'API
+func newSchedule(s schedule.Schedule) Schedule {
+	response := Schedule{
+		ID:              s.ID,
+		Script:          s.Script,
+		UserID:          s.UserID,
+		Operator:        s.Operator,
+		Status:          string(s.Status),
+		Cron:            s.Cron,
+		LastExecutionID: s.LastExecutionID,
+		LastError:       s.LastError,
+		Runs:            s.Runs,
+		CreatedAt:       s.CreatedAt,
+	}
+	if !s.RunAt.IsZero() {
+		runAt := s.RunAt
+		response.RunAt = &runAt
+	}
+	if !s.NextRun.IsZero() {
+		nextRun := s.NextRun
+		response.NextRun = &nextRun
+	}
+	if !s.LastRun.IsZero() {
+		lastRun := s.LastRun
+		response.LastRun = &lastRun
+	}
+	return response
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-form-app/internal/auth"
+	"go-form-app/internal/jobs"
+)
+
+func TestScheduleAPI(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	headers := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"}
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name         string
+		body         string
+		headers      map[string]string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"missing CSRF token", `{"script":"grant.sh","userId":"test123","cron":"@daily"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, ErrCodeMissingCSRFToken},
+		{"invalid user", `{"script":"grant.sh","userId":"bad","cron":"@daily"}`, headers, http.StatusBadRequest, ErrCodeInvalidUserID},
+		{"invalid script", `{"script":"other.sh","userId":"test123","cron":"@daily"}`, headers, http.StatusBadRequest, ErrCodeInvalidScript},
+		{"invalid cron", `{"script":"grant.sh","userId":"test123","cron":"every night"}`, headers, http.StatusBadRequest, ErrCodeInvalidSchedule},
+		{"past date", `{"script":"grant.sh","userId":"test123","runAt":"2020-01-01T00:00:00Z"}`, headers, http.StatusBadRequest, ErrCodeInvalidSchedule},
+		{"no timing", `{"script":"grant.sh","userId":"test123"}`, headers, http.StatusBadRequest, ErrCodeInvalidSchedule},
+		{"one-off", `{"script":"grant.sh","userId":"test123","runAt":"` + future + `"}`, headers, http.StatusCreated, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doAPIRequest(handlers, http.MethodPost, "/api/v1/schedules", tt.body, tt.headers)
+			if w.Code != tt.expectedCode {
+				t.Fatalf("create status = %d, want %d: %s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if tt.expectedErr != "" {
+				var response APIErrorResponse
+				json.Unmarshal(w.Body.Bytes(), &response)
+				if response.Error.Code != tt.expectedErr {
+					t.Errorf("error code = %s, want %s", response.Error.Code, tt.expectedErr)
+				}
+			}
+		})
+	}
+
+	w := doAPIRequest(handlers, http.MethodPost, "/api/v1/schedules", `{"script":"grant.sh","userId":"test123","cron":"0 2 * * *"}`, headers)
+	var created Schedule
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/api/v1/schedules/"+created.ID || created.NextRun == nil || created.NextRun.Hour() != 2 {
+		t.Fatalf("create recurring = %d %+v", w.Code, created)
+	}
+
+	var list ScheduleListResponse
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/schedules", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Schedules) != 2 || list.Schedules[1].ID != created.ID || list.Schedules[1].Operator != "anonymous" {
+		t.Errorf("list = %+v, want both schedules in creation order", list.Schedules)
+	}
+
+	var paused Schedule
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/schedules/"+created.ID+"/pause", "", headers)
+	json.Unmarshal(w.Body.Bytes(), &paused)
+	if w.Code != http.StatusOK || paused.Status != "paused" {
+		t.Errorf("pause = %d %+v", w.Code, paused)
+	}
+
+	var resumed Schedule
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/schedules/"+created.ID+"/resume", "", headers)
+	json.Unmarshal(w.Body.Bytes(), &resumed)
+	if w.Code != http.StatusOK || resumed.Status != "active" {
+		t.Errorf("resume = %d %+v", w.Code, resumed)
+	}
+
+	if w := doAPIRequest(handlers, http.MethodDelete, "/api/v1/schedules/"+created.ID, "", headers); w.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d, want 204", w.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := doAPIRequest(handlers, method, "/api/v1/schedules/"+created.ID, "", headers); w.Code != http.StatusNotFound {
+			t.Errorf("%s deleted schedule status = %d, want 404", method, w.Code)
+		}
+	}
+}
+
+func TestScheduleAPIErrorMessages(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		lang     string
+		expected string
+	}{
+		{"invalid cron", `{"script":"grant.sh","userId":"test123","cron":"every night"}`, "fr", "Planification invalide : l'expression cron est invalide (5 champs ou une macro comme @daily)"},
+		{"past date", `{"script":"grant.sh","userId":"test123","runAt":"2020-01-01T00:00:00Z"}`, "fr", "Planification invalide : runAt doit être dans le futur"},
+		{"no timing", `{"script":"grant.sh","userId":"test123"}`, "en", "Invalid schedule: runAt or cron is required"},
+		{"cron never matches", `{"script":"grant.sh","userId":"test123","cron":"0 0 30 2 *"}`, "en", "Invalid schedule: the cron expression never matches any date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token", "Accept-Language": tt.lang}
+			w := doAPIRequest(handlers, http.MethodPost, "/api/v1/schedules", tt.body, headers)
+			var response APIErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != http.StatusBadRequest || response.Error.Message != tt.expected {
+				t.Errorf("create = %d %q, want %d %q", w.Code, response.Error.Message, http.StatusBadRequest, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScheduleAPIRunsInHistory(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	handlers.scheduler.Start()
+	defer handlers.scheduler.Stop()
+	headers := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"}
+
+	runAt := time.Now().Add(100 * time.Millisecond).Format(time.RFC3339Nano)
+	w := doAPIRequest(handlers, http.MethodPost, "/api/v1/schedules", `{"script":"grant.sh","userId":"test123","runAt":"`+runAt+`"}`, headers)
+	var created Schedule
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var completed Schedule
+	deadline := time.Now().Add(3 * time.Second)
+	for completed.LastExecutionID == "" {
+		if time.Now().After(deadline) {
+			t.Fatalf("schedule never ran: %+v", completed)
+		}
+		time.Sleep(10 * time.Millisecond)
+		w = doAPIRequest(handlers, http.MethodGet, "/api/v1/schedules/"+created.ID, "", nil)
+		json.Unmarshal(w.Body.Bytes(), &completed)
+	}
+	handlers.jobs.Wait()
+
+	var job Execution
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/"+completed.LastExecutionID, "", nil)
+	json.Unmarshal(w.Body.Bytes(), &job)
+	if job.Source != jobs.SourceSchedule || job.Status != "succeeded" || completed.Status != "completed" {
+		t.Errorf("scheduled job = %+v, schedule = %+v", job, completed)
+	}
+
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/schedules/"+created.ID+"/pause", "", headers)
+	var response APIErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusConflict || response.Error.Code != ErrCodeScheduleCompleted {
+		t.Errorf("pause completed schedule = %d %s, want 409 %s", w.Code, response.Error.Code, ErrCodeScheduleCompleted)
+	}
+}
+
+func TestScheduleAPITokenScope(t *testing.T) {
+	handlers := newTestAPIHandlers(t)
+	scoped := auth.Token{ID: "t1", Name: "ticketing", Scripts: []string{"grant.sh"}}
+	other := auth.Token{ID: "t2", Name: "reporting", Scripts: []string{"other.sh"}}
+	body := `{"script":"grant.sh","userId":"test123","cron":"@daily"}`
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	if w := doAuthenticatedRequest(handlers, other, http.MethodPost, "/api/v1/schedules", body, headers); w.Code != http.StatusForbidden {
+		t.Errorf("create out of scope status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w := doAuthenticatedRequest(handlers, scoped, http.MethodPost, "/api/v1/schedules", body, headers)
+	var created Schedule
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create in scope status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	for _, op := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/schedules/" + created.ID + "/pause"},
+		{http.MethodPost, "/api/v1/schedules/" + created.ID + "/resume"},
+		{http.MethodDelete, "/api/v1/schedules/" + created.ID},
+	} {
+		if w := doAuthenticatedRequest(handlers, other, op.method, op.path, "", nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s out of scope status = %d, want %d", op.method, op.path, w.Code, http.StatusForbidden)
+		}
+	}
+	if w := doAuthenticatedRequest(handlers, scoped, http.MethodDelete, "/api/v1/schedules/"+created.ID, "", nil); w.Code != http.StatusNoContent {
+		t.Errorf("delete in scope status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"go-form-app/internal/logging"
+)
+
+// shutdownCloseGrace laisse aux réponses des exécutions interrompues le temps de partir
+const shutdownCloseGrace = 5 * time.Second
+
+// Shutdown arrête le serveur proprement : plus aucune connexion ni exécution n'est
+// acceptée, les scripts en cours sont attendus jusqu'à l'échéance de ctx puis annulés
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	server := s.httpServer
+	s.mu.Unlock()
+
+	s.logger.Info("stopping server, draining running executions", logging.KeyCategory, logging.CategoryShutdown)
+
+	httpDone := make(chan error, 1)
+	if server != nil {
+		go func() { httpDone <- server.Shutdown(context.Background()) }()
+	} else {
+		httpDone <- nil
+	}
+
+	// Plus aucune planification ne doit se déclencher pendant l'attente des scripts
+	s.handlers.scheduler.Stop()
+	interrupted := s.handlers.executor.Drain(ctx)
+	s.handlers.bulk.Wait()
+	s.handlers.workflows.Wait()
+	s.handlers.jobs.Wait()
+
+	if len(interrupted) > 0 {
+		s.logger.Warn("executions interrupted before completion", logging.KeyCategory, logging.CategoryShutdown, "count", len(interrupted))
+	} else {
+		s.logger.Info("all executions completed", logging.KeyCategory, logging.CategoryShutdown)
+	}
+
+	select {
+	case err := <-httpDone:
+		return err
+	case <-time.After(shutdownCloseGrace):
+		s.logger.Warn("closing remaining connections", logging.KeyCategory, logging.CategoryShutdown)
+		return server.Close()
+	}
+}
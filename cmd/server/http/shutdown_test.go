@@ -0,0 +1,34 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+func TestShutdownRejectsNewExecutions(t *testing.T) {
+	server, err := NewServer(config.Config{Scripts: config.Default().Scripts}, logging.New(os.Stdout, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	server.handlers = newTestAPIHandlers(t)
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	_, err = server.handlers.jobs.Run(context.Background(), scripts.ExecutionRequest{
+		UserID: "test123",
+		Script: "grant.sh",
+	}, jobs.SourceAPI)
+	if !errors.Is(err, scripts.ErrShuttingDown) {
+		t.Errorf("Run() after shutdown error = %v, want ErrShuttingDown", err)
+	}
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+// certReloadInterval limite la fréquence de vérification des fichiers de certificat
+const certReloadInterval = 5 * time.Second
+
+// certReloader recharge le certificat serveur lorsque les fichiers changent sur disque
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader charge le certificat initial et prépare le rechargement à chaud
+func newCertReloader(certFile, keyFile string, logger *slog.Logger) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate fournit le certificat courant à chaque handshake TLS
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// maybeReload recharge le certificat si l'un des fichiers a été modifié
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < certReloadInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	certMod, keyMod := r.certMod, r.keyMod
+	r.mu.Unlock()
+
+	newCertMod, newKeyMod, err := r.modTimes()
+	if err != nil {
+		r.logger.Error("TLS certificate stat failed, keeping current certificate", logging.KeyCategory, logging.CategoryTLS, "error", err)
+		return
+	}
+	if newCertMod.Equal(certMod) && newKeyMod.Equal(keyMod) {
+		return
+	}
+
+	if err := r.reload(); err != nil {
+		r.logger.Error("TLS certificate reload failed, keeping current certificate", logging.KeyCategory, logging.CategoryTLS, "error", err)
+		return
+	}
+	r.logger.Info("TLS certificate reloaded", logging.KeyCategory, logging.CategoryTLS, "cert_file", r.certFile)
+}
+
+// reload lit la paire certificat/clé depuis le disque
+func (r *certReloader) reload() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading key pair: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.lastCheck = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// modTimes retourne les dates de modification du certificat et de la clé
+func (r *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// buildTLSConfig construit la configuration TLS du serveur, avec mTLS optionnel
+func buildTLSConfig(cfg config.TLSConfig, logger *slog.Logger) (*tls.Config, error) {
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if cfg.MutualTLS() {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificate found in client CA bundle %s", cfg.ClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+// writeTestCertificate génère un certificat auto-signé et l'écrit dans dir
+func writeTestCertificate(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "server")
+
+	t.Run("server certificate only", func(t *testing.T) {
+		cfg, err := buildTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile}, logger)
+		if err != nil {
+			t.Fatalf("buildTLSConfig() error = %v", err)
+		}
+		if cfg.ClientAuth != tls.NoClientCert {
+			t.Errorf("buildTLSConfig() ClientAuth = %v, want NoClientCert", cfg.ClientAuth)
+		}
+		if cfg.MinVersion != tls.VersionTLS12 {
+			t.Errorf("buildTLSConfig() MinVersion = %x, want TLS 1.2", cfg.MinVersion)
+		}
+	})
+
+	t.Run("client CA bundle enables verification", func(t *testing.T) {
+		cfg, err := buildTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile}, logger)
+		if err != nil {
+			t.Fatalf("buildTLSConfig() error = %v", err)
+		}
+		if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+			t.Errorf("buildTLSConfig() ClientAuth = %v, want RequireAndVerifyClientCert", cfg.ClientAuth)
+		}
+		if cfg.ClientCAs == nil {
+			t.Error("buildTLSConfig() ClientCAs is nil")
+		}
+	})
+
+	t.Run("invalid client CA bundle", func(t *testing.T) {
+		badCA := filepath.Join(dir, "bad-ca.pem")
+		os.WriteFile(badCA, []byte("not a certificate"), 0o600)
+
+		if _, err := buildTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: badCA}, logger); err == nil {
+			t.Error("buildTLSConfig() expected error for invalid CA bundle")
+		}
+	})
+
+	t.Run("missing key pair", func(t *testing.T) {
+		if _, err := buildTLSConfig(config.TLSConfig{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}, logger); err == nil {
+			t.Error("buildTLSConfig() expected error for missing certificate")
+		}
+	})
+}
+
+func TestCertReloader(t *testing.T) {
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "first")
+
+	reloader, err := newCertReloader(certFile, keyFile, logger)
+	if err != nil {
+		t.Fatalf("newCertReloader() error = %v", err)
+	}
+
+	first, _ := reloader.GetCertificate(nil)
+	if first == nil {
+		t.Fatal("GetCertificate() returned nil certificate")
+	}
+
+	writeTestCertificate(t, dir, "second")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	os.Chtimes(keyFile, future, future)
+
+	// Forcer la vérification sans attendre l'intervalle de rechargement
+	reloader.mu.Lock()
+	reloader.lastCheck = time.Time{}
+	reloader.mu.Unlock()
+
+	second, _ := reloader.GetCertificate(nil)
+	leaf, err := x509.ParseCertificate(second.Certificate[0])
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	if leaf.Subject.CommonName != "second" {
+		t.Errorf("GetCertificate() CommonName = %s, want second", leaf.Subject.CommonName)
+	}
+
+	// Un fichier invalide ne doit pas remplacer le certificat courant
+	os.WriteFile(certFile, []byte("broken"), 0o600)
+	later := future.Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	reloader.mu.Lock()
+	reloader.lastCheck = time.Time{}
+	reloader.mu.Unlock()
+
+	current, _ := reloader.GetCertificate(nil)
+	if current != second {
+		t.Error("GetCertificate() replaced certificate with an invalid one")
+	}
+}
@@ -0,0 +1,224 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"go-form-app/internal/i18n"
+	"go-form-app/internal/logging"
+)
+
+//go:embed web/templates web/static
+var embeddedWeb embed.FS
+
+// Emplacements des ressources web, relatifs à la racine des assets (embarquée ou répertoire de dev)
+const (
+	templatesDir     = "templates"
+	templatesPattern = templatesDir + "/*.html"
+	staticDir        = "static"
+	formTemplate     = "form.html"
+)
+
+// templateFuncs expose aux templates la traduction des messages : {{t .Lang "ui.title"}}
+var templateFuncs = template.FuncMap{
+	"t": i18n.Default.T,
+}
+
+// Politiques de cache des fichiers statiques
+const (
+	staticCacheControl    = "public, max-age=3600"
+	devStaticCacheControl = "no-cache"
+)
+
+// webAssets sert les templates et les fichiers statiques, embarqués dans le binaire
+// ou lus depuis un répertoire de développement rechargé à chaud
+type webAssets struct {
+	root   fs.FS
+	dev    bool
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	templates *template.Template
+	signature string
+	etags     map[string]string
+}
+
+// newEmbeddedWebAssets charge les assets compilés dans le binaire
+func newEmbeddedWebAssets(logger *slog.Logger) (*webAssets, error) {
+	root, err := fs.Sub(embeddedWeb, "web")
+	if err != nil {
+		return nil, err
+	}
+	return newWebAssets(root, false, logger)
+}
+
+// newDevWebAssets lit les assets depuis dir, rechargés à chaque modification
+func newDevWebAssets(dir string, logger *slog.Logger) (*webAssets, error) {
+	if err := checkDirectory(dir); err != nil {
+		return nil, fmt.Errorf("web dev dir: %w", err)
+	}
+	return newWebAssets(os.DirFS(dir), true, logger)
+}
+
+// newWebAssets parse les templates et calcule les ETag une fois pour toutes
+func newWebAssets(root fs.FS, dev bool, logger *slog.Logger) (*webAssets, error) {
+	assets := &webAssets{root: root, dev: dev, logger: logger}
+	if _, err := assets.Templates(); err != nil {
+		return nil, err
+	}
+	if !dev {
+		etags, err := hashStaticFiles(root)
+		if err != nil {
+			return nil, err
+		}
+		assets.etags = etags
+	}
+	return assets, nil
+}
+
+// Templates retourne les templates parsés, relus en mode dev si un fichier a changé
+func (a *webAssets) Templates() (*template.Template, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.templates != nil && !a.dev {
+		return a.templates, nil
+	}
+
+	signature, err := a.templatesSignature()
+	if err != nil {
+		return nil, err
+	}
+	if a.templates != nil && signature == a.signature {
+		return a.templates, nil
+	}
+
+	tmpl, err := template.New("").Funcs(templateFuncs).ParseFS(a.root, templatesPattern)
+	if err != nil {
+		return nil, fmt.Errorf("parse templates: %w", err)
+	}
+	if a.templates != nil {
+		a.logger.Info("templates reloaded", logging.KeyCategory, logging.CategoryHTTP)
+	}
+	a.templates = tmpl
+	a.signature = signature
+	return tmpl, nil
+}
+
+// templatesSignature résume les noms, tailles et dates des templates pour détecter les changements
+func (a *webAssets) templatesSignature() (string, error) {
+	if !a.dev {
+		return "", nil
+	}
+
+	matches, err := fs.Glob(a.root, templatesPattern)
+	if err != nil {
+		return "", err
+	}
+	var signature strings.Builder
+	for _, name := range matches {
+		info, err := fs.Stat(a.root, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&signature, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return signature.String(), nil
+}
+
+// Render exécute un template dans un tampon pour ne rien envoyer en cas d'erreur
+func (a *webAssets) Render(w io.Writer, name string, data interface{}) error {
+	tmpl, err := a.Templates()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// CheckStatic vérifie la présence du répertoire des fichiers statiques
+func (a *webAssets) CheckStatic() error {
+	info, err := fs.Stat(a.root, staticDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", staticDir)
+	}
+	return nil
+}
+
+// StaticHandler sert les fichiers statiques avec ETag et Cache-Control
+func (a *webAssets) StaticHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + strings.TrimPrefix(r.URL.Path, "/static/"))
+		name = staticDir + name
+
+		file, err := a.root.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		content, ok := file.(io.ReadSeeker)
+		if !ok {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if a.dev {
+			w.Header().Set("Cache-Control", devStaticCacheControl)
+			w.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+		} else {
+			w.Header().Set("Cache-Control", staticCacheControl)
+			w.Header().Set("ETag", a.etags[name])
+		}
+
+		// ServeContent gère If-None-Match et répond 304 quand l'ETag correspond
+		http.ServeContent(w, r, info.Name(), info.ModTime(), content)
+	})
+}
+
+// hashStaticFiles calcule l'ETag de chaque fichier statique à partir de son contenu
+func hashStaticFiles(root fs.FS) (map[string]string, error) {
+	etags := make(map[string]string)
+	err := fs.WalkDir(root, staticDir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(root, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		etags[name] = `"` + hex.EncodeToString(sum[:16]) + `"`
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hash static files: %w", err)
+	}
+	return etags, nil
+}
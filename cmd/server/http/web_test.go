@@ -0,0 +1,130 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+// newTestWebDir crée un répertoire de développement minimal (templates/, static/)
+func newTestWebDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "templates", "form.html"), "<p>v1 {{ .CSRFToken }}</p>")
+	writeTestFile(t, filepath.Join(dir, "static", "style.css"), "body { color: black; }")
+	return dir
+}
+
+// writeTestFile écrit un fichier en créant ses répertoires parents
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmbeddedStaticHandler(t *testing.T) {
+	web, err := newEmbeddedWebAssets(logging.New(os.Stdout, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("newEmbeddedWebAssets() error = %v", err)
+	}
+	handler := web.StaticHandler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/style.css", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Errorf("ETag = %q, want a strong content hash", etag)
+	}
+	if got := w.Header().Get("Cache-Control"); got != staticCacheControl {
+		t.Errorf("Cache-Control = %q, want %q", got, staticCacheControl)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("Content-Type = %q, want text/css", w.Header().Get("Content-Type"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/static/style.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional request status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	for _, path := range []string{"/static/missing.css", "/static/../templates/form.html", "/static/fonts"} {
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s status = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestDevWebAssetsReload(t *testing.T) {
+	dir := newTestWebDir(t)
+	web, err := newDevWebAssets(dir, logging.New(os.Stdout, slog.LevelDebug))
+	if err != nil {
+		t.Fatalf("newDevWebAssets() error = %v", err)
+	}
+
+	render := func() string {
+		var out strings.Builder
+		if err := web.Render(&out, formTemplate, struct{ CSRFToken string }{"tok"}); err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		return out.String()
+	}
+
+	if got := render(); got != "<p>v1 tok</p>" {
+		t.Errorf("Render() = %q", got)
+	}
+
+	path := filepath.Join(dir, "templates", "form.html")
+	writeTestFile(t, path, "<p>v2 {{ .CSRFToken }}</p>")
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if got := render(); got != "<p>v2 tok</p>" {
+		t.Errorf("Render() after edit = %q, want reloaded template", got)
+	}
+
+	w := httptest.NewRecorder()
+	web.StaticHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/style.css", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "body { color: black; }" {
+		t.Errorf("static response = %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Cache-Control"); got != devStaticCacheControl {
+		t.Errorf("Cache-Control = %q, want %q", got, devStaticCacheControl)
+	}
+}
+
+func TestNewServerWebDevDir(t *testing.T) {
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+
+	if _, err := NewServer(config.Config{Scripts: config.Default().Scripts, Web: config.WebConfig{DevDir: filepath.Join(t.TempDir(), "missing")}}, logger); err == nil {
+		t.Error("NewServer() expected error for a missing dev directory")
+	}
+
+	server, err := NewServer(config.Config{Scripts: config.Default().Scripts, Web: config.WebConfig{DevDir: newTestWebDir(t)}}, logger)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	if !server.handlers.web.dev {
+		t.Error("NewServer() did not switch to the dev directory")
+	}
+}
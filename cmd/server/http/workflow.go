@@ -0,0 +1,131 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-form-app/internal/history"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/workflow"
+)
+
+// WorkflowStep décrit une étape d'un workflow configuré
+type WorkflowStep struct {
+	Name     string   `json:"name"`
+	Script   string   `json:"script"`
+	Rollback string   `json:"rollback,omitempty"`
+	Needs    []string `json:"needs,omitempty"`
+}
+
+// Workflow décrit un workflow configuré
+type Workflow struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Steps       []WorkflowStep `json:"steps"`
+}
+
+// WorkflowListResponse liste les workflows configurés
+type WorkflowListResponse struct {
+	Workflows []Workflow `json:"workflows"`
+}
+
+// WorkflowExecuteRequest est le corps JSON d'une demande d'exécution de workflow
+type WorkflowExecuteRequest struct {
+	UserID string `json:"userId"`
+}
+
+// StepExecution décrit le résultat d'une étape dans une exécution de workflow
+type StepExecution struct {
+	Name        string `json:"name"`
+	Script      string `json:"script"`
+	Status      string `json:"status"`
+	ExecutionID string `json:"executionId,omitempty"`
+	ExitCode    int    `json:"exitCode"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"durationMs"`
+	// Rollback* décrivent la compensation lancée après l'échec d'une étape suivante
+	RollbackScript      string `json:"rollbackScript,omitempty"`
+	RollbackStatus      string `json:"rollbackStatus,omitempty"`
+	RollbackExecutionID string `json:"rollbackExecutionId,omitempty"`
+}
+
+// apiListWorkflows liste les workflows configurés
+func (h *Handlers) apiListWorkflows(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	response := WorkflowListResponse{Workflows: []Workflow{}}
+	for _, name := range h.workflows.Names() {
+		definition, _ := h.workflows.Get(name)
+		wf := Workflow{Name: name, Description: definition.Description, Steps: []WorkflowStep{}}
+		for _, step := range definition.Steps {
+			wf.Steps = append(wf.Steps, WorkflowStep{Name: step.Name, Script: step.Script, Rollback: step.Rollback, Needs: step.Needs})
+		}
+		response.Workflows = append(response.Workflows, wf)
+	}
+	h.sendAPIJSON(w, http.StatusOK, response)
+}
+
+// apiExecuteWorkflow démarre un workflow en arrière-plan pour un utilisateur
+func (h *Handlers) apiExecuteWorkflow(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	definition, ok := h.workflows.Get(params["name"])
+	if !ok {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	}
+	// Le token doit couvrir toutes les étapes, compensations comprises
+	if !h.authorizeExecution(w, r, definition.Scripts()...) {
+		return
+	}
+
+	var body WorkflowExecuteRequest
+	if !h.decodeAPIJSON(w, r, &body) {
+		return
+	}
+
+	body.UserID = strings.TrimSpace(body.UserID)
+	if !h.validateUserID(body.UserID) {
+		h.logSecurityEvent(r, "invalid_user_id", body.UserID)
+		h.sendAPIError(w, r, http.StatusBadRequest, ErrCodeInvalidUserID)
+		return
+	}
+
+	h.logSecurityEvent(r, "workflow_execution_request",
+		fmt.Sprintf("user:%s workflow:%s steps:%d", body.UserID, params["name"], len(definition.Steps)))
+
+	rec, err := h.workflows.Start(r.Context(), params["name"], body.UserID, getOperator(r))
+	if errors.Is(err, workflow.ErrNotFound) {
+		h.sendAPIError(w, r, http.StatusNotFound, ErrCodeNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "workflow execution failed", logging.KeyCategory, logging.CategoryExecution, "error", err)
+		h.sendAPIError(w, r, http.StatusInternalServerError, ErrCodeExecutionFailed)
+		return
+	}
+
+	w.Header().Set("Location", apiPrefix+"/jobs/"+rec.ID)
+	h.sendAPIJSON(w, http.StatusAccepted, newExecution(rec))
+}
+
+// newStepExecutions convertit les étapes d'une exécution de workflow en réponse d'API
+func newStepExecutions(steps []history.StepResult) []StepExecution {
+	if len(steps) == 0 {
+		return nil
+	}
+	executions := make([]StepExecution, 0, len(steps))
+	for _, step := range steps {
+		executions = append(executions, StepExecution{
+			Name:                step.Name,
+			Script:              step.Script,
+			Status:              string(step.Status),
+			ExecutionID:         step.ExecutionID,
+			ExitCode:            step.ExitCode,
+			Error:               step.Error,
+			DurationMs:          step.Duration.Milliseconds(),
+			RollbackScript:      step.RollbackScript,
+			RollbackStatus:      string(step.RollbackStatus),
+			RollbackExecutionID: step.RollbackExecutionID,
+		})
+	}
+	return executions
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go-form-app/internal/auth"
+	"go-form-app/internal/config"
+	"go-form-app/internal/jobs"
+)
+
+func TestWorkflowAPI(t *testing.T) {
+	handlers := newTestAPIHandlersWith(t, func(cfg *config.Config) {
+		cfg.Workflows = map[string]config.WorkflowConfig{
+			"onboarding": {Description: "Arrivée d'un collaborateur", Steps: []config.WorkflowStep{
+				{Name: "grant", Script: "grant.sh", Rollback: "grant.sh"},
+				{Name: "confirm", Script: "grant.sh"},
+			}},
+		}
+	})
+	headers := map[string]string{"Content-Type": "application/json", "X-CSRF-Token": "token"}
+
+	var list WorkflowListResponse
+	w := doAPIRequest(handlers, http.MethodGet, "/api/v1/workflows", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Workflows) != 1 || len(list.Workflows[0].Steps) != 2 || list.Workflows[0].Steps[0].Rollback != "grant.sh" {
+		t.Fatalf("list = %d %+v", w.Code, list)
+	}
+
+	tests := []struct {
+		name         string
+		workflow     string
+		body         string
+		headers      map[string]string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"missing CSRF token", "onboarding", `{"userId":"test123"}`, map[string]string{"Content-Type": "application/json"}, http.StatusBadRequest, ErrCodeMissingCSRFToken},
+		{"unknown workflow", "offboarding", `{"userId":"test123"}`, headers, http.StatusNotFound, ErrCodeNotFound},
+		{"invalid user", "onboarding", `{"userId":"bad"}`, headers, http.StatusBadRequest, ErrCodeInvalidUserID},
+		{"unknown field", "onboarding", `{"userId":"test123","script":"grant.sh"}`, headers, http.StatusBadRequest, ErrCodeInvalidJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doAPIRequest(handlers, http.MethodPost, "/api/v1/workflows/"+tt.workflow+"/executions", tt.body, tt.headers)
+			var response APIErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != tt.expectedCode || response.Error.Code != tt.expectedErr {
+				t.Errorf("execute = %d %s, want %d %s", w.Code, response.Error.Code, tt.expectedCode, tt.expectedErr)
+			}
+		})
+	}
+
+	outOfScope := auth.Token{ID: "t1", Name: "ticketing", Scripts: []string{"other.sh"}}
+	w = doAuthenticatedRequest(handlers, outOfScope, http.MethodPost, "/api/v1/workflows/onboarding/executions", `{"userId":"test123"}`, map[string]string{"Content-Type": "application/json"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("execute with a token out of scope = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = doAPIRequest(handlers, http.MethodPost, "/api/v1/workflows/onboarding/executions", `{"userId":"test123"}`, headers)
+	var started Execution
+	json.Unmarshal(w.Body.Bytes(), &started)
+	if w.Code != http.StatusAccepted || w.Header().Get("Location") != "/api/v1/jobs/"+started.ID || started.Workflow != "onboarding" || len(started.Steps) != 2 {
+		t.Fatalf("execute = %d %+v", w.Code, started)
+	}
+	handlers.workflows.Wait()
+
+	var finished Execution
+	w = doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/"+started.ID, "", nil)
+	json.Unmarshal(w.Body.Bytes(), &finished)
+	if finished.Status != "succeeded" || finished.Source != jobs.SourceWorkflow || finished.Operator != "anonymous" {
+		t.Fatalf("finished workflow = %+v", finished)
+	}
+	for _, step := range finished.Steps {
+		var job Execution
+		w = doAPIRequest(handlers, http.MethodGet, "/api/v1/jobs/"+step.ExecutionID, "", nil)
+		json.Unmarshal(w.Body.Bytes(), &job)
+		if step.Status != "succeeded" || job.Output != "granted test123\n" || step.RollbackStatus != "" {
+			t.Errorf("step %s = %+v, execution = %+v", step.Name, step, job)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenPrefix identifie les tokens d'API dans les logs et les outils de détection de secrets
+const tokenPrefix = "gfa"
+
+// Types de tokens
+const (
+	KindPersonal = "personal"
+	KindService  = "service"
+)
+
+// AllScripts autorise un token à exécuter tous les scripts de la whitelist
+const AllScripts = "*"
+
+var (
+	// ErrInvalidToken est retournée pour un token inconnu ou mal formé
+	ErrInvalidToken = errors.New("invalid API token")
+	// ErrTokenExpired est retournée pour un token dont la date d'expiration est passée
+	ErrTokenExpired = errors.New("API token expired")
+	// ErrTokenRevoked est retournée pour un token révoqué
+	ErrTokenRevoked = errors.New("API token revoked")
+	// ErrTokenNotFound est retournée lorsqu'aucun token ne correspond à l'identifiant
+	ErrTokenNotFound = errors.New("API token not found")
+)
+
+// Token décrit un token d'API ; seul le hash du secret est conservé
+type Token struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	Owner     string     `json:"owner"`
+	Scripts   []string   `json:"scripts"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Allows indique si le token est autorisé à exécuter le script
+func (t Token) Allows(script string) bool {
+	for _, allowed := range t.Scripts {
+		if allowed == AllScripts || allowed == script {
+			return true
+		}
+	}
+	return false
+}
+
+// Subject retourne l'identité d'opérateur associée au token
+func (t Token) Subject() string {
+	return fmt.Sprintf("token:%s", t.Name)
+}
+
+// TokenStore conserve les tokens d'API dans un fichier JSON, relu lorsqu'il est
+// modifié par la CLI d'administration
+type TokenStore struct {
+	path string
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	tokens  map[string]*Token
+	modTime time.Time
+}
+
+// NewTokenStore ouvre (ou prépare) le fichier de tokens
+func NewTokenStore(path string) (*TokenStore, error) {
+	s := &TokenStore{
+		path:   path,
+		now:    time.Now,
+		tokens: make(map[string]*Token),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("creating token directory: %w", err)
+	}
+	if err := s.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Issue crée un nouveau token et retourne le secret en clair, affiché une seule fois
+func (s *TokenStore) Issue(name, kind, owner string, scripts []string, ttl time.Duration) (string, Token, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", Token{}, errors.New("token name is required")
+	}
+	if kind != KindPersonal && kind != KindService {
+		return "", Token{}, fmt.Errorf("invalid token kind %q", kind)
+	}
+	if len(scripts) == 0 {
+		return "", Token{}, errors.New("at least one script scope is required")
+	}
+	if ttl <= 0 {
+		return "", Token{}, errors.New("token TTL must be positive")
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", Token{}, err
+	}
+	secretPart, err := randomHex(32)
+	if err != nil {
+		return "", Token{}, err
+	}
+	secret := fmt.Sprintf("%s_%s_%s", tokenPrefix, id, secretPart)
+
+	now := s.now()
+	token := Token{
+		ID:        id,
+		Name:      name,
+		Kind:      kind,
+		Owner:     owner,
+		Scripts:   scripts,
+		Hash:      hashSecret(secret),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadLocked(); err != nil {
+		return "", Token{}, err
+	}
+	s.tokens[id] = &token
+	if err := s.persistLocked(); err != nil {
+		delete(s.tokens, id)
+		return "", Token{}, err
+	}
+	return secret, token, nil
+}
+
+// Revoke révoque un token ; il reste listé pour l'audit
+func (s *TokenStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reloadLocked(); err != nil {
+		return err
+	}
+
+	token, ok := s.tokens[id]
+	if !ok {
+		return ErrTokenNotFound
+	}
+	if token.RevokedAt == nil {
+		now := s.now()
+		token.RevokedAt = &now
+	}
+	return s.persistLocked()
+}
+
+// List retourne les tokens triés par date de création
+func (s *TokenStore) List() []Token {
+	if err := s.reloadIfChanged(); err != nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]Token, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, *token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens
+}
+
+// Authenticate vérifie un secret présenté dans l'en-tête Authorization
+func (s *TokenStore) Authenticate(secret string) (Token, error) {
+	parts := strings.Split(secret, "_")
+	if len(parts) != 3 || parts[0] != tokenPrefix {
+		return Token{}, ErrInvalidToken
+	}
+
+	if err := s.reloadIfChanged(); err != nil {
+		return Token{}, err
+	}
+
+	s.mu.RLock()
+	token, ok := s.tokens[parts[1]]
+	s.mu.RUnlock()
+
+	if !ok || subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hashSecret(secret))) != 1 {
+		return Token{}, ErrInvalidToken
+	}
+	if token.RevokedAt != nil {
+		return Token{}, ErrTokenRevoked
+	}
+	if !s.now().Before(token.ExpiresAt) {
+		return Token{}, ErrTokenExpired
+	}
+	return *token, nil
+}
+
+// reloadIfChanged relit le fichier si sa date de modification a changé
+func (s *TokenStore) reloadIfChanged() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading token file: %w", err)
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime)
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloadLocked()
+}
+
+// reloadLocked relit le fichier de tokens (mu doit être verrouillé en écriture)
+func (s *TokenStore) reloadLocked() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading token file: %w", err)
+	}
+
+	var tokens []*Token
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			return fmt.Errorf("parsing token file: %w", err)
+		}
+	}
+
+	s.tokens = make(map[string]*Token, len(tokens))
+	for _, token := range tokens {
+		s.tokens[token.ID] = token
+	}
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// persistLocked réécrit atomiquement le fichier de tokens (mu doit être verrouillé en écriture)
+func (s *TokenStore) persistLocked() error {
+	tokens := make([]*Token, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing token file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing token file: %w", err)
+	}
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// hashSecret calcule l'empreinte SHA-256 conservée au repos
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex génère n octets aléatoires encodés en hexadécimal
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTokenStore(t *testing.T) *TokenStore {
+	t.Helper()
+	store, err := NewTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("NewTokenStore() error = %v", err)
+	}
+	return store
+}
+
+func TestIssueAndAuthenticate(t *testing.T) {
+	store := newTestTokenStore(t)
+
+	secret, token, err := store.Issue("ticketing", KindService, "itsm", []string{"script1.py"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if !strings.HasPrefix(secret, "gfa_"+token.ID+"_") {
+		t.Errorf("Issue() secret = %s, want gfa_<id>_ prefix", secret)
+	}
+	if token.Hash == "" || strings.Contains(token.Hash, secret) {
+		t.Error("Issue() token hash is empty or contains the secret")
+	}
+
+	data, _ := os.ReadFile(store.path)
+	if strings.Contains(string(data), secret) {
+		t.Error("token file contains the plaintext secret")
+	}
+
+	authenticated, err := store.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if authenticated.ID != token.ID || authenticated.Subject() != "token:ticketing" {
+		t.Errorf("Authenticate() = %+v", authenticated)
+	}
+}
+
+func TestAuthenticateFailures(t *testing.T) {
+	store := newTestTokenStore(t)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	secret, token, _ := store.Issue("ticketing", KindService, "", []string{"*"}, time.Hour)
+	revokedSecret, revoked, _ := store.Issue("old", KindPersonal, "", []string{"*"}, time.Hour)
+	store.Revoke(revoked.ID)
+
+	tests := []struct {
+		name     string
+		secret   string
+		expected error
+	}{
+		{"malformed", "not-a-token", ErrInvalidToken},
+		{"unknown id", "gfa_0000000000000000_abcdef", ErrInvalidToken},
+		{"wrong secret", "gfa_" + token.ID + "_deadbeef", ErrInvalidToken},
+		{"revoked", revokedSecret, ErrTokenRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.Authenticate(tt.secret); !errors.Is(err, tt.expected) {
+				t.Errorf("Authenticate() error = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := store.Authenticate(secret); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Authenticate() after expiry error = %v, want %v", err, ErrTokenExpired)
+	}
+}
+
+func TestIssueValidation(t *testing.T) {
+	store := newTestTokenStore(t)
+
+	tests := []struct {
+		name    string
+		tName   string
+		kind    string
+		scripts []string
+		ttl     time.Duration
+	}{
+		{"missing name", "", KindService, []string{"*"}, time.Hour},
+		{"invalid kind", "x", "robot", []string{"*"}, time.Hour},
+		{"no scope", "x", KindService, nil, time.Hour},
+		{"non-positive TTL", "x", KindService, []string{"*"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := store.Issue(tt.tName, tt.kind, "", tt.scripts, tt.ttl); err == nil {
+				t.Error("Issue() expected error but got none")
+			}
+		})
+	}
+}
+
+func TestTokenStoreSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	server, _ := NewTokenStore(path)
+	cli, _ := NewTokenStore(path)
+
+	secret, token, err := cli.Issue("ticketing", KindService, "", []string{"*"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if _, err := server.Authenticate(secret); err != nil {
+		t.Fatalf("server did not pick up token issued by CLI: %v", err)
+	}
+
+	// Forcer une date de modification différente pour simuler une écriture ultérieure
+	cli.Revoke(token.ID)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	if _, err := server.Authenticate(secret); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("server did not pick up revocation: %v", err)
+	}
+	if len(server.List()) != 1 {
+		t.Errorf("List() returned %d tokens, want 1", len(server.List()))
+	}
+
+	if err := cli.Revoke("missing"); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Revoke() error = %v, want %v", err, ErrTokenNotFound)
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	scoped := Token{Scripts: []string{"script1.py"}}
+	if !scoped.Allows("script1.py") || scoped.Allows("script2.py") {
+		t.Error("scoped token Allows() mismatch")
+	}
+
+	wildcard := Token{Scripts: []string{AllScripts}}
+	if !wildcard.Allows("script2.py") {
+		t.Error("wildcard token should allow every script")
+	}
+}
@@ -0,0 +1,316 @@
+package bulk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+// maxBatches borne le nombre de lots conservés en mémoire
+const maxBatches = 50
+
+// Status représente l'état d'un lot
+type Status string
+
+const (
+	StatusPreview   Status = "preview"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+)
+
+// RowStatus représente l'état d'une ligne du lot
+type RowStatus string
+
+const (
+	RowPending   RowStatus = "pending"
+	RowInvalid   RowStatus = "invalid"
+	RowRunning   RowStatus = "running"
+	RowSucceeded RowStatus = "succeeded"
+	RowFailed    RowStatus = "failed"
+	RowCancelled RowStatus = "cancelled"
+)
+
+// Erreurs retournées par le Runner
+var (
+	ErrNotFound       = errors.New("bulk batch not found")
+	ErrAlreadyStarted = errors.New("bulk batch already started")
+	ErrNoValidRows    = errors.New("bulk batch has no valid rows")
+)
+
+// Row est une ligne du fichier et le résultat de son exécution
+type Row struct {
+	Line        int
+	UserID      string
+	Arguments   []string
+	Status      RowStatus
+	Error       string
+	ExecutionID string
+	ExitCode    int
+	Duration    time.Duration
+}
+
+// Batch est un lot d'exécutions d'un même script
+type Batch struct {
+	ID         string
+	Script     string
+	Operator   string
+	Status     Status
+	Columns    []string
+	Rows       []Row
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Summary compte les lignes par état
+type Summary map[RowStatus]int
+
+// Summary retourne le nombre de lignes du lot par état
+func (b Batch) Summary() Summary {
+	summary := make(Summary)
+	for _, row := range b.Rows {
+		summary[row.Status]++
+	}
+	return summary
+}
+
+// clone copie le lot pour le partager sans verrou
+func (b *Batch) clone() Batch {
+	copied := *b
+	copied.Rows = append([]Row(nil), b.Rows...)
+	return copied
+}
+
+// Runner valide les fichiers CSV puis exécute les lots avec une concurrence bornée
+type Runner struct {
+	jobs        *jobs.Manager
+	maxRows     int
+	concurrency int
+	logger      *slog.Logger
+
+	mu      sync.Mutex
+	batches map[string]*Batch
+	order   []string
+	wg      sync.WaitGroup
+}
+
+// NewRunner crée un Runner ; les valeurs non positives de cfg prennent la valeur par défaut
+func NewRunner(manager *jobs.Manager, cfg config.BulkConfig, logger *slog.Logger) *Runner {
+	defaults := config.Default().Bulk
+	if cfg.MaxRows < 1 {
+		cfg.MaxRows = defaults.MaxRows
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = defaults.Concurrency
+	}
+
+	return &Runner{
+		jobs:        manager,
+		maxRows:     cfg.MaxRows,
+		concurrency: cfg.Concurrency,
+		logger:      logger,
+		batches:     make(map[string]*Batch),
+	}
+}
+
+// MaxRows retourne le nombre maximal de lignes accepté dans un fichier
+func (r *Runner) MaxRows() int {
+	return r.maxRows
+}
+
+// Prepare lit et valide le fichier ; le lot reste en prévisualisation jusqu'à Start
+func (r *Runner) Prepare(script, operator string, file io.Reader) (Batch, error) {
+	columns, rows, err := Parse(file, r.maxRows)
+	if err != nil {
+		return Batch{}, err
+	}
+
+	seen := make(map[string]int)
+	for i := range rows {
+		row := &rows[i]
+		err := r.jobs.Validate(scripts.ExecutionRequest{UserID: row.UserID, Script: script, Arguments: row.Arguments})
+		switch {
+		case err != nil:
+			row.Status = RowInvalid
+			row.Error = err.Error()
+		case seen[row.UserID] != 0:
+			row.Status = RowInvalid
+			row.Error = fmt.Sprintf("duplicate user ID (line %d)", seen[row.UserID])
+		default:
+			seen[row.UserID] = row.Line
+		}
+	}
+
+	batch := &Batch{
+		ID:        newID(),
+		Script:    script,
+		Operator:  operator,
+		Status:    StatusPreview,
+		Columns:   columns,
+		Rows:      rows,
+		CreatedAt: time.Now(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches[batch.ID] = batch
+	r.order = append(r.order, batch.ID)
+	r.evict()
+	return batch.clone(), nil
+}
+
+// Start lance l'exécution des lignes valides en arrière-plan ; le lot conserve les
+// valeurs de ctx (identifiant de requête) mais pas son annulation
+func (r *Runner) Start(ctx context.Context, id string) (Batch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch, ok := r.batches[id]
+	if !ok {
+		return Batch{}, ErrNotFound
+	}
+	if batch.Status != StatusPreview {
+		return Batch{}, ErrAlreadyStarted
+	}
+	if batch.Summary()[RowPending] == 0 {
+		return Batch{}, ErrNoValidRows
+	}
+
+	batch.Status = StatusRunning
+	batch.StartedAt = time.Now()
+
+	ctx = logging.WithAttrs(context.WithoutCancel(ctx), "batch_id", batch.ID)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(ctx, batch)
+	}()
+
+	return batch.clone(), nil
+}
+
+// Get retourne une copie du lot
+func (r *Runner) Get(id string) (Batch, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch, ok := r.batches[id]
+	if !ok {
+		return Batch{}, false
+	}
+	return batch.clone(), true
+}
+
+// Wait attend la fin des lots en cours d'exécution
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// run exécute les lignes en attente, au plus concurrency à la fois
+func (r *Runner) run(ctx context.Context, batch *Batch) {
+	r.logger.InfoContext(ctx, "bulk execution started", logging.KeyCategory, logging.CategoryExecution,
+		logging.KeyScript, batch.Script, "rows", len(batch.Rows), "concurrency", r.concurrency)
+
+	slots := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	for i := range batch.Rows {
+		if batch.Rows[i].Status != RowPending {
+			continue
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			r.runRow(ctx, batch, i)
+		}(i)
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	batch.Status = StatusCompleted
+	batch.FinishedAt = time.Now()
+	summary := batch.Summary()
+	r.mu.Unlock()
+
+	r.logger.InfoContext(ctx, "bulk execution completed", logging.KeyCategory, logging.CategoryExecution,
+		logging.KeyScript, batch.Script, "succeeded", summary[RowSucceeded], "failed", summary[RowFailed],
+		"cancelled", summary[RowCancelled], "invalid", summary[RowInvalid])
+}
+
+// runRow exécute une ligne via le gestionnaire d'exécutions, qui l'enregistre dans l'historique
+func (r *Runner) runRow(ctx context.Context, batch *Batch, i int) {
+	r.mu.Lock()
+	row := batch.Rows[i]
+	batch.Rows[i].Status = RowRunning
+	r.mu.Unlock()
+
+	rec, err := r.jobs.Run(ctx, scripts.ExecutionRequest{
+		UserID:    row.UserID,
+		Script:    batch.Script,
+		Arguments: row.Arguments,
+		Operator:  batch.Operator,
+	}, jobs.SourceBulk)
+
+	row.ExecutionID = rec.ID
+	row.ExitCode = rec.ExitCode
+	row.Duration = rec.Duration
+	row.Error = rec.Error
+	switch rec.Status {
+	case history.StatusSucceeded:
+		row.Status = RowSucceeded
+	case history.StatusCancelled:
+		row.Status = RowCancelled
+	default:
+		row.Status = RowFailed
+	}
+	if err != nil && row.Error == "" {
+		row.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	batch.Rows[i] = row
+	r.mu.Unlock()
+}
+
+// evict retire les lots terminés ou jamais lancés les plus anciens au-delà de maxBatches
+func (r *Runner) evict() {
+	for len(r.order) > maxBatches {
+		evicted := false
+		for i, id := range r.order {
+			if r.batches[id].Status != StatusRunning {
+				delete(r.batches, id)
+				r.order = append(r.order[:i], r.order[i+1:]...)
+				evicted = true
+				break
+			}
+		}
+		if !evicted {
+			return
+		}
+	}
+}
+
+// newID génère un identifiant de lot aléatoire
+func newID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(bytes)
+}
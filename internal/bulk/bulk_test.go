@@ -0,0 +1,148 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/jobs/jobstest"
+	"go-form-app/internal/logging"
+)
+
+// newTestRunner crée un Runner avec des scripts bash temporaires
+func newTestRunner(t *testing.T, cfg config.BulkConfig, scriptBodies map[string]string) *Runner {
+	t.Helper()
+
+	return NewRunner(jobstest.NewManager(t, scriptBodies), cfg, logging.New(os.Stdout, slog.LevelDebug))
+}
+
+func TestRunnerPrepare(t *testing.T) {
+	runner := newTestRunner(t, config.BulkConfig{MaxRows: 10, Concurrency: 2}, map[string]string{"grant.sh": "echo $@"})
+
+	batch, err := runner.Prepare("grant.sh", "jdupont", strings.NewReader("userId,role\nabc1234,reader\nbad,reader\nabc1234,writer\ndef5678,a;b\n"))
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if batch.Status != StatusPreview || batch.Operator != "jdupont" {
+		t.Errorf("Prepare() batch = %+v, want preview by jdupont", batch)
+	}
+
+	want := []RowStatus{RowPending, RowInvalid, RowInvalid, RowInvalid}
+	for i, row := range batch.Rows {
+		if row.Status != want[i] {
+			t.Errorf("row %d status = %s, want %s (%s)", row.Line, row.Status, want[i], row.Error)
+		}
+	}
+	if !strings.Contains(batch.Rows[2].Error, "duplicate") {
+		t.Errorf("duplicate row error = %q", batch.Rows[2].Error)
+	}
+
+	if _, ok := runner.Get(batch.ID); !ok {
+		t.Error("Get() did not find the prepared batch")
+	}
+}
+
+func TestRunnerStart(t *testing.T) {
+	runner := newTestRunner(t, config.BulkConfig{MaxRows: 50, Concurrency: 3}, map[string]string{
+		"grant.sh": `if [ "$1" = "fail0001" ]; then exit 4; fi; echo granted $1 $2`,
+	})
+
+	var csv strings.Builder
+	csv.WriteString("userId,role\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&csv, "user%04d,reader\n", i)
+	}
+	csv.WriteString("fail0001,reader\nbad,reader\n")
+
+	batch, err := runner.Prepare("grant.sh", "jdupont", strings.NewReader(csv.String()))
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	if _, err := runner.Start(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Start(missing) error = %v, want ErrNotFound", err)
+	}
+
+	started, err := runner.Start(context.Background(), batch.ID)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if started.Status != StatusRunning {
+		t.Errorf("Start() status = %s, want running", started.Status)
+	}
+	if _, err := runner.Start(context.Background(), batch.ID); !errors.Is(err, ErrAlreadyStarted) {
+		t.Errorf("second Start() error = %v, want ErrAlreadyStarted", err)
+	}
+
+	runner.Wait()
+
+	done, _ := runner.Get(batch.ID)
+	if done.Status != StatusCompleted || done.FinishedAt.IsZero() {
+		t.Errorf("batch after Wait() = %s, want completed", done.Status)
+	}
+	summary := done.Summary()
+	if summary[RowSucceeded] != 10 || summary[RowFailed] != 1 || summary[RowInvalid] != 1 {
+		t.Errorf("summary = %v, want 10 succeeded, 1 failed, 1 invalid", summary)
+	}
+
+	for _, row := range done.Rows {
+		if row.Status == RowInvalid {
+			continue
+		}
+		rec, ok := runner.jobs.Store().Get(row.ExecutionID)
+		if !ok || rec.Source != jobs.SourceBulk || rec.UserID != row.UserID {
+			t.Errorf("row %d history record = %+v, want bulk record for %s", row.Line, rec, row.UserID)
+		}
+		if row.Status == RowFailed && row.ExitCode != 4 {
+			t.Errorf("failed row exit code = %d, want 4", row.ExitCode)
+		}
+	}
+}
+
+func TestRunnerStartWithoutValidRows(t *testing.T) {
+	runner := newTestRunner(t, config.BulkConfig{}, map[string]string{"grant.sh": "echo ok"})
+
+	batch, err := runner.Prepare("grant.sh", "", strings.NewReader("userId\nbad\n"))
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if _, err := runner.Start(context.Background(), batch.ID); !errors.Is(err, ErrNoValidRows) {
+		t.Errorf("Start() error = %v, want ErrNoValidRows", err)
+	}
+}
+
+func TestRunnerBoundsConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	script := fmt.Sprintf(`mkdir %[1]s/$1 && n=$(ls %[1]s | wc -l) && echo $n >> %[1]s.max; sleep 0.2; rmdir %[1]s/$1`, filepath.Join(dir, "running"))
+	os.MkdirAll(filepath.Join(dir, "running"), 0o755)
+	runner := newTestRunner(t, config.BulkConfig{MaxRows: 10, Concurrency: 2}, map[string]string{"slow.sh": script})
+
+	batch, err := runner.Prepare("slow.sh", "", strings.NewReader("userId\nuser0001\nuser0002\nuser0003\nuser0004\nuser0005\n"))
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	if _, err := runner.Start(context.Background(), batch.ID); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	runner.Wait()
+
+	data, err := os.ReadFile(filepath.Join(dir, "running") + ".max")
+	if err != nil {
+		t.Fatalf("no execution recorded: %v", err)
+	}
+	for _, field := range strings.Fields(string(data)) {
+		if field != "1" && field != "2" {
+			t.Errorf("observed %s concurrent executions, want at most 2", field)
+		}
+	}
+	if done, _ := runner.Get(batch.ID); done.Summary()[RowSucceeded] != 5 {
+		t.Errorf("summary = %v, want 5 succeeded", done.Summary())
+	}
+}
@@ -0,0 +1,109 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// UserIDColumn est la colonne obligatoire du fichier ; les autres colonnes sont des paramètres
+const UserIDColumn = "userId"
+
+// Erreurs de lecture du fichier CSV
+var (
+	ErrEmptyFile     = errors.New("CSV file is empty")
+	ErrMissingUserID = errors.New("CSV header must contain a " + UserIDColumn + " column")
+	ErrTooManyRows   = errors.New("CSV file has too many rows")
+	ErrNoRows        = errors.New("CSV file has no data rows")
+)
+
+// Parse lit un fichier CSV avec en-tête ; les colonnes autres que userId sont passées
+// au script comme arguments, dans l'ordre de l'en-tête
+func Parse(r io.Reader, maxRows int) (columns []string, rows []Row, err error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, ErrEmptyFile
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSV header: %w", err)
+	}
+
+	userIDIndex := -1
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if name == UserIDColumn {
+			userIDIndex = i
+			continue
+		}
+		columns = append(columns, name)
+	}
+	if userIDIndex < 0 {
+		return nil, nil, ErrMissingUserID
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rows) == maxRows {
+			return nil, nil, fmt.Errorf("%w (maximum %d)", ErrTooManyRows, maxRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line, Status: RowPending}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if i == userIDIndex {
+				row.UserID = value
+			} else {
+				row.Arguments = append(row.Arguments, value)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil, ErrNoRows
+	}
+	return columns, rows, nil
+}
+
+// reportHeader liste les colonnes du rapport par ligne
+var reportHeader = []string{"line", "userId", "arguments", "status", "exitCode", "durationMs", "executionId", "error"}
+
+// WriteReport écrit le rapport CSV du lot, une ligne par ligne du fichier d'origine
+func WriteReport(w io.Writer, batch Batch) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(reportHeader); err != nil {
+		return err
+	}
+
+	for _, row := range batch.Rows {
+		record := []string{
+			strconv.Itoa(row.Line),
+			row.UserID,
+			strings.Join(row.Arguments, " "),
+			string(row.Status),
+			strconv.Itoa(row.ExitCode),
+			strconv.FormatInt(row.Duration.Milliseconds(), 10),
+			row.ExecutionID,
+			row.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,85 @@
+package bulk
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	input := "\ufeffuserId, team ,role\nabc1234,ops,reader\n\n def5678 ,dev,writer\n"
+
+	columns, rows, err := Parse(strings.NewReader(input), 10)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if strings.Join(columns, ",") != "team,role" {
+		t.Errorf("Parse() columns = %v, want [team role]", columns)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Parse() rows = %d, want 2", len(rows))
+	}
+
+	want := []Row{
+		{Line: 2, UserID: "abc1234", Arguments: []string{"ops", "reader"}, Status: RowPending},
+		{Line: 4, UserID: "def5678", Arguments: []string{"dev", "writer"}, Status: RowPending},
+	}
+	for i, row := range rows {
+		if row.Line != want[i].Line || row.UserID != want[i].UserID || strings.Join(row.Arguments, ",") != strings.Join(want[i].Arguments, ",") || row.Status != RowPending {
+			t.Errorf("row %d = %+v, want %+v", i, row, want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		maxRows int
+		wantErr error
+	}{
+		{"empty file", "", 10, ErrEmptyFile},
+		{"missing userId column", "user,role\nabc1234,reader\n", 10, ErrMissingUserID},
+		{"header only", "userId\n", 10, ErrNoRows},
+		{"too many rows", "userId\nabc1234\ndef5678\n", 1, ErrTooManyRows},
+		{"inconsistent field count", "userId,role\nabc1234\n", 10, csv.ErrFieldCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(strings.NewReader(tt.input), tt.maxRows)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	batch := Batch{Rows: []Row{
+		{Line: 2, UserID: "abc1234", Arguments: []string{"ops"}, Status: RowSucceeded, ExecutionID: "e1", Duration: 1500 * time.Millisecond},
+		{Line: 3, UserID: "bad", Status: RowInvalid, Error: "invalid user ID format: bad"},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, batch); err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("report is not valid CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("report has %d records, want 3", len(records))
+	}
+	if strings.Join(records[1], ",") != "2,abc1234,ops,succeeded,0,1500,e1," {
+		t.Errorf("report row = %v", records[1])
+	}
+	if records[2][3] != string(RowInvalid) || records[2][7] == "" {
+		t.Errorf("invalid row = %v, want status and error", records[2])
+	}
+}
@@ -0,0 +1,599 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config regroupe l'ensemble des réglages de l'application
+type Config struct {
+	Server      ServerConfig  `yaml:"server"`
+	Scripts     ScriptsConfig `yaml:"scripts"`
+	HistoryFile string        `yaml:"history_file"`
+	// SchedulesFile persiste les planifications (mémoire seule si vide)
+	SchedulesFile string `yaml:"schedules_file"`
+	// IdempotencyWindow est la durée pendant laquelle une clé d'idempotence rejoue l'exécution d'origine
+	IdempotencyWindow time.Duration `yaml:"idempotency_window"`
+	TLS               TLSConfig     `yaml:"tls"`
+	TrustedProxies    []string      `yaml:"trusted_proxies"`
+	// ForwardedHeader est l'en-tête réécrit par les proxies de confiance : "xff" ou "forwarded"
+	ForwardedHeader string          `yaml:"forwarded_header"`
+	AccessRules     []AccessRule    `yaml:"access_rules"`
+	BanPolicy       BanPolicy       `yaml:"ban"`
+	RateLimit       RateLimitConfig `yaml:"rate_limit"`
+	APITokensFile   string          `yaml:"api_tokens_file"`
+	// SecretsFile est le coffre chiffré des secrets injectés dans les scripts, déchiffré
+	// avec la clé lue dans SecretsKeyFile
+	SecretsFile    string     `yaml:"secrets_file"`
+	SecretsKeyFile string     `yaml:"secrets_key_file"`
+	Log            LogConfig  `yaml:"log"`
+	Web            WebConfig  `yaml:"web"`
+	Bulk           BulkConfig `yaml:"bulk"`
+	// Workflows enchaîne des scripts du catalogue, par nom de workflow
+	Workflows map[string]WorkflowConfig `yaml:"workflows"`
+}
+
+// BulkConfig borne les exécutions en masse à partir d'un fichier CSV
+type BulkConfig struct {
+	// MaxRows limite le nombre de lignes d'un fichier
+	MaxRows int `yaml:"max_rows"`
+	// Concurrency limite le nombre de scripts exécutés simultanément pour un lot
+	Concurrency int `yaml:"concurrency"`
+}
+
+// WorkflowConfig décrit un enchaînement d'étapes exécutées pour un même utilisateur
+type WorkflowConfig struct {
+	Description string         `yaml:"description"`
+	Steps       []WorkflowStep `yaml:"steps"`
+}
+
+// WorkflowStep est une étape d'un workflow
+type WorkflowStep struct {
+	Name   string `yaml:"name"`
+	Script string `yaml:"script"`
+	// Rollback est le script de compensation exécuté si le workflow échoue après le succès de l'étape
+	Rollback string `yaml:"rollback"`
+	// Needs liste les étapes précédentes dont le succès conditionne l'étape
+	Needs []string `yaml:"needs"`
+}
+
+// Dependencies retourne les étapes requises par l'étape i : ses needs si le workflow
+// déclare un graphe, sinon l'étape précédente (enchaînement ordonné)
+func (w WorkflowConfig) Dependencies(i int) []string {
+	for _, step := range w.Steps {
+		if len(step.Needs) > 0 {
+			return w.Steps[i].Needs
+		}
+	}
+	if i == 0 {
+		return nil
+	}
+	return []string{w.Steps[i-1].Name}
+}
+
+// Scripts retourne les scripts lancés par le workflow, compensations comprises
+func (w WorkflowConfig) Scripts() []string {
+	var scripts []string
+	for _, step := range w.Steps {
+		scripts = append(scripts, step.Script)
+		if step.Rollback != "" {
+			scripts = append(scripts, step.Rollback)
+		}
+	}
+	return scripts
+}
+
+// workflowNamePattern borne les noms de workflows et d'étapes
+var workflowNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// WebConfig contient les réglages des templates et fichiers statiques
+type WebConfig struct {
+	// DevDir remplace les assets embarqués par un répertoire relu à chaud (templates/, static/)
+	DevDir string `yaml:"dev_dir"`
+}
+
+// LogConfig contient les réglages des logs structurés
+type LogConfig struct {
+	// Level vaut debug, info, warn ou error
+	Level string `yaml:"level"`
+}
+
+// SlogLevel convertit le niveau configuré ; Validate garantit qu'il est reconnu
+func (c LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// ServerConfig contient les réglages d'écoute et les timeouts HTTP
+type ServerConfig struct {
+	// Port fixe ; vide pour chercher un port libre dans la plage configurée
+	Port            string        `yaml:"port"`
+	PortRangeStart  int           `yaml:"port_range_start"`
+	PortRangeEnd    int           `yaml:"port_range_end"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes  int           `yaml:"max_header_bytes"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// ScriptsConfig contient les réglages d'exécution des scripts
+type ScriptsConfig struct {
+	Dir              string        `yaml:"dir"`
+	AllowedScripts   []string      `yaml:"allowed"`
+	MaxExecutionTime time.Duration `yaml:"max_execution_time"`
+	UserIDPattern    string        `yaml:"user_id_pattern"`
+	// Workers borne le nombre de scripts exécutés simultanément, tous scripts confondus
+	Workers int `yaml:"workers"`
+	// QueueSize borne le nombre d'exécutions en attente d'un worker (0 : refus immédiat)
+	QueueSize int `yaml:"queue_size"`
+	// UserLockTimeout borne l'attente du verrou de l'utilisateur cible (0 : échec immédiat)
+	UserLockTimeout time.Duration `yaml:"user_lock_timeout"`
+	// Settings contient les réglages propres à chaque script, par nom de script
+	Settings map[string]ScriptSettings `yaml:"settings"`
+	// Redact masque des valeurs dans la sortie de tous les scripts
+	Redact RedactConfig `yaml:"redact"`
+	// WorkDir accueille le répertoire de travail de chaque exécution (répertoire temporaire du système si vide)
+	WorkDir string `yaml:"work_dir"`
+	// ArtifactRetention conserve le répertoire et les artefacts d'une exécution (0 : supprimés à la fin)
+	ArtifactRetention time.Duration `yaml:"artifact_retention"`
+}
+
+// RedactConfig décrit les valeurs masquées dans la sortie des scripts, en plus des
+// secrets injectés dans chaque script
+type RedactConfig struct {
+	// Patterns sont des expressions régulières ; si l'expression contient un groupe,
+	// seul le premier groupe est masqué
+	Patterns []string `yaml:"patterns"`
+	// Values sont des secrets connus, référencés par "file:/chemin" ou "store:nom"
+	Values []string `yaml:"values"`
+}
+
+// Politiques appliquées lorsque le client se déconnecte pendant une exécution synchrone
+const (
+	DisconnectComplete = "complete"
+	DisconnectCancel   = "cancel"
+)
+
+// Verrous pris sur l'utilisateur cible pendant l'exécution d'un script
+const (
+	UserLockExclusive = "exclusive"
+	UserLockShared    = "shared"
+)
+
+// En-têtes portant l'IP du client derrière un proxy de confiance
+const (
+	ForwardedHeaderXFF     = "xff"
+	ForwardedHeaderRFC7239 = "forwarded"
+)
+
+// Protocoles de résultat structuré : un script déclaré émet des lignes JSON
+// (progression, résultat) préfixées sur sa sortie ou sur un descripteur dédié
+const (
+	ProtocolNone   = "none"
+	ProtocolPrefix = "prefix"
+	ProtocolFD     = "fd"
+)
+
+// ScriptSettings contient les réglages d'un script
+type ScriptSettings struct {
+	// OnDisconnect vaut "complete" (par défaut) ou "cancel"
+	OnDisconnect string `yaml:"on_disconnect"`
+	// MaxConcurrency borne les exécutions simultanées du script (0 : seule la limite globale s'applique)
+	MaxConcurrency int `yaml:"max_concurrency"`
+	// UserLock vaut "exclusive" (par défaut) ou "shared" (cumulable avec les autres scripts partagés)
+	UserLock string `yaml:"user_lock"`
+	// Protocol vaut "none" (par défaut), "prefix" ou "fd"
+	Protocol string `yaml:"protocol"`
+	// DryRun déclare que le script sait simuler son exécution (variable DRY_RUN=1)
+	DryRun bool `yaml:"dry_run"`
+	// Env ajoute des variables d'environnement au script, par nom de variable
+	Env map[string]string `yaml:"env"`
+	// Secrets ajoute des variables dont la valeur est lue à chaque exécution depuis une
+	// référence "file:/chemin" ou "store:nom" ; ces valeurs sont masquées dans la sortie
+	Secrets map[string]string `yaml:"secrets"`
+}
+
+// Préfixes des références de secrets
+const (
+	SecretFilePrefix  = "file:"
+	SecretStorePrefix = "store:"
+)
+
+// envNamePattern borne les noms des variables d'environnement déclarées
+var envNamePattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]{0,63}$`)
+
+// reservedEnv liste les variables fixées par l'executor, qu'un script ne peut pas redéfinir
+var reservedEnv = []string{
+	"HOME", "USER", "SHELL", "PATH", "LANG", "LC_ALL", "LANGUAGE", "LC_CTYPE",
+	"PYTHONIOENCODING", "PYTHONUNBUFFERED", "PYTHONLEGACYWINDOWSSTDIO",
+	"DRY_RUN", "EVENTS_PREFIX", "EVENTS_FD", "TMPDIR", "ARTIFACTS_DIR",
+}
+
+// SettingsFor retourne les réglages d'un script, complétés des valeurs par défaut
+func (c ScriptsConfig) SettingsFor(script string) ScriptSettings {
+	settings := c.Settings[script]
+	if settings.OnDisconnect == "" {
+		settings.OnDisconnect = DisconnectComplete
+	}
+	if settings.UserLock == "" {
+		settings.UserLock = UserLockExclusive
+	}
+	if settings.Protocol == "" {
+		settings.Protocol = ProtocolNone
+	}
+	return settings
+}
+
+// TLSConfig contient la configuration HTTPS native du serveur
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Enabled indique si le serveur doit écouter en HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// MutualTLS indique si les certificats clients doivent être vérifiés
+func (c TLSConfig) MutualTLS() bool {
+	return c.ClientCAFile != ""
+}
+
+// AccessRule définit les listes d'autorisation et de refus pour un préfixe de route
+type AccessRule struct {
+	PathPrefix string   `yaml:"path"`
+	Allow      []string `yaml:"allow"`
+	Deny       []string `yaml:"deny"`
+}
+
+// BanPolicy définit le bannissement temporaire après des échecs de validation répétés
+type BanPolicy struct {
+	MaxFailures int           `yaml:"max_failures"`
+	Window      time.Duration `yaml:"window"`
+	Duration    time.Duration `yaml:"duration"`
+}
+
+// Enabled indique si le bannissement automatique est actif
+func (p BanPolicy) Enabled() bool {
+	return p.MaxFailures > 0 && p.Window > 0 && p.Duration > 0
+}
+
+// RateLimitConfig définit le débit autorisé par client (IP ou token d'API)
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+// Enabled indique si la limitation de débit est active
+func (c RateLimitConfig) Enabled() bool {
+	return c.RequestsPerSecond > 0 && c.Burst > 0
+}
+
+// DefaultUserIDPattern est le format d'identifiant utilisateur accepté par défaut
+const DefaultUserIDPattern = `^[a-zA-Z0-9]{7,12}$`
+
+// Default retourne la configuration par défaut de l'application
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			PortRangeStart:  8001,
+			PortRangeEnd:    8015,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			MaxHeaderBytes:  1 << 20, // 1 MB
+			ShutdownTimeout: 25 * time.Second,
+		},
+		Scripts: ScriptsConfig{
+			Dir: "internal/scripts",
+			AllowedScripts: []string{
+				"script1.py",
+				"script2.py",
+				"script1.sh",
+				"script1.zsh",
+			},
+			MaxExecutionTime: 30 * time.Second,
+			UserIDPattern:    DefaultUserIDPattern,
+			Workers:          8,
+			QueueSize:        50,
+		},
+		IdempotencyWindow: 24 * time.Hour,
+		ForwardedHeader:   ForwardedHeaderXFF,
+		BanPolicy: BanPolicy{
+			MaxFailures: 10,
+			Window:      5 * time.Minute,
+			Duration:    15 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			RequestsPerSecond: 10,
+			Burst:             20,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Bulk: BulkConfig{
+			MaxRows:     1000,
+			Concurrency: 4,
+		},
+	}
+}
+
+// Validate vérifie la cohérence de la configuration et retourne toutes les erreurs trouvées
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Port != "" {
+		if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+			add("server.port: %q is not a valid TCP port", c.Server.Port)
+		}
+	}
+	if c.Server.PortRangeStart < 1 || c.Server.PortRangeEnd > 65535 || c.Server.PortRangeStart > c.Server.PortRangeEnd {
+		add("server.port_range: invalid range %d-%d", c.Server.PortRangeStart, c.Server.PortRangeEnd)
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	} {
+		if timeout.value <= 0 {
+			add("%s: must be positive, got %v", timeout.name, timeout.value)
+		}
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		add("server.max_header_bytes: must be positive, got %d", c.Server.MaxHeaderBytes)
+	}
+
+	if c.Scripts.Dir == "" {
+		add("scripts.dir: must not be empty")
+	}
+	if len(c.Scripts.AllowedScripts) == 0 {
+		add("scripts.allowed: at least one script is required")
+	}
+	for _, script := range c.Scripts.AllowedScripts {
+		if script == "" || script != filepath.Base(script) || strings.Contains(script, "..") {
+			add("scripts.allowed: %q must be a plain file name", script)
+		}
+	}
+	if c.Scripts.MaxExecutionTime <= 0 {
+		add("scripts.max_execution_time: must be positive, got %v", c.Scripts.MaxExecutionTime)
+	}
+	scriptNames := make([]string, 0, len(c.Scripts.Settings))
+	for script := range c.Scripts.Settings {
+		scriptNames = append(scriptNames, script)
+	}
+	sort.Strings(scriptNames)
+	for _, script := range scriptNames {
+		settings := c.Scripts.Settings[script]
+		if !contains(c.Scripts.AllowedScripts, script) {
+			add("scripts.settings: %q is not an allowed script", script)
+		}
+		switch settings.OnDisconnect {
+		case "", DisconnectComplete, DisconnectCancel:
+		default:
+			add("scripts.settings[%s].on_disconnect: %q is not one of complete, cancel", script, settings.OnDisconnect)
+		}
+		switch settings.UserLock {
+		case "", UserLockExclusive, UserLockShared:
+		default:
+			add("scripts.settings[%s].user_lock: %q is not one of exclusive, shared", script, settings.UserLock)
+		}
+		switch settings.Protocol {
+		case "", ProtocolNone, ProtocolPrefix, ProtocolFD:
+		default:
+			add("scripts.settings[%s].protocol: %q is not one of none, prefix, fd", script, settings.Protocol)
+		}
+		if settings.MaxConcurrency < 0 {
+			add("scripts.settings[%s].max_concurrency: must not be negative, got %d", script, settings.MaxConcurrency)
+		}
+		for _, name := range sortedKeys(settings.Env) {
+			if !envNamePattern.MatchString(name) || contains(reservedEnv, name) {
+				add("scripts.settings[%s].env: %q is not an allowed variable name", script, name)
+			}
+			if _, ok := settings.Secrets[name]; ok {
+				add("scripts.settings[%s].env: %q is also declared as a secret", script, name)
+			}
+		}
+		for _, name := range sortedKeys(settings.Secrets) {
+			if !envNamePattern.MatchString(name) || contains(reservedEnv, name) {
+				add("scripts.settings[%s].secrets: %q is not an allowed variable name", script, name)
+			}
+			if err := c.checkSecretRef(settings.Secrets[name]); err != nil {
+				add("scripts.settings[%s].secrets[%s]: %v", script, name, err)
+			}
+		}
+	}
+	for i, pattern := range c.Scripts.Redact.Patterns {
+		re, err := regexp.Compile(pattern)
+		switch {
+		case err != nil:
+			add("scripts.redact.patterns[%d]: %v", i, err)
+		case re.MatchString(""):
+			add("scripts.redact.patterns[%d]: %q matches the empty string", i, pattern)
+		}
+	}
+	for i, ref := range c.Scripts.Redact.Values {
+		if err := c.checkSecretRef(ref); err != nil {
+			add("scripts.redact.values[%d]: %v", i, err)
+		}
+	}
+	if c.Scripts.Workers < 1 {
+		add("scripts.workers: must be positive, got %d", c.Scripts.Workers)
+	}
+	if c.Scripts.QueueSize < 0 {
+		add("scripts.queue_size: must not be negative, got %d", c.Scripts.QueueSize)
+	}
+	if c.Scripts.UserLockTimeout < 0 {
+		add("scripts.user_lock_timeout: must not be negative, got %v", c.Scripts.UserLockTimeout)
+	}
+	if c.Scripts.ArtifactRetention < 0 {
+		add("scripts.artifact_retention: must not be negative, got %v", c.Scripts.ArtifactRetention)
+	}
+	if c.Scripts.UserIDPattern == "" {
+		add("scripts.user_id_pattern: must not be empty")
+	} else if _, err := regexp.Compile(c.Scripts.UserIDPattern); err != nil {
+		add("scripts.user_id_pattern: %v", err)
+	}
+
+	if (c.SecretsFile == "") != (c.SecretsKeyFile == "") {
+		add("secrets: secrets_file and secrets_key_file must be set together")
+	}
+
+	if c.IdempotencyWindow <= 0 {
+		add("idempotency_window: must be positive, got %v", c.IdempotencyWindow)
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		add("tls: cert_file and key_file must be set together")
+	}
+	if c.TLS.MutualTLS() && !c.TLS.Enabled() {
+		add("tls.client_ca_file: requires cert_file and key_file")
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if proxy = strings.TrimSpace(proxy); proxy != "" && !validCIDR(proxy) {
+			add("trusted_proxies: invalid address or CIDR %q", proxy)
+		}
+	}
+	switch c.ForwardedHeader {
+	case "", ForwardedHeaderXFF, ForwardedHeaderRFC7239:
+	default:
+		add("forwarded_header: %q is not one of xff, forwarded", c.ForwardedHeader)
+	}
+	for _, rule := range c.AccessRules {
+		if !strings.HasPrefix(rule.PathPrefix, "/") {
+			add("access_rules: path %q must start with /", rule.PathPrefix)
+		}
+		for _, cidr := range append(append([]string{}, rule.Allow...), rule.Deny...) {
+			if !validCIDR(cidr) {
+				add("access_rules[%s]: invalid address or CIDR %q", rule.PathPrefix, cidr)
+			}
+		}
+	}
+
+	if c.BanPolicy.MaxFailures < 0 || c.BanPolicy.Window < 0 || c.BanPolicy.Duration < 0 {
+		add("ban: values must not be negative")
+	}
+	if c.RateLimit.RequestsPerSecond < 0 || c.RateLimit.Burst < 0 {
+		add("rate_limit: values must not be negative")
+	}
+	if c.Bulk.MaxRows < 1 || c.Bulk.Concurrency < 1 {
+		add("bulk: max_rows and concurrency must be positive")
+	}
+
+	workflowNames := make([]string, 0, len(c.Workflows))
+	for name := range c.Workflows {
+		workflowNames = append(workflowNames, name)
+	}
+	sort.Strings(workflowNames)
+	for _, name := range workflowNames {
+		errs = append(errs, c.Workflows[name].validate(name, c.Scripts.AllowedScripts)...)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		add("log.level: %q is not one of debug, info, warn, error", c.Log.Level)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate vérifie les étapes d'un workflow ; les needs ne peuvent citer que des étapes
+// déclarées avant, ce qui garantit un graphe sans cycle
+func (w WorkflowConfig) validate(name string, allowed []string) []error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("workflows[%s]"+format, append([]any{name}, args...)...))
+	}
+
+	if !workflowNamePattern.MatchString(name) {
+		add(": name must be 1 to 64 letters, digits or _ . -")
+	}
+	if len(w.Steps) == 0 {
+		add(": at least one step is required")
+	}
+	seen := make(map[string]bool)
+	for i, step := range w.Steps {
+		if !workflowNamePattern.MatchString(step.Name) {
+			add(".steps[%d].name: %q must be 1 to 64 letters, digits or _ . -", i, step.Name)
+		} else if seen[step.Name] {
+			add(".steps[%d].name: duplicate step %q", i, step.Name)
+		}
+		if !contains(allowed, step.Script) {
+			add(".steps[%s].script: %q is not an allowed script", step.Name, step.Script)
+		}
+		if step.Rollback != "" && !contains(allowed, step.Rollback) {
+			add(".steps[%s].rollback: %q is not an allowed script", step.Name, step.Rollback)
+		}
+		for _, need := range step.Needs {
+			if !seen[need] {
+				add(".steps[%s].needs: %q is not a previous step", step.Name, need)
+			}
+		}
+		seen[step.Name] = true
+	}
+	return errs
+}
+
+// contains indique si la liste contient la valeur
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSecretRef vérifie une référence de secret "file:/chemin" ou "store:nom"
+func (c Config) checkSecretRef(ref string) error {
+	switch {
+	case strings.HasPrefix(ref, SecretFilePrefix) && len(ref) > len(SecretFilePrefix):
+		return nil
+	case strings.HasPrefix(ref, SecretStorePrefix) && len(ref) > len(SecretStorePrefix):
+		if c.SecretsFile == "" {
+			return fmt.Errorf("%q requires secrets_file", ref)
+		}
+		return nil
+	default:
+		return errors.New("reference must start with file: or store:")
+	}
+}
+
+// sortedKeys retourne les clés d'une table par ordre alphabétique
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validCIDR accepte un CIDR ou une adresse IP seule
+func validCIDR(value string) bool {
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
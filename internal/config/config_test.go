@@ -0,0 +1,197 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default().Validate() error = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		errorMsg string
+	}{
+		{"invalid port", func(c *Config) { c.Server.Port = "80a" }, "server.port"},
+		{"inverted port range", func(c *Config) { c.Server.PortRangeStart, c.Server.PortRangeEnd = 9000, 8000 }, "server.port_range"},
+		{"zero write timeout", func(c *Config) { c.Server.WriteTimeout = 0 }, "server.write_timeout"},
+		{"empty scripts dir", func(c *Config) { c.Scripts.Dir = "" }, "scripts.dir"},
+		{"no allowed scripts", func(c *Config) { c.Scripts.AllowedScripts = nil }, "scripts.allowed"},
+		{"script with path", func(c *Config) { c.Scripts.AllowedScripts = []string{"../evil.sh"} }, "plain file name"},
+		{"negative execution time", func(c *Config) { c.Scripts.MaxExecutionTime = -time.Second }, "scripts.max_execution_time"},
+		{"invalid user ID pattern", func(c *Config) { c.Scripts.UserIDPattern = "([a-z" }, "scripts.user_id_pattern"},
+		{"zero idempotency window", func(c *Config) { c.IdempotencyWindow = 0 }, "idempotency_window"},
+		{"cert without key", func(c *Config) { c.TLS.CertFile = "cert.pem" }, "tls"},
+		{"client CA without HTTPS", func(c *Config) { c.TLS.ClientCAFile = "ca.pem" }, "tls.client_ca_file"},
+		{"invalid trusted proxy", func(c *Config) { c.TrustedProxies = []string{"proxy"} }, "trusted_proxies"},
+		{"invalid forwarded header", func(c *Config) { c.ForwardedHeader = "x-real-ip" }, "forwarded_header"},
+		{"relative access rule", func(c *Config) { c.AccessRules = []AccessRule{{PathPrefix: "api"}} }, "access_rules"},
+		{"invalid access CIDR", func(c *Config) { c.AccessRules = []AccessRule{{PathPrefix: "/", Deny: []string{"10.0.0.0/40"}}} }, "access_rules[/]"},
+		{"negative rate limit", func(c *Config) { c.RateLimit.Burst = -1 }, "rate_limit"},
+		{"zero workers", func(c *Config) { c.Scripts.Workers = 0 }, "scripts.workers"},
+		{"negative queue size", func(c *Config) { c.Scripts.QueueSize = -1 }, "scripts.queue_size"},
+		{"negative script concurrency", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"script2.py": {MaxConcurrency: -1}}
+		}, "max_concurrency"},
+		{"negative artifact retention", func(c *Config) { c.Scripts.ArtifactRetention = -time.Hour }, "scripts.artifact_retention"},
+		{"negative user lock timeout", func(c *Config) { c.Scripts.UserLockTimeout = -time.Second }, "scripts.user_lock_timeout"},
+		{"unknown user lock", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"script1.py": {UserLock: "read"}}
+		}, "user_lock"},
+		{"unknown protocol", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"script1.py": {Protocol: "stdout"}}
+		}, "scripts.settings[script1.py].protocol"},
+		{"reserved env variable", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"script1.py": {Env: map[string]string{"PATH": "/opt/bin"}}}
+		}, "scripts.settings[script1.py].env"},
+		{"env declared as a secret", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"script1.py": {
+				Env:     map[string]string{"IDM_TOKEN": "x"},
+				Secrets: map[string]string{"IDM_TOKEN": "file:/run/secrets/idm"},
+			}}
+		}, "also declared as a secret"},
+		{"invalid secret reference", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"script1.py": {Secrets: map[string]string{"IDM_TOKEN": "vault:idm"}}}
+		}, "secrets[IDM_TOKEN]: reference"},
+		{"store secret without store", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"script1.py": {Secrets: map[string]string{"IDM_TOKEN": "store:idm"}}}
+		}, "requires secrets_file"},
+		{"invalid redaction pattern", func(c *Config) { c.Scripts.Redact.Patterns = []string{"token=(\\S+"} }, "scripts.redact.patterns[0]"},
+		{"redaction pattern matching nothing", func(c *Config) { c.Scripts.Redact.Patterns = []string{"x*"} }, "matches the empty string"},
+		{"invalid redaction value", func(c *Config) { c.Scripts.Redact.Values = []string{"s3cr3t"} }, "scripts.redact.values[0]: reference"},
+		{"secrets file without key", func(c *Config) { c.SecretsFile = "/data/secrets" }, "secrets_key_file"},
+		{"zero bulk concurrency", func(c *Config) { c.Bulk.Concurrency = 0 }, "bulk"},
+		{"settings for unknown script", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"other.sh": {OnDisconnect: DisconnectCancel}}
+		}, "not an allowed script"},
+		{"unknown disconnect policy", func(c *Config) {
+			c.Scripts.Settings = map[string]ScriptSettings{"script1.sh": {OnDisconnect: "abort"}}
+		}, "on_disconnect"},
+		{"unknown log level", func(c *Config) { c.Log.Level = "verbose" }, "log.level"},
+		{"empty workflow", func(c *Config) { c.Workflows = map[string]WorkflowConfig{"onboarding": {}} }, "workflows[onboarding]: at least one step"},
+		{"workflow with unknown script", func(c *Config) {
+			c.Workflows = map[string]WorkflowConfig{"onboarding": {Steps: []WorkflowStep{{Name: "grant", Script: "other.sh"}}}}
+		}, "workflows[onboarding].steps[grant].script"},
+		{"workflow with unknown rollback", func(c *Config) {
+			c.Workflows = map[string]WorkflowConfig{"onboarding": {Steps: []WorkflowStep{{Name: "grant", Script: "script1.py", Rollback: "other.sh"}}}}
+		}, "steps[grant].rollback"},
+		{"duplicate workflow step", func(c *Config) {
+			c.Workflows = map[string]WorkflowConfig{"onboarding": {Steps: []WorkflowStep{{Name: "grant", Script: "script1.py"}, {Name: "grant", Script: "script2.py"}}}}
+		}, "duplicate step"},
+		{"workflow step needing a later step", func(c *Config) {
+			c.Workflows = map[string]WorkflowConfig{"onboarding": {Steps: []WorkflowStep{
+				{Name: "grant", Script: "script1.py", Needs: []string{"notify"}},
+				{Name: "notify", Script: "script2.py"},
+			}}}
+		}, "not a previous step"},
+		{"invalid workflow name", func(c *Config) {
+			c.Workflows = map[string]WorkflowConfig{"on boarding": {Steps: []WorkflowStep{{Name: "grant", Script: "script1.py"}}}}
+		}, "workflows[on boarding]: name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate() expected error but got none")
+			}
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Validate() error = %v, want message containing %s", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Scripts.Dir = ""
+	cfg.Server.ReadTimeout = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "scripts.dir") || !strings.Contains(err.Error(), "server.read_timeout") {
+		t.Errorf("Validate() error = %v, want both problems reported", err)
+	}
+}
+
+func TestTLSConfigFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		tls       TLSConfig
+		enabled   bool
+		mutualTLS bool
+	}{
+		{"plain HTTP", TLSConfig{}, false, false},
+		{"cert without key", TLSConfig{CertFile: "cert.pem"}, false, false},
+		{"HTTPS", TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}, true, false},
+		{"mutual TLS", TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem"}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tls.Enabled(); got != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.enabled)
+			}
+			if got := tt.tls.MutualTLS(); got != tt.mutualTLS {
+				t.Errorf("MutualTLS() = %v, want %v", got, tt.mutualTLS)
+			}
+		})
+	}
+}
+
+func TestWorkflowDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow WorkflowConfig
+		want     [][]string
+	}{
+		{"ordered steps", WorkflowConfig{Steps: []WorkflowStep{{Name: "a"}, {Name: "b"}, {Name: "c"}}}, [][]string{nil, {"a"}, {"b"}}},
+		{"graph", WorkflowConfig{Steps: []WorkflowStep{{Name: "a"}, {Name: "b"}, {Name: "c", Needs: []string{"a"}}}}, [][]string{nil, nil, {"a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.workflow.Dependencies(i); strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Errorf("Dependencies(%d) = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflowScripts(t *testing.T) {
+	workflow := WorkflowConfig{Steps: []WorkflowStep{
+		{Name: "grant", Script: "grant.sh", Rollback: "revoke.sh"},
+		{Name: "notify", Script: "notify.py"},
+	}}
+	if got := strings.Join(workflow.Scripts(), ","); got != "grant.sh,revoke.sh,notify.py" {
+		t.Errorf("Scripts() = %s, want grant.sh,revoke.sh,notify.py", got)
+	}
+}
+
+func TestSettingsFor(t *testing.T) {
+	cfg := Default().Scripts
+	cfg.Settings = map[string]ScriptSettings{"script1.sh": {OnDisconnect: DisconnectCancel}}
+
+	if got := cfg.SettingsFor("script1.sh").OnDisconnect; got != DisconnectCancel {
+		t.Errorf("SettingsFor(script1.sh).OnDisconnect = %s, want cancel", got)
+	}
+	if got := cfg.SettingsFor("script1.py").OnDisconnect; got != DisconnectComplete {
+		t.Errorf("SettingsFor(script1.py).OnDisconnect = %s, want complete by default", got)
+	}
+	if got := cfg.SettingsFor("script1.py").Protocol; got != ProtocolNone {
+		t.Errorf("SettingsFor(script1.py).Protocol = %s, want none by default", got)
+	}
+}
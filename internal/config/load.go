@@ -0,0 +1,347 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting associe un réglage à sa variable d'environnement et à son option de ligne de commande
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	apply func(c *Config, value string) error
+}
+
+// settings liste les réglages surchargeables, par ordre d'affichage dans l'aide
+var settings = []setting{
+	{"PORT", "port", "port d'écoute fixe", stringSetting(func(c *Config) *string { return &c.Server.Port })},
+	{"PORT_RANGE_START", "port-range-start", "début de la plage de ports libres", intSetting(func(c *Config) *int { return &c.Server.PortRangeStart })},
+	{"PORT_RANGE_END", "port-range-end", "fin de la plage de ports libres", intSetting(func(c *Config) *int { return &c.Server.PortRangeEnd })},
+	{"READ_TIMEOUT", "read-timeout", "timeout de lecture HTTP", durationSetting(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"WRITE_TIMEOUT", "write-timeout", "timeout d'écriture HTTP", durationSetting(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"IDLE_TIMEOUT", "idle-timeout", "timeout des connexions inactives", durationSetting(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "attente des scripts en cours à l'arrêt", durationSetting(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"SCRIPTS_DIR", "scripts-dir", "répertoire des scripts", stringSetting(func(c *Config) *string { return &c.Scripts.Dir })},
+	{"ALLOWED_SCRIPTS", "allowed-scripts", "scripts autorisés, séparés par des virgules", listSetting(func(c *Config) *[]string { return &c.Scripts.AllowedScripts })},
+	{"MAX_EXECUTION_TIME", "max-execution-time", "durée maximale d'un script", durationSetting(func(c *Config) *time.Duration { return &c.Scripts.MaxExecutionTime })},
+	{"CANCEL_ON_DISCONNECT", "cancel-on-disconnect", "scripts annulés si le client se déconnecte, séparés par des virgules", disconnectSetting},
+	{"MAX_WORKERS", "max-workers", "scripts exécutés simultanément, tous scripts confondus", intSetting(func(c *Config) *int { return &c.Scripts.Workers })},
+	{"QUEUE_SIZE", "queue-size", "exécutions en attente d'un worker (0 refuse immédiatement)", intSetting(func(c *Config) *int { return &c.Scripts.QueueSize })},
+	{"SCRIPT_MAX_CONCURRENCY", "script-max-concurrency", "exécutions simultanées par script (script=n,script=n)", concurrencySetting},
+	{"USER_LOCK_TIMEOUT", "user-lock-timeout", "attente du verrou de l'utilisateur cible (0 échoue immédiatement)", durationSetting(func(c *Config) *time.Duration { return &c.Scripts.UserLockTimeout })},
+	{"SHARED_USER_LOCK", "shared-user-lock", "scripts prenant un verrou partagé sur l'utilisateur, séparés par des virgules", sharedLockSetting},
+	{"WORK_DIR", "work-dir", "répertoire de base des répertoires de travail des exécutions", stringSetting(func(c *Config) *string { return &c.Scripts.WorkDir })},
+	{"ARTIFACT_RETENTION", "artifact-retention", "conservation des artefacts d'une exécution (0 les supprime à la fin)", durationSetting(func(c *Config) *time.Duration { return &c.Scripts.ArtifactRetention })},
+	{"USER_ID_PATTERN", "user-id-pattern", "expression régulière des identifiants utilisateur", stringSetting(func(c *Config) *string { return &c.Scripts.UserIDPattern })},
+	{"HISTORY_FILE", "history-file", "fichier JSON Lines de l'historique", stringSetting(func(c *Config) *string { return &c.HistoryFile })},
+	{"SCHEDULES_FILE", "schedules-file", "fichier JSON des planifications", stringSetting(func(c *Config) *string { return &c.SchedulesFile })},
+	{"IDEMPOTENCY_WINDOW", "idempotency-window", "durée de validité des clés d'idempotence", durationSetting(func(c *Config) *time.Duration { return &c.IdempotencyWindow })},
+	{"TLS_CERT_FILE", "tls-cert-file", "certificat serveur", stringSetting(func(c *Config) *string { return &c.TLS.CertFile })},
+	{"TLS_KEY_FILE", "tls-key-file", "clé privée du certificat serveur", stringSetting(func(c *Config) *string { return &c.TLS.KeyFile })},
+	{"TLS_CLIENT_CA_FILE", "tls-client-ca-file", "bundle CA des certificats clients", stringSetting(func(c *Config) *string { return &c.TLS.ClientCAFile })},
+	{"TRUSTED_PROXIES", "trusted-proxies", "proxies de confiance, séparés par des virgules", listSetting(func(c *Config) *[]string { return &c.TrustedProxies })},
+	{"FORWARDED_HEADER", "forwarded-header", "en-tête posé par les proxies de confiance (xff ou forwarded)", stringSetting(func(c *Config) *string { return &c.ForwardedHeader })},
+	{"IP_ALLOWLIST", "ip-allowlist", "CIDR autorisés par route (/=cidr,cidr;/route=cidr)", accessSetting(true)},
+	{"IP_DENYLIST", "ip-denylist", "CIDR refusés par route", accessSetting(false)},
+	{"BAN_MAX_FAILURES", "ban-max-failures", "échecs avant bannissement (0 désactive)", intSetting(func(c *Config) *int { return &c.BanPolicy.MaxFailures })},
+	{"BAN_WINDOW", "ban-window", "fenêtre de comptage des échecs", durationSetting(func(c *Config) *time.Duration { return &c.BanPolicy.Window })},
+	{"BAN_DURATION", "ban-duration", "durée du bannissement", durationSetting(func(c *Config) *time.Duration { return &c.BanPolicy.Duration })},
+	{"RATE_LIMIT_RPS", "rate-limit-rps", "requêtes par seconde par client (0 désactive)", floatSetting(func(c *Config) *float64 { return &c.RateLimit.RequestsPerSecond })},
+	{"RATE_LIMIT_BURST", "rate-limit-burst", "rafale maximale autorisée", intSetting(func(c *Config) *int { return &c.RateLimit.Burst })},
+	{"BULK_MAX_ROWS", "bulk-max-rows", "lignes maximales d'un fichier CSV d'exécution en masse", intSetting(func(c *Config) *int { return &c.Bulk.MaxRows })},
+	{"BULK_CONCURRENCY", "bulk-concurrency", "scripts exécutés simultanément par lot", intSetting(func(c *Config) *int { return &c.Bulk.Concurrency })},
+	{"API_TOKENS_FILE", "api-tokens-file", "fichier des tokens d'API", stringSetting(func(c *Config) *string { return &c.APITokensFile })},
+	{"SECRETS_FILE", "secrets-file", "coffre chiffré des secrets des scripts", stringSetting(func(c *Config) *string { return &c.SecretsFile })},
+	{"SECRETS_KEY_FILE", "secrets-key-file", "fichier de la clé du coffre de secrets", stringSetting(func(c *Config) *string { return &c.SecretsKeyFile })},
+	{"WEB_DEV_DIR", "web-dev-dir", "répertoire des templates et assets à relire à chaud (développement)", stringSetting(func(c *Config) *string { return &c.Web.DevDir })},
+	{"LOG_LEVEL", "log-level", "niveau de log (debug, info, warn, error)", stringSetting(func(c *Config) *string { return &c.Log.Level })},
+}
+
+// Load construit la configuration : valeurs par défaut, puis fichier YAML (-config ou
+// CONFIG_FILE), puis variables d'environnement, puis options de ligne de commande
+func Load(args []string, getenv func(string) string, output io.Writer) (Config, error) {
+	cfg, rest, err := LoadCommand("go-form-app", args, getenv, output)
+	if err == nil && len(rest) > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments: %s", strings.Join(rest, " "))
+	}
+	return cfg, err
+}
+
+// LoadCommand construit la configuration comme Load pour une sous-commande : les options
+// de configuration précèdent ses propres arguments, retournés tels quels
+func LoadCommand(name string, args []string, getenv func(string) string, output io.Writer) (Config, []string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	configFile := fs.String("config", getenv("CONFIG_FILE"), "fichier de configuration YAML")
+	var overrides []func(c *Config) error
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flag, s.usage+" (env "+s.env+")", func(value string) error {
+			overrides = append(overrides, func(c *Config) error { return s.apply(c, value) })
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			if err := s.apply(&cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, override := range overrides {
+		if err := override(&cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile applique le contenu d'un fichier YAML ; les clés inconnues sont refusées
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func stringSetting(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func intSetting(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func floatSetting(field func(c *Config) *float64) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func durationSetting(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func listSetting(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(c) = items
+		return nil
+	}
+}
+
+// disconnectSetting applique la politique "cancel" aux scripts listés
+func disconnectSetting(c *Config, value string) error {
+	for _, script := range strings.Split(value, ",") {
+		if script = strings.TrimSpace(script); script == "" {
+			continue
+		}
+		if c.Scripts.Settings == nil {
+			c.Scripts.Settings = make(map[string]ScriptSettings)
+		}
+		settings := c.Scripts.Settings[script]
+		settings.OnDisconnect = DisconnectCancel
+		c.Scripts.Settings[script] = settings
+	}
+	return nil
+}
+
+// sharedLockSetting applique le verrou partagé aux scripts listés
+func sharedLockSetting(c *Config, value string) error {
+	for _, script := range strings.Split(value, ",") {
+		if script = strings.TrimSpace(script); script == "" {
+			continue
+		}
+		if c.Scripts.Settings == nil {
+			c.Scripts.Settings = make(map[string]ScriptSettings)
+		}
+		settings := c.Scripts.Settings[script]
+		settings.UserLock = UserLockShared
+		c.Scripts.Settings[script] = settings
+	}
+	return nil
+}
+
+// concurrencySetting fixe la concurrence maximale des scripts listés au format "script=n,script=n"
+func concurrencySetting(c *Config, value string) error {
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		script, limit, found := strings.Cut(part, "=")
+		if !found {
+			return fmt.Errorf("invalid script concurrency %q: expected script=n", part)
+		}
+		parsed, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", limit)
+		}
+		script = strings.TrimSpace(script)
+		if c.Scripts.Settings == nil {
+			c.Scripts.Settings = make(map[string]ScriptSettings)
+		}
+		settings := c.Scripts.Settings[script]
+		settings.MaxConcurrency = parsed
+		c.Scripts.Settings[script] = settings
+	}
+	return nil
+}
+
+// accessSetting remplace les listes d'autorisation ou de refus des routes citées
+func accessSetting(allow bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		spec := [2]string{}
+		if allow {
+			spec[0] = value
+		} else {
+			spec[1] = value
+		}
+		rules, err := ParseAccessRules(spec[0], spec[1])
+		if err != nil {
+			return err
+		}
+		c.AccessRules = mergeAccessRules(c.AccessRules, rules, allow)
+		return nil
+	}
+}
+
+// mergeAccessRules remplace, pour chaque route surchargée, la liste concernée
+func mergeAccessRules(current, overrides []AccessRule, allow bool) []AccessRule {
+	merged := append([]AccessRule(nil), current...)
+	for _, override := range overrides {
+		index := -1
+		for i := range merged {
+			if merged[i].PathPrefix == override.PathPrefix {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			merged = append(merged, AccessRule{PathPrefix: override.PathPrefix})
+			index = len(merged) - 1
+		}
+		if allow {
+			merged[index].Allow = override.Allow
+		} else {
+			merged[index].Deny = override.Deny
+		}
+	}
+	return merged
+}
+
+// ParseAccessRules lit les règles au format "/=10.0.0.0/8,192.168.0.0/16;/run-script=10.20.0.0/16"
+func ParseAccessRules(allow, deny string) ([]AccessRule, error) {
+	rulesByPath := make(map[string]*AccessRule)
+	var order []string
+
+	parse := func(spec string, isAllow bool) error {
+		for _, part := range strings.Split(spec, ";") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			path, cidrs, found := strings.Cut(part, "=")
+			if !found {
+				return fmt.Errorf("invalid access rule %q: expected path=cidr[,cidr]", part)
+			}
+			path = strings.TrimSpace(path)
+
+			rule, ok := rulesByPath[path]
+			if !ok {
+				rule = &AccessRule{PathPrefix: path}
+				rulesByPath[path] = rule
+				order = append(order, path)
+			}
+
+			for _, cidr := range strings.Split(cidrs, ",") {
+				if cidr = strings.TrimSpace(cidr); cidr == "" {
+					continue
+				}
+				if isAllow {
+					rule.Allow = append(rule.Allow, cidr)
+				} else {
+					rule.Deny = append(rule.Deny, cidr)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := parse(allow, true); err != nil {
+		return nil, err
+	}
+	if err := parse(deny, false); err != nil {
+		return nil, err
+	}
+
+	rules := make([]AccessRule, 0, len(order))
+	for _, path := range order {
+		rules = append(rules, *rulesByPath[path])
+	}
+	return rules, nil
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envMap simule les variables d'environnement pour Load
+func envMap(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, envMap(nil), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Scripts.MaxExecutionTime != 30*time.Second || cfg.Server.PortRangeStart != 8001 {
+		t.Errorf("Load() = %+v, want defaults", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  port: "8100"
+  write_timeout: 45s
+scripts:
+  dir: /srv/scripts
+  allowed: [grant.sh]
+  max_execution_time: 1m
+access_rules:
+  - path: /
+    allow: [10.0.0.0/8]
+    deny: [10.66.0.0/16]
+`)
+
+	env := envMap(map[string]string{
+		"CONFIG_FILE":        path,
+		"PORT":               "8200",
+		"MAX_EXECUTION_TIME": "2m",
+		"IP_DENYLIST":        "/=10.99.0.0/16",
+	})
+
+	cfg, err := Load([]string{"-port", "8300", "-allowed-scripts", "grant.sh, revoke.sh"}, env, io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Port != "8300" {
+		t.Errorf("Port = %s, want flag value 8300", cfg.Server.Port)
+	}
+	if cfg.Scripts.MaxExecutionTime != 2*time.Minute {
+		t.Errorf("MaxExecutionTime = %v, want env value 2m", cfg.Scripts.MaxExecutionTime)
+	}
+	if cfg.Server.WriteTimeout != 45*time.Second || cfg.Scripts.Dir != "/srv/scripts" {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.Server.ReadTimeout != 10*time.Second {
+		t.Errorf("ReadTimeout = %v, want default 10s", cfg.Server.ReadTimeout)
+	}
+	if strings.Join(cfg.Scripts.AllowedScripts, ",") != "grant.sh,revoke.sh" {
+		t.Errorf("AllowedScripts = %v, want flag list", cfg.Scripts.AllowedScripts)
+	}
+	if len(cfg.AccessRules) != 1 || cfg.AccessRules[0].Allow[0] != "10.0.0.0/8" || cfg.AccessRules[0].Deny[0] != "10.99.0.0/16" {
+		t.Errorf("AccessRules = %+v, want file allowlist and env denylist", cfg.AccessRules)
+	}
+}
+
+func TestLoadCancelOnDisconnect(t *testing.T) {
+	path := writeConfigFile(t, `
+scripts:
+  settings:
+    script1.py:
+      on_disconnect: complete
+`)
+
+	cfg, err := Load([]string{"-config", path}, envMap(map[string]string{"CANCEL_ON_DISCONNECT": "script1.sh, script1.zsh"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for script, want := range map[string]string{
+		"script1.sh":  DisconnectCancel,
+		"script1.zsh": DisconnectCancel,
+		"script1.py":  DisconnectComplete,
+		"script2.py":  DisconnectComplete,
+	} {
+		if got := cfg.Scripts.SettingsFor(script).OnDisconnect; got != want {
+			t.Errorf("SettingsFor(%s).OnDisconnect = %s, want %s", script, got, want)
+		}
+	}
+}
+
+func TestLoadScriptMaxConcurrency(t *testing.T) {
+	path := writeConfigFile(t, `
+scripts:
+  workers: 4
+  settings:
+    script2.py:
+      on_disconnect: cancel
+      max_concurrency: 3
+`)
+
+	cfg, err := Load([]string{"-config", path, "-queue-size", "0"}, envMap(map[string]string{"SCRIPT_MAX_CONCURRENCY": "script2.py=1, script1.sh=2"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Scripts.Workers != 4 || cfg.Scripts.QueueSize != 0 {
+		t.Errorf("Workers/QueueSize = %d/%d, want 4/0", cfg.Scripts.Workers, cfg.Scripts.QueueSize)
+	}
+	script2 := cfg.Scripts.SettingsFor("script2.py")
+	if script2.MaxConcurrency != 1 || script2.OnDisconnect != DisconnectCancel {
+		t.Errorf("SettingsFor(script2.py) = %+v, want serial with cancel policy", script2)
+	}
+	if got := cfg.Scripts.SettingsFor("script1.sh").MaxConcurrency; got != 2 {
+		t.Errorf("SettingsFor(script1.sh).MaxConcurrency = %d, want 2", got)
+	}
+}
+
+func TestLoadUserLock(t *testing.T) {
+	cfg, err := Load([]string{"-user-lock-timeout", "10s"}, envMap(map[string]string{"SHARED_USER_LOCK": "script1.py"}), io.Discard)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Scripts.UserLockTimeout != 10*time.Second {
+		t.Errorf("UserLockTimeout = %v, want 10s", cfg.Scripts.UserLockTimeout)
+	}
+	for script, want := range map[string]string{"script1.py": UserLockShared, "script2.py": UserLockExclusive} {
+		if got := cfg.Scripts.SettingsFor(script).UserLock; got != want {
+			t.Errorf("SettingsFor(%s).UserLock = %s, want %s", script, got, want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		file     string
+		errorMsg string
+	}{
+		{"unknown file key", nil, nil, "scripts:\n  timeout: 5s\n", "field timeout not found"},
+		{"invalid file duration", nil, nil, "server:\n  read_timeout: soon\n", "config file"},
+		{"missing file", []string{"-config", "/nonexistent/config.yaml"}, nil, "", "config file"},
+		{"invalid env duration", nil, map[string]string{"BAN_WINDOW": "5 minutes"}, "", "BAN_WINDOW"},
+		{"invalid flag integer", []string{"-rate-limit-burst", "many"}, nil, "", "invalid integer"},
+		{"invalid script concurrency", nil, map[string]string{"SCRIPT_MAX_CONCURRENCY": "script2.py"}, "", "SCRIPT_MAX_CONCURRENCY"},
+		{"invalid access rule", nil, map[string]string{"IP_ALLOWLIST": "10.0.0.0/8"}, "", "IP_ALLOWLIST"},
+		{"validation failure", []string{"-scripts-dir", ""}, nil, "", "scripts.dir"},
+		{"positional argument", []string{"serve"}, nil, "", "unexpected arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			if tt.file != "" {
+				env["CONFIG_FILE"] = writeConfigFile(t, tt.file)
+			}
+
+			_, err := Load(tt.args, envMap(env), io.Discard)
+			if err == nil {
+				t.Fatal("Load() expected error but got none")
+			}
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Load() error = %v, want message containing %s", err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestLoadCommand(t *testing.T) {
+	path := writeConfigFile(t, "api_tokens_file: /data/file-tokens.json\n")
+
+	tests := []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		tokensFile string
+		rest       []string
+	}{
+		{"file from the environment", []string{"list"}, map[string]string{"CONFIG_FILE": path}, "/data/file-tokens.json", []string{"list"}},
+		{"file from the flag", []string{"-config", path, "revoke", "abc"}, nil, "/data/file-tokens.json", []string{"revoke", "abc"}},
+		{"environment overrides the file", []string{"-config", path, "list"}, map[string]string{"API_TOKENS_FILE": "/data/env-tokens.json"}, "/data/env-tokens.json", []string{"list"}},
+		{"command options are left untouched", []string{"issue", "-name", "ticketing"}, nil, "", []string{"issue", "-name", "ticketing"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, rest, err := LoadCommand("token", tt.args, envMap(tt.env), io.Discard)
+			if err != nil {
+				t.Fatalf("LoadCommand() error = %v", err)
+			}
+			if cfg.APITokensFile != tt.tokensFile || strings.Join(rest, " ") != strings.Join(tt.rest, " ") {
+				t.Errorf("LoadCommand() = %q, %q; want %q, %q", cfg.APITokensFile, rest, tt.tokensFile, tt.rest)
+			}
+		})
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+	if _, err := Load([]string{"-h"}, envMap(nil), io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Load(-h) error = %v, want flag.ErrHelp", err)
+	}
+}
+
+func TestParseAccessRules(t *testing.T) {
+	rules, err := ParseAccessRules("/=10.0.0.0/8, 192.168.0.0/16;/run-script=10.20.0.0/16", "/=10.66.0.0/16")
+	if err != nil {
+		t.Fatalf("ParseAccessRules() error = %v", err)
+	}
+
+	if len(rules) != 2 {
+		t.Fatalf("ParseAccessRules() returned %d rules, want 2", len(rules))
+	}
+	if rules[0].PathPrefix != "/" || len(rules[0].Allow) != 2 || len(rules[0].Deny) != 1 {
+		t.Errorf("ParseAccessRules() root rule = %+v", rules[0])
+	}
+	if rules[1].PathPrefix != "/run-script" || len(rules[1].Allow) != 1 {
+		t.Errorf("ParseAccessRules() run-script rule = %+v", rules[1])
+	}
+
+	if _, err := ParseAccessRules("10.0.0.0/8", ""); err == nil {
+		t.Error("ParseAccessRules() expected error for missing path")
+	}
+}
@@ -0,0 +1,356 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go-form-app/internal/scripts"
+)
+
+// Status représente l'état d'une exécution
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+	// StatusSkipped marque une étape de workflow non lancée car une étape requise a échoué
+	StatusSkipped Status = "skipped"
+)
+
+// Finished indique si l'exécution est terminée
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// Record représente une exécution de script conservée dans l'historique
+type Record struct {
+	ID        string `json:"id"`
+	Script    string `json:"script"`
+	UserID    string `json:"userId"`
+	Operator  string `json:"operator"`
+	Source    string `json:"source"`
+	RequestID string `json:"requestId,omitempty"`
+	// IdempotencyKey est la clé fournie par le client pour dédoublonner ses demandes
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// IdempotencyScope distingue les clés de clients qui partagent le même opérateur (anonyme)
+	IdempotencyScope string `json:"idempotencyScope,omitempty"`
+	// DryRun marque une simulation : le script n'a appliqué aucune modification
+	DryRun     bool          `json:"dryRun,omitempty"`
+	Status     Status        `json:"status"`
+	Success    bool          `json:"success"`
+	ExitCode   int           `json:"exitCode"`
+	Output     string        `json:"output,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	StartedAt  time.Time     `json:"startedAt,omitempty"`
+	FinishedAt time.Time     `json:"finishedAt,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	// Events et Result sont émis par un script déclarant un protocole de résultat structuré
+	Events []scripts.Event `json:"events,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	// Redactions compte les valeurs masquées dans la sortie du script
+	Redactions int `json:"redactions,omitempty"`
+	// Artifacts liste les fichiers déposés par le script, conservés dans ArtifactsDir
+	// jusqu'à l'expiration de la rétention
+	Artifacts    []scripts.Artifact `json:"artifacts,omitempty"`
+	ArtifactsDir string             `json:"artifactsDir,omitempty"`
+	// Workflow et Steps décrivent l'exécution d'un workflow et le résultat de chacune de ses étapes
+	Workflow string       `json:"workflow,omitempty"`
+	Steps    []StepResult `json:"steps,omitempty"`
+	// Replayed indique un enregistrement retourné pour une clé d'idempotence déjà utilisée (non persisté)
+	Replayed bool `json:"-"`
+}
+
+// Scripts retourne les scripts lancés par l'exécution : son script, ou pour un workflow
+// ceux de ses étapes et de leurs compensations
+func (r Record) Scripts() []string {
+	if r.Workflow == "" {
+		return []string{r.Script}
+	}
+	var scripts []string
+	for _, step := range r.Steps {
+		scripts = append(scripts, step.Script)
+		if step.RollbackScript != "" {
+			scripts = append(scripts, step.RollbackScript)
+		}
+	}
+	return scripts
+}
+
+// neverStarted indique une exécution terminée sans que le script ait démarré
+func (r *Record) neverStarted() bool {
+	return r.Status.Finished() && r.StartedAt.IsZero()
+}
+
+// StepResult est le résultat d'une étape de workflow ; l'exécution du script est
+// enregistrée séparément dans l'historique (ExecutionID)
+type StepResult struct {
+	Name        string        `json:"name"`
+	Script      string        `json:"script"`
+	Status      Status        `json:"status"`
+	ExecutionID string        `json:"executionId,omitempty"`
+	ExitCode    int           `json:"exitCode"`
+	Error       string        `json:"error,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	// Rollback* décrivent le script de compensation lancé après l'échec du workflow
+	RollbackScript      string `json:"rollbackScript,omitempty"`
+	RollbackStatus      Status `json:"rollbackStatus,omitempty"`
+	RollbackExecutionID string `json:"rollbackExecutionId,omitempty"`
+}
+
+// Filter restreint les enregistrements retournés par List
+type Filter struct {
+	Script string
+	UserID string
+	// IdempotencyKey retrouve l'exécution d'une demande, par exemple pour suivre sa progression
+	IdempotencyKey string
+	// Allowed restreint les enregistrements retournés, par exemple à la portée d'un token d'API
+	Allowed func(Record) bool
+	Limit   int
+}
+
+// matches indique si l'enregistrement satisfait le filtre
+func (f Filter) matches(rec *Record) bool {
+	if f.Script != "" && rec.Script != f.Script {
+		return false
+	}
+	if f.UserID != "" && rec.UserID != f.UserID {
+		return false
+	}
+	if f.IdempotencyKey != "" && rec.IdempotencyKey != f.IdempotencyKey {
+		return false
+	}
+	if f.Allowed != nil && !f.Allowed(*rec) {
+		return false
+	}
+	return true
+}
+
+// compactRatio déclenche la réécriture du fichier lorsqu'il contient plus de lignes que
+// compactRatio fois le nombre maximal d'enregistrements
+const compactRatio = 4
+
+// Store conserve l'historique des exécutions en mémoire, avec persistance
+// optionnelle dans un fichier JSON Lines
+type Store struct {
+	path       string
+	maxRecords int
+
+	mu      sync.RWMutex
+	records map[string]*Record
+	order   []string
+	// lines compte les lignes du fichier, plusieurs versions d'un même enregistrement comprises
+	lines int
+}
+
+// NewStore crée un historique borné à maxRecords entrées. Si path est non vide,
+// les entrées existantes sont rechargées et chaque mise à jour y est ajoutée.
+func NewStore(path string, maxRecords int) (*Store, error) {
+	s := &Store{
+		path:       path,
+		maxRecords: maxRecords,
+		records:    make(map[string]*Record),
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("creating history directory: %w", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save insère ou met à jour un enregistrement
+func (s *Store) Save(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(rec)
+
+	if s.path == "" {
+		return nil
+	}
+	if err := s.appendLine(rec); err != nil {
+		return err
+	}
+	if s.lines > compactRatio*max(s.maxRecords, len(s.order), 1) {
+		return s.compact()
+	}
+	return nil
+}
+
+// Update met à jour un enregistrement en mémoire seulement, par exemple la progression
+// d'une exécution en cours ; la version suivante passée à Save est persistée
+func (s *Store) Update(rec Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(rec)
+}
+
+// Get retourne l'enregistrement correspondant à l'identifiant
+func (s *Store) Get(id string) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.records[id]
+	if !ok {
+		return Record{}, false
+	}
+	return *rec, true
+}
+
+// FindIdempotent retourne l'exécution la plus récente créée depuis since par l'opérateur
+// avec la clé d'idempotence et la portée données
+func (s *Store) FindIdempotent(key, operator, scope string, since time.Time) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.order) - 1; i >= 0; i-- {
+		rec := s.records[s.order[i]]
+		if rec.CreatedAt.Before(since) {
+			break
+		}
+		// Une exécution terminée sans avoir démarré (refusée, file pleine) ne retient pas la clé
+		if rec.IdempotencyKey == key && rec.Operator == operator && rec.IdempotencyScope == scope && !rec.neverStarted() {
+			return *rec, true
+		}
+	}
+	return Record{}, false
+}
+
+// List retourne les enregistrements du plus récent au plus ancien
+func (s *Store) List(filter Filter) []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Record
+	for i := len(s.order) - 1; i >= 0; i-- {
+		rec := s.records[s.order[i]]
+		if !filter.matches(rec) {
+			continue
+		}
+		result = append(result, *rec)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// Check vérifie que l'historique est utilisable (fichier accessible en écriture)
+func (s *Store) Check() error {
+	if s.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// put insère l'enregistrement et applique la limite de taille (mu doit être verrouillé)
+func (s *Store) put(rec Record) {
+	if _, exists := s.records[rec.ID]; !exists {
+		s.order = append(s.order, rec.ID)
+	}
+	s.records[rec.ID] = &rec
+
+	for s.maxRecords > 0 && len(s.order) > s.maxRecords {
+		delete(s.records, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// appendLine ajoute une version de l'enregistrement au fichier (mu doit être verrouillé)
+func (s *Store) appendLine(rec Record) error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return fmt.Errorf("opening history file: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.lines++
+	return nil
+}
+
+// load relit le fichier ; la dernière version d'un enregistrement l'emporte
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("opening history file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		s.put(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading history file: %w", err)
+	}
+
+	sort.SliceStable(s.order, func(i, j int) bool {
+		return s.records[s.order[i]].CreatedAt.Before(s.records[s.order[j]].CreatedAt)
+	})
+	return nil
+}
+
+// compact réécrit le fichier avec une seule ligne par enregistrement (mu doit être verrouillé)
+func (s *Store) compact() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("compacting history file: %w", err)
+	}
+
+	encoder := json.NewEncoder(f)
+	for _, id := range s.order {
+		if err := encoder.Encode(s.records[id]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.lines = len(s.order)
+	return nil
+}
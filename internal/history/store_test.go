@@ -0,0 +1,237 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveAndGet(t *testing.T) {
+	store, err := NewStore("", 10)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	rec := Record{ID: "abc", Script: "script1.py", UserID: "test123", Status: StatusRunning, CreatedAt: time.Now()}
+	if err := store.Save(rec); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	rec.Status = StatusSucceeded
+	rec.Success = true
+	store.Save(rec)
+
+	got, ok := store.Get("abc")
+	if !ok {
+		t.Fatal("Get() did not find saved record")
+	}
+	if got.Status != StatusSucceeded || !got.Success {
+		t.Errorf("Get() = %+v, want updated record", got)
+	}
+	if len(store.List(Filter{})) != 1 {
+		t.Errorf("List() returned %d records, want 1 after update", len(store.List(Filter{})))
+	}
+
+	if _, ok := store.Get("missing"); ok {
+		t.Error("Get() found a record that was never saved")
+	}
+}
+
+func TestStoreListFilterAndOrder(t *testing.T) {
+	store, _ := NewStore("", 0)
+	base := time.Now()
+
+	store.Save(Record{ID: "1", Script: "script1.py", UserID: "user0001", CreatedAt: base})
+	store.Save(Record{ID: "2", Script: "script2.py", UserID: "user0001", IdempotencyKey: "form-42", CreatedAt: base.Add(time.Second)})
+	store.Save(Record{ID: "3", Script: "script1.py", UserID: "user0002", CreatedAt: base.Add(2 * time.Second)})
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"no filter, newest first", Filter{}, []string{"3", "2", "1"}},
+		{"by script", Filter{Script: "script1.py"}, []string{"3", "1"}},
+		{"by user", Filter{UserID: "user0001"}, []string{"2", "1"}},
+		{"by idempotency key", Filter{IdempotencyKey: "form-42"}, []string{"2"}},
+		{"with limit", Filter{Limit: 2}, []string{"3", "2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := store.List(tt.filter)
+			if len(records) != len(tt.expected) {
+				t.Fatalf("List() returned %d records, want %d", len(records), len(tt.expected))
+			}
+			for i, rec := range records {
+				if rec.ID != tt.expected[i] {
+					t.Errorf("List()[%d].ID = %s, want %s", i, rec.ID, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStoreFindIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, _ := NewStore(path, 100)
+	base := time.Now()
+
+	store.Save(Record{ID: "1", Operator: "jdupont", IdempotencyKey: "key-1", CreatedAt: base.Add(-2 * time.Hour)})
+	store.Save(Record{ID: "2", Operator: "jdupont", IdempotencyKey: "key-1", CreatedAt: base})
+	store.Save(Record{ID: "3", Operator: "mmartin", IdempotencyKey: "key-2", CreatedAt: base})
+	store.Save(Record{ID: "4", Operator: "jdupont", IdempotencyKey: "key-4", CreatedAt: base, Status: StatusQueued})
+	store.Save(Record{ID: "5", Operator: "jdupont", IdempotencyKey: "key-5", CreatedAt: base, Status: StatusFailed, FinishedAt: base})
+	store.Save(Record{ID: "6", Operator: "anonymous", IdempotencyKey: "key-6", IdempotencyScope: "ip:192.0.2.1", CreatedAt: base})
+
+	// Les clés sont conservées avec l'historique et retrouvées après un redémarrage
+	reloaded, err := NewStore(path, 100)
+	if err != nil {
+		t.Fatalf("NewStore() reload error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		operator string
+		scope    string
+		since    time.Time
+		expected string
+	}{
+		{"most recent match", "key-1", "jdupont", "", base.Add(-3 * time.Hour), "2"},
+		{"other operator", "key-2", "jdupont", "", base.Add(-time.Hour), ""},
+		{"outside the window", "key-1", "jdupont", "", base.Add(time.Minute), ""},
+		{"unknown key", "key-3", "jdupont", "", base.Add(-time.Hour), ""},
+		{"queued execution holds the key", "key-4", "jdupont", "", base.Add(-time.Hour), "4"},
+		{"execution rejected before starting releases the key", "key-5", "jdupont", "", base.Add(-time.Hour), ""},
+		{"same scope", "key-6", "anonymous", "ip:192.0.2.1", base.Add(-time.Hour), "6"},
+		{"other scope", "key-6", "anonymous", "ip:192.0.2.2", base.Add(-time.Hour), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, ok := reloaded.FindIdempotent(tt.key, tt.operator, tt.scope, tt.since)
+			if ok != (tt.expected != "") || rec.ID != tt.expected {
+				t.Errorf("FindIdempotent() = %q, %t; want %q", rec.ID, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStoreMaxRecords(t *testing.T) {
+	store, _ := NewStore("", 2)
+	store.Save(Record{ID: "1"})
+	store.Save(Record{ID: "2"})
+	store.Save(Record{ID: "3"})
+
+	if _, ok := store.Get("1"); ok {
+		t.Error("oldest record should have been evicted")
+	}
+	if len(store.List(Filter{})) != 2 {
+		t.Errorf("List() returned %d records, want 2", len(store.List(Filter{})))
+	}
+}
+
+func TestStoreCompactsWhileRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, err := NewStore(path, 2)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	// Chaque exécution est enregistrée plusieurs fois (en file, démarrée, terminée)
+	for i := 0; i < 20; i++ {
+		id := strconv.Itoa(i)
+		for _, status := range []Status{StatusQueued, StatusRunning, StatusSucceeded} {
+			if err := store.Save(Record{ID: id, Status: status}); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines > compactRatio*2 {
+		t.Errorf("history file has %d lines, want at most %d", lines, compactRatio*2)
+	}
+
+	reloaded, err := NewStore(path, 2)
+	if err != nil {
+		t.Fatalf("NewStore() reload error = %v", err)
+	}
+	records := reloaded.List(Filter{})
+	if len(records) != 2 || records[0].ID != "19" || records[0].Status != StatusSucceeded {
+		t.Errorf("reloaded records = %+v, want the last 2 succeeded", records)
+	}
+}
+
+func TestStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "history.jsonl")
+
+	store, err := NewStore(path, 100)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	base := time.Now()
+	store.Save(Record{ID: "1", Status: StatusRunning, CreatedAt: base})
+	store.Save(Record{ID: "2", Status: StatusRunning, CreatedAt: base.Add(time.Second)})
+	store.Save(Record{ID: "1", Status: StatusFailed, CreatedAt: base})
+
+	if err := store.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	reloaded, err := NewStore(path, 100)
+	if err != nil {
+		t.Fatalf("NewStore() reload error = %v", err)
+	}
+
+	rec, ok := reloaded.Get("1")
+	if !ok || rec.Status != StatusFailed {
+		t.Errorf("reloaded record = %+v, want latest version with status failed", rec)
+	}
+	if records := reloaded.List(Filter{}); len(records) != 2 || records[0].ID != "2" {
+		t.Errorf("reloaded List() = %+v, want 2 records newest first", records)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := 0
+	for _, b := range data {
+		if b == '\n' {
+			lines++
+		}
+	}
+	if lines != 2 {
+		t.Errorf("history file has %d lines after compaction, want 2", lines)
+	}
+}
+
+func TestStatusFinished(t *testing.T) {
+	if StatusQueued.Finished() || StatusRunning.Finished() {
+		t.Error("queued and running statuses should not be finished")
+	}
+	if !StatusSucceeded.Finished() || !StatusFailed.Finished() {
+		t.Error("terminal statuses should be finished")
+	}
+}
+
+func TestStoreUpdateInMemoryOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store, _ := NewStore(path, 100)
+
+	store.Save(Record{ID: "1", Status: StatusRunning, CreatedAt: time.Now()})
+	store.Update(Record{ID: "1", Status: StatusRunning, Result: []byte(`{"pct":40}`), CreatedAt: time.Now()})
+
+	if rec, _ := store.Get("1"); string(rec.Result) != `{"pct":40}` {
+		t.Errorf("Get() after Update() = %+v, want the in-memory version", rec)
+	}
+	data, _ := os.ReadFile(path)
+	if bytes.Count(data, []byte("\n")) != 1 {
+		t.Errorf("history file = %s, want only the saved version", data)
+	}
+}
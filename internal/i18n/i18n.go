@@ -0,0 +1,147 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage est utilisée quand aucune langue demandée n'est disponible
+const DefaultLanguage = "fr"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Default est le catalogue des messages embarqués (un fichier locales/<langue>.json par langue)
+var Default = mustLoad(locales, "locales")
+
+// Catalog associe à chaque langue ses messages, indexés par clé
+type Catalog struct {
+	messages  map[string]map[string]string
+	languages []string
+}
+
+// Load lit les catalogues <langue>.json du répertoire dir ; la langue par défaut est obligatoire
+func Load(fsys fs.FS, dir string) (*Catalog, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{messages: make(map[string]map[string]string)}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", file, err)
+		}
+		lang := strings.TrimSuffix(path.Base(file), ".json")
+		catalog.messages[lang] = messages
+		catalog.languages = append(catalog.languages, lang)
+	}
+	sort.Strings(catalog.languages)
+
+	if _, ok := catalog.messages[DefaultLanguage]; !ok {
+		return nil, fmt.Errorf("catalog for default language %q is missing", DefaultLanguage)
+	}
+	return catalog, nil
+}
+
+func mustLoad(fsys fs.FS, dir string) *Catalog {
+	catalog, err := Load(fsys, dir)
+	if err != nil {
+		panic(fmt.Sprintf("i18n: %v", err))
+	}
+	return catalog
+}
+
+// Languages retourne les langues disponibles, triées
+func (c *Catalog) Languages() []string {
+	return append([]string(nil), c.languages...)
+}
+
+// Supported retourne la langue normalisée ("en-GB" devient "en") si elle est disponible
+func (c *Catalog) Supported(lang string) (string, bool) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if base, _, found := strings.Cut(lang, "-"); found {
+		lang = base
+	}
+	_, ok := c.messages[lang]
+	return lang, ok
+}
+
+// Negotiate choisit la langue préférée d'un en-tête Accept-Language parmi les langues disponibles
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		lang    string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{tag, quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, candidate := range candidates {
+		if candidate.lang == "*" {
+			return DefaultLanguage
+		}
+		if lang, ok := c.Supported(candidate.lang); ok {
+			return lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// T retourne le message traduit, formaté avec args ; à défaut celui de la langue par défaut, puis la clé
+func (c *Catalog) T(lang, key string, args ...any) string {
+	message, ok := c.messages[lang][key]
+	if !ok {
+		message, ok = c.messages[DefaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Prefixed retourne les messages dont la clé commence par prefix, préfixe retiré
+func (c *Catalog) Prefixed(lang, prefix string) map[string]string {
+	result := make(map[string]string)
+	for _, source := range []string{DefaultLanguage, lang} {
+		for key, message := range c.messages[source] {
+			if name, found := strings.CutPrefix(key, prefix); found {
+				result[name] = message
+			}
+		}
+	}
+	return result
+}
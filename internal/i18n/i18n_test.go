@@ -0,0 +1,111 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDefaultCatalogsAreComplete(t *testing.T) {
+	reference := Default.messages[DefaultLanguage]
+	for _, lang := range Default.Languages() {
+		messages := Default.messages[lang]
+		for key := range reference {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("catalog %s is missing %s", lang, key)
+			}
+		}
+		for key := range messages {
+			if _, ok := reference[key]; !ok {
+				t.Errorf("catalog %s has unknown key %s", lang, key)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", DefaultLanguage},
+		{"en", "en"},
+		{"en-GB,en;q=0.9", "en"},
+		{"de-DE,de;q=0.9,en;q=0.8,fr;q=0.7", "en"},
+		{"fr;q=0.5, en;q=0.9", "en"},
+		{"en;q=0, fr", "fr"},
+		{"de, *;q=0.1", DefaultLanguage},
+		{"EN-us", "en"},
+		{"en;q=abc", DefaultLanguage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := Default.Negotiate(tt.header); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	catalog, err := Load(fstest.MapFS{
+		"locales/fr.json": {Data: []byte(`{"greeting": "Bonjour %s", "only_fr": "seulement"}`)},
+		"locales/en.json": {Data: []byte(`{"greeting": "Hello %s"}`)},
+	}, "locales")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		lang string
+		key  string
+		args []any
+		want string
+	}{
+		{"en", "greeting", []any{"Ada"}, "Hello Ada"},
+		{"fr", "greeting", []any{"Ada"}, "Bonjour Ada"},
+		{"en", "only_fr", nil, "seulement"},
+		{"de", "greeting", []any{"Ada"}, "Bonjour Ada"},
+		{"en", "missing", nil, "missing"},
+	}
+	for _, tt := range tests {
+		if got := catalog.T(tt.lang, tt.key, tt.args...); got != tt.want {
+			t.Errorf("T(%s, %s) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+
+	if got := catalog.Languages(); strings.Join(got, ",") != "en,fr" {
+		t.Errorf("Languages() = %v, want [en fr]", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"missing default language", fstest.MapFS{"locales/en.json": {Data: []byte(`{}`)}}},
+		{"invalid JSON", fstest.MapFS{"locales/fr.json": {Data: []byte(`{`)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.files, "locales"); err == nil {
+				t.Error("Load() expected error but got none")
+			}
+		})
+	}
+}
+
+func TestPrefixed(t *testing.T) {
+	messages := Default.Prefixed("en", "js.")
+	if messages["validation_failed"] != "Validation failed" {
+		t.Errorf("Prefixed() validation_failed = %q", messages["validation_failed"])
+	}
+	for key := range messages {
+		if strings.HasPrefix(key, "js.") || strings.HasPrefix(key, "ui.") {
+			t.Errorf("Prefixed() leaked key %s", key)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package jobstest fournit un gestionnaire d'exécutions pour les tests des paquets
+// construits sur jobs (exécutions en masse, planifications, workflows)
+package jobstest
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+// NewManager crée un gestionnaire avec un historique en mémoire et des scripts bash
+// temporaires, autorisés sous leur nom
+func NewManager(t testing.TB, scriptBodies map[string]string) *jobs.Manager {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "bash"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := make([]string, 0, len(scriptBodies))
+	for name, body := range scriptBodies {
+		if err := os.WriteFile(filepath.Join(dir, "bash", name), []byte(body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		allowed = append(allowed, name)
+	}
+	sort.Strings(allowed)
+
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	executor := scripts.NewExecutor(config.ScriptsConfig{
+		Dir:              dir,
+		AllowedScripts:   allowed,
+		MaxExecutionTime: 5 * time.Second,
+		WorkDir:          t.TempDir(),
+	}, logger)
+	store, err := history.NewStore("", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jobs.NewManager(executor, store, logger)
+}
@@ -0,0 +1,249 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+// Sources des demandes d'exécution enregistrées dans l'historique
+const (
+	SourceForm     = "form"
+	SourceAPI      = "api"
+	SourceBulk     = "bulk"
+	SourceSchedule = "schedule"
+	SourceWorkflow = "workflow"
+)
+
+// AnonymousOperator est l'opérateur d'une demande sans identité authentifiée (ni token
+// d'API ni certificat client)
+const AnonymousOperator = "anonymous"
+
+// ErrIdempotencyKeyReused est retournée lorsqu'une clé d'idempotence est réutilisée
+// pour un autre script, un autre utilisateur ou en changeant de mode (simulation)
+var ErrIdempotencyKeyReused = errors.New("idempotency key already used for a different request")
+
+// Manager orchestre les exécutions de scripts et leur enregistrement dans l'historique
+type Manager struct {
+	executor          *scripts.Executor
+	store             *history.Store
+	logger            *slog.Logger
+	idempotencyWindow time.Duration
+	wg                sync.WaitGroup
+
+	// idempotencyMu rend atomiques la recherche d'une clé et l'enregistrement de la nouvelle exécution
+	idempotencyMu sync.Mutex
+}
+
+// NewManager crée un gestionnaire d'exécutions
+func NewManager(executor *scripts.Executor, store *history.Store, logger *slog.Logger) *Manager {
+	return &Manager{
+		executor:          executor,
+		store:             store,
+		logger:            logger,
+		idempotencyWindow: config.Default().IdempotencyWindow,
+	}
+}
+
+// SetIdempotencyWindow fixe la durée pendant laquelle une clé d'idempotence rejoue
+// l'exécution d'origine ; une durée non positive conserve la valeur par défaut
+func (m *Manager) SetIdempotencyWindow(window time.Duration) {
+	if window > 0 {
+		m.idempotencyWindow = window
+	}
+}
+
+// Store retourne l'historique des exécutions
+func (m *Manager) Store() *history.Store {
+	return m.store
+}
+
+// Validate vérifie une demande d'exécution sans lancer le script
+func (m *Manager) Validate(req scripts.ExecutionRequest) error {
+	return m.executor.Validate(req)
+}
+
+// admission indique si une exécution a obtenu un worker ou une place dans la file
+type admission struct {
+	rec history.Record
+	err error
+}
+
+// Run exécute le script de manière synchrone et enregistre le résultat. Une clé
+// d'idempotence déjà utilisée retourne l'exécution d'origine (terminée ou en cours)
+// marquée Replayed, sans relancer le script.
+func (m *Manager) Run(ctx context.Context, req scripts.ExecutionRequest, source string) (history.Record, error) {
+	rec, err := m.reserve(ctx, req, source)
+	if err != nil || rec.Replayed {
+		return rec, err
+	}
+
+	return m.execute(ctx, rec, req, nil)
+}
+
+// Start valide la demande puis lance l'exécution en arrière-plan ; les clés
+// d'idempotence sont traitées comme dans Run. Start rend la main dès que l'exécution a démarré ou pris place dans la file
+// (scripts.ErrQueueFull sinon). L'enregistrement retourné permet de suivre le job
+// via l'historique ; le job conserve les valeurs de ctx (identifiant de requête)
+// mais pas son annulation.
+func (m *Manager) Start(ctx context.Context, req scripts.ExecutionRequest, source string) (history.Record, error) {
+	if err := m.Validate(req); err != nil {
+		return history.Record{}, err
+	}
+
+	rec, err := m.reserve(ctx, req, source)
+	if err != nil || rec.Replayed {
+		return rec, err
+	}
+
+	admitted := make(chan admission, 1)
+	jobCtx := context.WithoutCancel(ctx)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.execute(jobCtx, rec, req, admitted)
+	}()
+
+	result := <-admitted
+	return result.rec, result.err
+}
+
+// QueuePosition retourne la position d'une exécution dans la file d'attente, 0 si elle n'attend pas
+func (m *Manager) QueuePosition(id string) int {
+	return m.executor.QueuePosition(id)
+}
+
+// Wait attend la fin des exécutions lancées en arrière-plan
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// execute lance le script et met à jour l'enregistrement à chaque étape ; admitted,
+// s'il est fourni, reçoit l'enregistrement dès la mise en file, le démarrage ou le refus
+func (m *Manager) execute(ctx context.Context, rec history.Record, req scripts.ExecutionRequest, admitted chan<- admission) (history.Record, error) {
+	ctx = logging.WithAttrs(ctx, "execution_id", rec.ID)
+
+	admit := func(rec history.Record, err error) {
+		if admitted != nil {
+			admitted <- admission{rec: rec, err: err}
+			admitted = nil
+		}
+	}
+	req.ID = rec.ID
+	req.OnQueued = func(int) {
+		admit(rec, nil)
+	}
+	req.OnStarted = func() {
+		rec.Status = history.StatusRunning
+		rec.StartedAt = time.Now()
+		m.save(ctx, rec)
+		admit(rec, nil)
+	}
+	req.OnEvent = func(event scripts.Event) {
+		// Copie à chaque événement : les lecteurs de l'historique gardent leur version
+		rec.Events = append(slices.Clip(rec.Events), event)
+		m.store.Update(rec)
+	}
+
+	result, err := m.executor.Execute(ctx, req)
+
+	rec.FinishedAt = time.Now()
+	rec.Status = history.StatusFailed
+	if result != nil {
+		rec.Success = result.Success
+		rec.ExitCode = result.ExitCode
+		rec.Output = result.Output
+		rec.Error = result.Error
+		rec.Duration = result.Duration
+		rec.Events = result.Events
+		rec.Result = result.Result
+		rec.Redactions = result.Redactions
+		rec.Artifacts = result.Artifacts
+		rec.ArtifactsDir = result.ArtifactsDir
+		switch {
+		case result.Success:
+			rec.Status = history.StatusSucceeded
+		case result.Cancelled:
+			rec.Status = history.StatusCancelled
+		}
+	}
+	if err != nil && rec.Error == "" {
+		rec.Error = err.Error()
+	}
+
+	m.save(ctx, rec)
+	admit(rec, err)
+	return rec, err
+}
+
+// reserve enregistre la nouvelle exécution ; si sa clé d'idempotence a déjà servi
+// pendant la fenêtre de validité, l'exécution d'origine est retournée à la place
+func (m *Manager) reserve(ctx context.Context, req scripts.ExecutionRequest, source string) (history.Record, error) {
+	if req.IdempotencyKey == "" {
+		rec := m.newRecord(ctx, req, source)
+		m.save(ctx, rec)
+		return rec, nil
+	}
+
+	m.idempotencyMu.Lock()
+	defer m.idempotencyMu.Unlock()
+
+	existing, ok := m.store.FindIdempotent(req.IdempotencyKey, req.Operator, req.IdempotencyScope, time.Now().Add(-m.idempotencyWindow))
+	if ok {
+		if existing.Script != req.Script || existing.UserID != req.UserID || existing.DryRun != req.DryRun {
+			return history.Record{}, ErrIdempotencyKeyReused
+		}
+		m.logger.InfoContext(ctx, "idempotent request replayed", logging.KeyCategory, logging.CategoryExecution,
+			"execution_id", existing.ID, "status", existing.Status)
+		existing.Replayed = true
+		return existing, nil
+	}
+
+	rec := m.newRecord(ctx, req, source)
+	m.save(ctx, rec)
+	return rec, nil
+}
+
+// newRecord prépare l'enregistrement d'une nouvelle exécution
+func (m *Manager) newRecord(ctx context.Context, req scripts.ExecutionRequest, source string) history.Record {
+	return history.Record{
+		ID:               newID(),
+		RequestID:        logging.RequestID(ctx),
+		IdempotencyKey:   req.IdempotencyKey,
+		IdempotencyScope: req.IdempotencyScope,
+		DryRun:           req.DryRun,
+		Script:           req.Script,
+		UserID:           req.UserID,
+		Operator:         req.Operator,
+		Source:           source,
+		Status:           history.StatusQueued,
+		CreatedAt:        time.Now(),
+	}
+}
+
+// save persiste l'enregistrement sans interrompre l'exécution en cas d'échec
+func (m *Manager) save(ctx context.Context, rec history.Record) {
+	if err := m.store.Save(rec); err != nil {
+		m.logger.ErrorContext(ctx, "failed to save execution", logging.KeyCategory, logging.CategoryHistory,
+			"execution_id", rec.ID, "error", err)
+	}
+}
+
+// newID génère un identifiant d'exécution aléatoire
+func newID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,356 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+// newTestManager crée un gestionnaire avec des scripts bash temporaires ; les autres
+// paquets utilisent jobstest.NewManager, qui ne peut pas être importé ici
+func newTestManager(t *testing.T, scriptBodies map[string]string) *Manager {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "bash"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var allowed []string
+	for name, body := range scriptBodies {
+		if err := os.WriteFile(filepath.Join(dir, "bash", name), []byte(body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		allowed = append(allowed, name)
+	}
+
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	executor := scripts.NewExecutor(config.ScriptsConfig{Dir: dir, AllowedScripts: allowed, MaxExecutionTime: 5 * time.Second}, logger)
+	store, err := history.NewStore("", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(executor, store, logger)
+}
+
+func TestManagerRun(t *testing.T) {
+	manager := newTestManager(t, map[string]string{
+		"ok.sh":   "echo granted $1",
+		"fail.sh": "echo denied; exit 3",
+	})
+
+	rec, err := manager.Run(context.Background(), scripts.ExecutionRequest{UserID: "test123", Script: "ok.sh", Operator: "jdupont"}, SourceAPI)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if rec.Status != history.StatusSucceeded || !rec.Success {
+		t.Errorf("Run() record = %+v, want succeeded", rec)
+	}
+	if rec.Output != "granted test123\n" {
+		t.Errorf("Run() output = %q", rec.Output)
+	}
+	if rec.Operator != "jdupont" || rec.Source != SourceAPI {
+		t.Errorf("Run() record operator/source = %s/%s", rec.Operator, rec.Source)
+	}
+
+	stored, ok := manager.Store().Get(rec.ID)
+	if !ok || stored.Status != history.StatusSucceeded {
+		t.Errorf("stored record = %+v, want succeeded", stored)
+	}
+
+	rec, err = manager.Run(context.Background(), scripts.ExecutionRequest{UserID: "test123", Script: "fail.sh"}, SourceForm)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if rec.Status != history.StatusFailed || rec.ExitCode != 3 {
+		t.Errorf("Run() record = %+v, want failed with exit code 3", rec)
+	}
+}
+
+func TestManagerRunValidationError(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"ok.sh": "echo ok"})
+
+	rec, err := manager.Run(context.Background(), scripts.ExecutionRequest{UserID: "bad", Script: "ok.sh"}, SourceForm)
+	if err == nil {
+		t.Fatal("Run() expected validation error")
+	}
+	if rec.Status != history.StatusFailed || rec.Error == "" {
+		t.Errorf("Run() record = %+v, want failed with error", rec)
+	}
+}
+
+func TestManagerStart(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"ok.sh": "sleep 0.1; echo done"})
+
+	if _, err := manager.Start(context.Background(), scripts.ExecutionRequest{UserID: "x", Script: "ok.sh"}, SourceAPI); err == nil {
+		t.Error("Start() expected synchronous validation error")
+	}
+
+	rec, err := manager.Start(context.Background(), scripts.ExecutionRequest{UserID: "test123", Script: "ok.sh"}, SourceAPI)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if rec.Status != history.StatusRunning {
+		t.Errorf("Start() status = %s, want running", rec.Status)
+	}
+
+	manager.Wait()
+
+	stored, _ := manager.Store().Get(rec.ID)
+	if stored.Status != history.StatusSucceeded {
+		t.Errorf("job status after Wait() = %s, want succeeded", stored.Status)
+	}
+}
+
+func TestManagerPropagatesRequestID(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"ok.sh": "echo ok"})
+	var logs bytes.Buffer
+	manager.executor = scripts.NewExecutor(config.ScriptsConfig{
+		Dir:              manager.executor.ScriptsDir(),
+		AllowedScripts:   manager.executor.AllowedScripts(),
+		MaxExecutionTime: 5 * time.Second,
+	}, logging.New(&logs, slog.LevelDebug))
+
+	ctx, cancel := context.WithCancel(logging.WithRequestID(context.Background(), "req-async-1"))
+	rec, err := manager.Start(ctx, scripts.ExecutionRequest{UserID: "test123", Script: "ok.sh"}, SourceAPI)
+	cancel()
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	manager.Wait()
+
+	if rec.RequestID != "req-async-1" {
+		t.Errorf("record RequestID = %q, want req-async-1", rec.RequestID)
+	}
+	stored, _ := manager.Store().Get(rec.ID)
+	if stored.Status != history.StatusSucceeded {
+		t.Errorf("job status = %s, want succeeded despite the cancelled request context", stored.Status)
+	}
+	for _, want := range []string{`"request_id":"req-async-1"`, `"execution_id":"` + rec.ID + `"`, `"msg":"execution completed"`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("executor logs missing %s: %s", want, logs.String())
+		}
+	}
+}
+
+func TestManagerRunCancelled(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"slow.sh": "exec sleep 10"})
+	manager.executor = scripts.NewExecutor(config.ScriptsConfig{
+		Dir:              manager.executor.ScriptsDir(),
+		AllowedScripts:   manager.executor.AllowedScripts(),
+		MaxExecutionTime: 30 * time.Second,
+		Settings:         map[string]config.ScriptSettings{"slow.sh": {OnDisconnect: config.DisconnectCancel}},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	rec, err := manager.Run(ctx, scripts.ExecutionRequest{UserID: "test123", Script: "slow.sh"}, SourceForm)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if rec.Status != history.StatusCancelled || rec.Success {
+		t.Errorf("Run() record = %+v, want cancelled", rec)
+	}
+	if !rec.Status.Finished() {
+		t.Error("cancelled status should count as finished")
+	}
+}
+
+func TestManagerStartQueued(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"serial.sh": "sleep 0.2; echo done"})
+	manager.executor = scripts.NewExecutor(config.ScriptsConfig{
+		Dir:              manager.executor.ScriptsDir(),
+		AllowedScripts:   manager.executor.AllowedScripts(),
+		MaxExecutionTime: 5 * time.Second,
+		QueueSize:        1,
+		Settings:         map[string]config.ScriptSettings{"serial.sh": {MaxConcurrency: 1}},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+	req := func(userID string) scripts.ExecutionRequest {
+		return scripts.ExecutionRequest{UserID: userID, Script: "serial.sh"}
+	}
+
+	first, err := manager.Start(context.Background(), req("user0001"), SourceAPI)
+	if err != nil || first.Status != history.StatusRunning {
+		t.Fatalf("first Start() = %s, %v; want running", first.Status, err)
+	}
+	second, err := manager.Start(context.Background(), req("user0002"), SourceAPI)
+	if err != nil || second.Status != history.StatusQueued {
+		t.Fatalf("second Start() = %s, %v; want queued", second.Status, err)
+	}
+	if position := manager.QueuePosition(second.ID); position != 1 {
+		t.Errorf("QueuePosition() = %d, want 1", position)
+	}
+
+	rejected, err := manager.Start(context.Background(), req("user0003"), SourceAPI)
+	if !errors.Is(err, scripts.ErrQueueFull) || rejected.Status != history.StatusFailed {
+		t.Errorf("third Start() = %s, %v; want failed with ErrQueueFull", rejected.Status, err)
+	}
+
+	manager.Wait()
+
+	for _, rec := range []history.Record{first, second} {
+		stored, _ := manager.Store().Get(rec.ID)
+		if stored.Status != history.StatusSucceeded || stored.StartedAt.IsZero() {
+			t.Errorf("job %s after Wait() = %s, want succeeded with a start time", rec.ID, stored.Status)
+		}
+	}
+	if manager.QueuePosition(second.ID) != 0 {
+		t.Error("finished job still has a queue position")
+	}
+}
+
+func TestManagerIdempotencyKey(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"ok.sh": "sleep 0.2; echo granted $1"})
+	req := scripts.ExecutionRequest{UserID: "test123", Script: "ok.sh", Operator: "jdupont", IdempotencyKey: "key-1"}
+
+	job, err := manager.Start(context.Background(), req, SourceAPI)
+	if err != nil || job.Replayed {
+		t.Fatalf("Start() = %+v, %v; want a new job", job, err)
+	}
+
+	inProgress, err := manager.Run(context.Background(), req, SourceForm)
+	if err != nil || !inProgress.Replayed || inProgress.ID != job.ID || inProgress.Status.Finished() {
+		t.Errorf("Run() during the job = %+v, %v; want the in-progress job replayed", inProgress, err)
+	}
+
+	manager.Wait()
+
+	replayed, err := manager.Run(context.Background(), req, SourceForm)
+	if err != nil || !replayed.Replayed || replayed.ID != job.ID || replayed.Status != history.StatusSucceeded {
+		t.Errorf("Run() after the job = %+v, %v; want the original result replayed", replayed, err)
+	}
+	if len(manager.Store().List(history.Filter{})) != 1 {
+		t.Error("replayed requests should not create history records")
+	}
+
+	other := req
+	other.UserID = "test456"
+	if _, err := manager.Run(context.Background(), other, SourceForm); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Run() with a reused key error = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	other = req
+	other.DryRun = true
+	if _, err := manager.Run(context.Background(), other, SourceForm); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("Run() simulating with a reused key error = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	other = req
+	other.Operator = "mmartin"
+	if rec, err := manager.Run(context.Background(), other, SourceForm); err != nil || rec.Replayed {
+		t.Errorf("Run() by another operator = %+v, %v; want a new execution", rec, err)
+	}
+
+	// Les clés des clients anonymes sont propres à leur portée
+	anonymous := req
+	anonymous.Operator, anonymous.IdempotencyKey, anonymous.IdempotencyScope = AnonymousOperator, "key-2", "ip:192.0.2.1"
+	first, _ := manager.Run(context.Background(), anonymous, SourceAPI)
+	if rec, err := manager.Run(context.Background(), anonymous, SourceForm); err != nil || !rec.Replayed || rec.ID != first.ID {
+		t.Errorf("anonymous Run() with a used key = %+v, %v; want the original execution replayed", rec, err)
+	}
+	anonymous.IdempotencyScope = "ip:192.0.2.2"
+	if rec, err := manager.Run(context.Background(), anonymous, SourceForm); err != nil || rec.Replayed || rec.ID == first.ID {
+		t.Errorf("anonymous Run() from another client = %+v, %v; want a new execution", rec, err)
+	}
+
+	manager.SetIdempotencyWindow(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if rec, err := manager.Run(context.Background(), req, SourceForm); err != nil || rec.Replayed {
+		t.Errorf("Run() after the window = %+v, %v; want a new execution", rec, err)
+	}
+}
+
+func TestManagerIdempotencyKeyReleasedWhenRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings config.ScriptSettings
+		second   string
+		wantErr  error
+	}{
+		{"user busy", config.ScriptSettings{}, "test123", scripts.ErrUserBusy},
+		{"queue full", config.ScriptSettings{MaxConcurrency: 1}, "test456", scripts.ErrQueueFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newTestManager(t, map[string]string{"slow.sh": "sleep 0.3; echo granted $1"})
+			manager.executor = scripts.NewExecutor(config.ScriptsConfig{
+				Dir:              manager.executor.ScriptsDir(),
+				AllowedScripts:   manager.executor.AllowedScripts(),
+				MaxExecutionTime: 5 * time.Second,
+				Settings:         map[string]config.ScriptSettings{"slow.sh": tt.settings},
+			}, logging.New(os.Stdout, slog.LevelDebug))
+
+			if _, err := manager.Start(context.Background(), scripts.ExecutionRequest{UserID: "test123", Script: "slow.sh"}, SourceAPI); err != nil {
+				t.Fatalf("first Start() error = %v", err)
+			}
+
+			req := scripts.ExecutionRequest{UserID: tt.second, Script: "slow.sh", Operator: "jdupont", IdempotencyKey: "key-1"}
+			rejected, err := manager.Start(context.Background(), req, SourceAPI)
+			if !errors.Is(err, tt.wantErr) || rejected.Replayed {
+				t.Fatalf("Start() = %+v, %v; want %v", rejected, err, tt.wantErr)
+			}
+
+			manager.Wait()
+
+			retried, err := manager.Run(context.Background(), req, SourceAPI)
+			if err != nil || retried.Replayed || retried.ID == rejected.ID || retried.Status != history.StatusSucceeded {
+				t.Errorf("retry = %+v, %v; want a new successful execution", retried, err)
+			}
+			replayed, err := manager.Run(context.Background(), req, SourceAPI)
+			if err != nil || !replayed.Replayed || replayed.ID != retried.ID {
+				t.Errorf("second retry = %+v, %v; want the retried execution replayed", replayed, err)
+			}
+		})
+	}
+}
+
+func TestManagerRecordsEvents(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"events.sh": `echo '{"type":"progress","pct":50}' >&$EVENTS_FD; sleep 0.3; echo '{"type":"result","granted":["read_access"]}' >&$EVENTS_FD`})
+	manager.executor = scripts.NewExecutor(config.ScriptsConfig{
+		Dir:              manager.executor.ScriptsDir(),
+		AllowedScripts:   manager.executor.AllowedScripts(),
+		MaxExecutionTime: 5 * time.Second,
+		Settings:         map[string]config.ScriptSettings{"events.sh": {Protocol: config.ProtocolFD}},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+	store := manager.Store()
+
+	rec, err := manager.Start(context.Background(), scripts.ExecutionRequest{UserID: "test123", Script: "events.sh"}, SourceAPI)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// La progression est visible dans l'historique pendant l'exécution
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		live, _ := store.Get(rec.ID)
+		if len(live.Events) == 1 {
+			if live.Status != history.StatusRunning || *live.Events[0].Pct != 50 {
+				t.Errorf("live record = %+v, want running at 50%%", live)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no progress event recorded: %+v", live)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	manager.Wait()
+
+	final, _ := store.Get(rec.ID)
+	if final.Status != history.StatusSucceeded || len(final.Events) != 1 || string(final.Result) != `{"granted":["read_access"]}` {
+		t.Errorf("final record = %+v (result %s)", final, final.Result)
+	}
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"regexp"
+)
+
+// Catégories de logs, reprises dans le champ "category" de chaque entrée
+const (
+	CategoryHTTP      = "HTTP"
+	CategorySecurity  = "SECURITY_EVENT"
+	CategoryExecution = "EXECUTION"
+	CategoryHistory   = "HISTORY"
+	CategoryShutdown  = "SHUTDOWN"
+	CategoryTLS       = "TLS"
+	CategorySchedule  = "SCHEDULE"
+)
+
+// Clés des champs structurés communs
+const (
+	KeyCategory  = "category"
+	KeyRequestID = "request_id"
+	KeyClientIP  = "client_ip"
+	KeyOperator  = "operator"
+	KeyScript    = "script"
+	KeyUserID    = "user_id"
+)
+
+// requestIDPattern borne les identifiants de requête acceptés depuis l'extérieur
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type attrsContextKey struct{}
+
+type requestIDContextKey struct{}
+
+// New crée un logger JSON qui ajoute les champs portés par le contexte
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// WithAttrs retourne un contexte dont les logs porteront les champs donnés
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	record := slog.Record{}
+	record.Add(args...)
+
+	existing := attrsFrom(ctx)
+	attrs := make([]slog.Attr, 0, len(existing)+record.NumAttrs())
+	attrs = append(attrs, existing...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return context.WithValue(ctx, attrsContextKey{}, attrs)
+}
+
+// WithRequestID associe l'identifiant de requête au contexte et à ses logs
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDContextKey{}, id)
+	return WithAttrs(ctx, KeyRequestID, id)
+}
+
+// RequestID retourne l'identifiant de requête du contexte, vide s'il n'y en a pas
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// ValidRequestID indique si un identifiant reçu peut être propagé tel quel
+func ValidRequestID(id string) bool {
+	return requestIDPattern.MatchString(id)
+}
+
+// NewRequestID génère un identifiant de requête aléatoire
+func NewRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsContextKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler ajoute aux entrées les champs enregistrés dans le contexte
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := attrsFrom(ctx); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestContextAttrsAreLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	ctx = WithAttrs(ctx, KeyClientIP, "203.0.113.1")
+	logger.With(KeyScript, "script1.sh").InfoContext(ctx, "execution started", KeyUserID, "test123")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log line is not JSON: %v (%s)", err, buf.String())
+	}
+
+	expected := map[string]string{
+		"level":      "INFO",
+		"msg":        "execution started",
+		KeyRequestID: "req-123",
+		KeyClientIP:  "203.0.113.1",
+		KeyScript:    "script1.sh",
+		KeyUserID:    "test123",
+	}
+	for key, want := range expected {
+		if got, _ := entry[key].(string); got != want {
+			t.Errorf("entry[%s] = %q, want %q", key, got, want)
+		}
+	}
+
+	if RequestID(ctx) != "req-123" {
+		t.Errorf("RequestID() = %q, want req-123", RequestID(ctx))
+	}
+}
+
+func TestWithAttrsDoesNotLeakToParent(t *testing.T) {
+	parent := WithAttrs(context.Background(), "a", 1)
+	child := WithAttrs(parent, "b", 2)
+	WithAttrs(parent, "c", 3)
+
+	if got := len(attrsFrom(parent)); got != 1 {
+		t.Errorf("parent attrs = %d, want 1", got)
+	}
+	if got := len(attrsFrom(child)); got != 2 {
+		t.Errorf("child attrs = %d, want 2", got)
+	}
+}
+
+func TestLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelWarn)
+
+	logger.Info("ignored")
+	if buf.Len() != 0 {
+		t.Errorf("info entry written at warn level: %s", buf.String())
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"0f8fad5b-d9cb-469f-a165-70867728950e", true},
+		{"req.42:retry_1", true},
+		{"", false},
+		{"id with spaces", false},
+		{"id\ninjected", false},
+		{string(bytes.Repeat([]byte("a"), 129)), false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidRequestID(tt.id); got != tt.valid {
+			t.Errorf("ValidRequestID(%q) = %v, want %v", tt.id, got, tt.valid)
+		}
+	}
+
+	if first, second := NewRequestID(), NewRequestID(); first == second || !ValidRequestID(first) {
+		t.Errorf("NewRequestID() = %q, %q, want distinct valid IDs", first, second)
+	}
+}
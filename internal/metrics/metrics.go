@@ -0,0 +1,366 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default est le registre global exposé par l'endpoint /metrics
+var Default = NewRegistry()
+
+// DefaultDurationBuckets sont les bornes par défaut des histogrammes de durée (en secondes)
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// collector est implémenté par chaque famille de métriques
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry regroupe des métriques et les expose au format texte Prometheus
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []collector
+	names      map[string]bool
+}
+
+// NewRegistry crée un registre vide
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register ajoute une famille ; un nom dupliqué est une erreur de programmation
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[c.name()] {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", c.name()))
+	}
+	r.names[c.name()] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// Write écrit toutes les métriques au format d'exposition texte 0.0.4
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.RUnlock()
+
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].name() < collectors[j].name()
+	})
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler retourne le handler HTTP de l'endpoint /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// family contient les éléments communs à toutes les métriques à labels
+type family struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+// key construit la clé interne d'une combinaison de valeurs de labels
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// writeHeader écrit les lignes HELP et TYPE
+func (f *family) writeHeader(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.metricName, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.metricName, kind)
+}
+
+// formatLabels formate les paires label="valeur", avec un label supplémentaire optionnel
+func (f *family) formatLabels(values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, label := range f.labels {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, label, escapeLabel(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extraName, escapeLabel(extraValue)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec est un compteur monotone décliné par labels
+type CounterVec struct {
+	family
+	mu        sync.Mutex
+	values    map[string]float64
+	labelSets map[string][]string
+}
+
+// NewCounterVec crée et enregistre un compteur
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		family:    family{metricName: name, help: help, labels: labels},
+		values:    make(map[string]float64),
+		labelSets: make(map[string][]string),
+	}
+	r.register(c)
+	return c
+}
+
+// Inc incrémente le compteur de 1
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add ajoute une valeur positive au compteur
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.labelSets[key]; !ok {
+		c.labelSets[key] = append([]string(nil), labelValues...)
+	}
+	c.values[key] += delta
+}
+
+// Value retourne la valeur courante du compteur
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.family.writeHeader(w, "counter")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, c.formatLabels(c.labelSets[key], "", ""), formatFloat(c.values[key]))
+	}
+}
+
+// GaugeVec est une valeur pouvant monter ou descendre, déclinée par labels
+type GaugeVec struct {
+	family
+	mu        sync.Mutex
+	values    map[string]float64
+	labelSets map[string][]string
+}
+
+// NewGaugeVec crée et enregistre une jauge
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{
+		family:    family{metricName: name, help: help, labels: labels},
+		values:    make(map[string]float64),
+		labelSets: make(map[string][]string),
+	}
+	r.register(g)
+	return g
+}
+
+// Inc incrémente la jauge de 1
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec décrémente la jauge de 1
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Add ajoute une valeur (éventuellement négative) à la jauge
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.labelSets[key]; !ok {
+		g.labelSets[key] = append([]string(nil), labelValues...)
+	}
+	g.values[key] += delta
+}
+
+// Set fixe la valeur de la jauge
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.labelSets[key]; !ok {
+		g.labelSets[key] = append([]string(nil), labelValues...)
+	}
+	g.values[key] = value
+}
+
+// Value retourne la valeur courante de la jauge
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[key]
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.family.writeHeader(w, "gauge")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.metricName, g.formatLabels(g.labelSets[key], "", ""), formatFloat(g.values[key]))
+	}
+}
+
+// histogramSeries contient les compteurs d'une combinaison de labels
+type histogramSeries struct {
+	labels  []string
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// HistogramVec répartit des observations dans des buckets cumulatifs
+type HistogramVec struct {
+	family
+	bounds []float64
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+// NewHistogramVec crée et enregistre un histogramme
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+
+	h := &HistogramVec{
+		family: family{metricName: name, help: help, labels: labels},
+		bounds: bounds,
+		series: make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe enregistre une observation
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labels:  append([]string(nil), labelValues...),
+			buckets: make([]uint64, len(h.bounds)),
+		}
+		h.series[key] = s
+	}
+
+	for i, bound := range h.bounds {
+		if value <= bound {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+// Count retourne le nombre d'observations pour les labels donnés
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.family.writeHeader(w, "histogram")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+		for i, bound := range h.bounds {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.formatLabels(s.labels, "le", formatFloat(bound)), s.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.formatLabels(s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, h.formatLabels(s.labels, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, h.formatLabels(s.labels, "", ""), s.count)
+	}
+}
+
+// sortedKeys retourne les clés d'une map dans un ordre stable
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFloat formate une valeur selon les conventions Prometheus
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escapeLabel échappe une valeur de label
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+// escapeHelp échappe le texte d'aide
+func escapeHelp(help string) string {
+	help = strings.ReplaceAll(help, `\`, `\\`)
+	return strings.ReplaceAll(help, "\n", `\n`)
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterVec(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_requests_total", "Test counter.", "route", "status")
+
+	counter.Inc("/", "200")
+	counter.Inc("/", "200")
+	counter.Add(3, "/run-script", "500")
+	counter.Add(-1, "/", "200")
+
+	if got := counter.Value("/", "200"); got != 2 {
+		t.Errorf("Value(/, 200) = %v, want 2", got)
+	}
+	if got := counter.Value("/run-script", "500"); got != 3 {
+		t.Errorf("Value(/run-script, 500) = %v, want 3", got)
+	}
+
+	var buf bytes.Buffer
+	registry.Write(&buf)
+	output := buf.String()
+
+	for _, expected := range []string{
+		"# HELP test_requests_total Test counter.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{route="/",status="200"} 2`,
+		`test_requests_total{route="/run-script",status="500"} 3`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("output missing %q:\n%s", expected, output)
+		}
+	}
+}
+
+func TestGaugeVec(t *testing.T) {
+	registry := NewRegistry()
+	gauge := registry.NewGaugeVec("test_in_flight", "Test gauge.", "script")
+
+	gauge.Inc("script1.py")
+	gauge.Inc("script1.py")
+	gauge.Dec("script1.py")
+	gauge.Set(7, "script2.py")
+
+	if got := gauge.Value("script1.py"); got != 1 {
+		t.Errorf("Value(script1.py) = %v, want 1", got)
+	}
+	if got := gauge.Value("script2.py"); got != 7 {
+		t.Errorf("Value(script2.py) = %v, want 7", got)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.NewHistogramVec("test_duration_seconds", "Test histogram.", []float64{1, 0.1}, "script")
+
+	histogram.Observe(0.05, "script1.py")
+	histogram.Observe(0.5, "script1.py")
+	histogram.Observe(5, "script1.py")
+
+	if got := histogram.Count("script1.py"); got != 3 {
+		t.Errorf("Count() = %d, want 3", got)
+	}
+
+	var buf bytes.Buffer
+	registry.Write(&buf)
+	output := buf.String()
+
+	for _, expected := range []string{
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{script="script1.py",le="0.1"} 1`,
+		`test_duration_seconds_bucket{script="script1.py",le="1"} 2`,
+		`test_duration_seconds_bucket{script="script1.py",le="+Inf"} 3`,
+		`test_duration_seconds_sum{script="script1.py"} 5.55`,
+		`test_duration_seconds_count{script="script1.py"} 3`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("output missing %q:\n%s", expected, output)
+		}
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_escaping_total", "Help with \\ backslash.", "value")
+	counter.Inc("quote\"newline\nbackslash\\")
+
+	var buf bytes.Buffer
+	registry.Write(&buf)
+
+	if !strings.Contains(buf.String(), `test_escaping_total{value="quote\"newline\nbackslash\\"} 1`) {
+		t.Errorf("label not escaped:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `Help with \\ backslash.`) {
+		t.Errorf("help not escaped:\n%s", buf.String())
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "Test.", "a")
+
+	assertPanics(t, "duplicate name", func() { registry.NewGaugeVec("test_total", "Test.") })
+	assertPanics(t, "wrong label count", func() { counter.Inc("x", "y") })
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounterVec("test_total", "Test.").Inc()
+
+	w := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), "test_total 1") {
+		t.Errorf("body = %s", w.Body.String())
+	}
+}
+
+func assertPanics(t *testing.T, name string, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: expected panic", name)
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,165 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros associe les raccourcis usuels à leur expression à cinq champs
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSearchLimit borne la recherche de la prochaine occurrence d'une expression
+const cronSearchLimit = 5 // années
+
+// Cron est une expression cron à cinq champs : minute, heure, jour du mois, mois
+// et jour de la semaine (0 ou 7 pour dimanche), évaluée dans le fuseau de l'heure fournie
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAny et dowAny indiquent un champ « * » : si les deux jours sont restreints,
+	// une date correspond dès que l'un des deux correspond (comportement de cron)
+	domAny, dowAny bool
+}
+
+// ParseCron analyse une expression cron (listes, intervalles, pas et raccourcis @daily, ...)
+func ParseCron(expr string) (Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Cron{}, fmt.Errorf("cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	var c Cron
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return Cron{}, fmt.Errorf("cron minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return Cron{}, fmt.Errorf("cron hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return Cron{}, fmt.Errorf("cron day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return Cron{}, fmt.Errorf("cron month: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return Cron{}, fmt.Errorf("cron day of week: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = strings.HasPrefix(fields[2], "*")
+	c.dowAny = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// parseCronField convertit un champ en ensemble de valeurs autorisées (bit n pour la valeur n)
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = cronValue(from, min, max); err != nil {
+				return 0, err
+			}
+			if high, err = cronValue(to, min, max); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := cronValue(rangePart, min, max)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			if !hasStep {
+				high = value
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// cronValue convertit une valeur de champ en vérifiant ses bornes
+func cronValue(raw string, min, max int) (int, error) {
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", raw, min, max)
+	}
+	return value, nil
+}
+
+// Next retourne la première occurrence strictement postérieure à after, ou l'heure
+// zéro si l'expression ne correspond à aucune date (31 février, ...)
+func (c Cron) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchLimit, 0, 0)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		var next time.Time
+		switch {
+		case c.month&(1<<uint(month)) == 0:
+			next = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			next = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		// Un changement d'heure peut ramener time.Date en arrière : avancer d'une minute
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// dayMatches applique les champs jour du mois et jour de la semaine
+func (c Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	date := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04:05", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name  string
+		expr  string
+		after string
+		want  string
+	}{
+		{"every quarter hour", "*/15 * * * *", "2026-10-16 10:07:00", "2026-10-16 10:15:00"},
+		{"strictly after", "*/15 * * * *", "2026-10-16 10:15:00", "2026-10-16 10:30:00"},
+		{"weekdays skip the weekend", "0 9 * * 1-5", "2026-10-16 10:00:00", "2026-10-19 09:00:00"},
+		{"daily macro", "@daily", "2026-10-16 23:59:30", "2026-10-17 00:00:00"},
+		{"sunday as 7", "30 12 * * 7", "2026-10-16 00:00:00", "2026-10-18 12:30:00"},
+		{"day of month or day of week", "0 0 15 * 1", "2026-10-02 08:00:00", "2026-10-05 00:00:00"},
+		{"list and range with step", "0 8-18/5,22 * * *", "2026-10-16 13:01:00", "2026-10-16 18:00:00"},
+		{"leap day", "0 0 29 2 *", "2026-10-16 00:00:00", "2028-02-29 00:00:00"},
+		{"never matches", "0 0 31 2 *", "2026-10-16 00:00:00", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+
+			var want time.Time
+			if tt.want != "" {
+				want = date(tt.want)
+			}
+			if got := cron.Next(date(tt.after)); !got.Equal(want) {
+				t.Errorf("Next(%s) = %v, want %v", tt.after, got, want)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"@sometimes",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCron(expr); err == nil {
+				t.Errorf("ParseCron(%q) error = nil, want an error", expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package schedule
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+// maxSleep borne l'attente entre deux vérifications des échéances, pour suivre
+// les changements d'horloge du système
+const maxSleep = time.Minute
+
+// Erreurs retournées par le Scheduler
+var (
+	ErrNotFound  = errors.New("schedule not found")
+	ErrInvalid   = errors.New("invalid schedule")
+	ErrCompleted = errors.New("schedule already completed")
+)
+
+// Motifs de rejet d'une planification, toujours enveloppés dans ErrInvalid
+var (
+	ErrInvalidCron      = errors.New("invalid cron expression")
+	ErrCronNeverMatches = errors.New("cron expression never matches")
+	ErrTimingConflict   = errors.New("runAt and cron are mutually exclusive")
+	ErrTimingRequired   = errors.New("runAt or cron is required")
+	ErrRunAtPast        = errors.New("runAt must be in the future")
+)
+
+// Request décrit une planification à créer : RunAt pour une exécution unique,
+// Cron pour une exécution récurrente
+type Request struct {
+	Script   string
+	UserID   string
+	Operator string
+	RunAt    time.Time
+	Cron     string
+}
+
+// Scheduler déclenche les planifications échues via le gestionnaire d'exécutions,
+// qui enregistre chaque exécution dans l'historique
+type Scheduler struct {
+	jobs   *jobs.Manager
+	store  *Store
+	logger *slog.Logger
+
+	// mu sérialise les modifications des planifications entre l'API et la boucle de déclenchement
+	mu        sync.Mutex
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// NewScheduler crée un Scheduler ; les déclenchements commencent avec Start
+func NewScheduler(manager *jobs.Manager, store *Store, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		jobs:   manager,
+		store:  store,
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Create valide puis enregistre une planification
+func (s *Scheduler) Create(ctx context.Context, req Request) (Schedule, error) {
+	if err := s.jobs.Validate(scripts.ExecutionRequest{UserID: req.UserID, Script: req.Script}); err != nil {
+		return Schedule{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	now := time.Now()
+	schedule := Schedule{
+		ID:        newID(),
+		Script:    req.Script,
+		UserID:    req.UserID,
+		Operator:  req.Operator,
+		Status:    StatusActive,
+		CreatedAt: now,
+	}
+
+	req.Cron = strings.TrimSpace(req.Cron)
+	switch {
+	case req.Cron != "" && !req.RunAt.IsZero():
+		return Schedule{}, fmt.Errorf("%w: %w", ErrInvalid, ErrTimingConflict)
+	case req.Cron != "":
+		cron, err := ParseCron(req.Cron)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("%w: %w: %v", ErrInvalid, ErrInvalidCron, err)
+		}
+		schedule.Cron = req.Cron
+		schedule.NextRun = cron.Next(now)
+		if schedule.NextRun.IsZero() {
+			return Schedule{}, fmt.Errorf("%w: %w", ErrInvalid, ErrCronNeverMatches)
+		}
+	case req.RunAt.IsZero():
+		return Schedule{}, fmt.Errorf("%w: %w", ErrInvalid, ErrTimingRequired)
+	case !req.RunAt.After(now):
+		return Schedule{}, fmt.Errorf("%w: %w", ErrInvalid, ErrRunAtPast)
+	default:
+		schedule.RunAt = req.RunAt
+		schedule.NextRun = req.RunAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.store.Save(schedule); err != nil {
+		return Schedule{}, err
+	}
+	s.logger.InfoContext(ctx, "schedule created", logging.KeyCategory, logging.CategorySchedule,
+		"schedule_id", schedule.ID, logging.KeyScript, schedule.Script, logging.KeyUserID, schedule.UserID,
+		"cron", schedule.Cron, "next_run", schedule.NextRun)
+	s.notify()
+	return schedule, nil
+}
+
+// Get retourne une planification
+func (s *Scheduler) Get(id string) (Schedule, bool) {
+	return s.store.Get(id)
+}
+
+// List retourne les planifications de la plus ancienne à la plus récente
+func (s *Scheduler) List() []Schedule {
+	return s.store.List()
+}
+
+// Pause suspend une planification jusqu'à Resume
+func (s *Scheduler) Pause(id string) (Schedule, error) {
+	return s.update(id, func(schedule *Schedule) {
+		schedule.Status = StatusPaused
+	})
+}
+
+// Resume réactive une planification suspendue ; les occurrences récurrentes manquées
+// pendant la pause sont ignorées, une exécution unique échue part aussitôt
+func (s *Scheduler) Resume(id string) (Schedule, error) {
+	return s.update(id, func(schedule *Schedule) {
+		schedule.Status = StatusActive
+		if schedule.Recurring() {
+			schedule.NextRun = nextOccurrence(*schedule, time.Now())
+		}
+	})
+}
+
+// Delete supprime une planification ; les exécutions déjà lancées restent dans l'historique
+func (s *Scheduler) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.store.Get(id); !ok {
+		return ErrNotFound
+	}
+	if err := s.store.Delete(id); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// Start lance la boucle de déclenchement ; les échéances manquées pendant un arrêt
+// du serveur sont déclenchées une fois au démarrage
+func (s *Scheduler) Start() {
+	s.startOnce.Do(func() {
+		s.logger.Info("scheduler started", logging.KeyCategory, logging.CategorySchedule, "schedules", len(s.store.List()))
+		go s.loop()
+	})
+}
+
+// Stop arrête la boucle de déclenchement et attend sa fin
+func (s *Scheduler) Stop() {
+	// Un Scheduler jamais démarré n'a pas de boucle à attendre
+	s.startOnce.Do(func() { close(s.done) })
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// loop déclenche les planifications échues puis attend la prochaine échéance ou une modification
+func (s *Scheduler) loop() {
+	defer close(s.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-timer.C:
+		case <-s.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+
+		wait := maxSleep
+		if next := s.runDue(time.Now()); !next.IsZero() && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+		timer.Reset(wait)
+	}
+}
+
+// runDue déclenche les planifications échues et retourne la prochaine échéance
+func (s *Scheduler) runDue(now time.Time) time.Time {
+	s.mu.Lock()
+	var due []Schedule
+	var next time.Time
+	for _, schedule := range s.store.List() {
+		if schedule.Status != StatusActive {
+			continue
+		}
+		if !schedule.NextRun.After(now) {
+			due = append(due, schedule)
+			s.advance(&schedule, now)
+		}
+		if schedule.Status == StatusActive && (next.IsZero() || schedule.NextRun.Before(next)) {
+			next = schedule.NextRun
+		}
+	}
+	s.mu.Unlock()
+
+	for _, schedule := range due {
+		s.trigger(schedule)
+	}
+	return next
+}
+
+// advance passe une planification échue à sa prochaine occurrence, ou la termine ;
+// elle est enregistrée avant le déclenchement pour ne jamais lancer deux fois la même occurrence
+func (s *Scheduler) advance(schedule *Schedule, now time.Time) {
+	schedule.LastRun = now
+	schedule.Runs++
+	schedule.NextRun = nextOccurrence(*schedule, now)
+	if schedule.NextRun.IsZero() {
+		schedule.Status = StatusCompleted
+	}
+	s.save(*schedule)
+}
+
+// trigger lance l'exécution d'une occurrence ; la clé d'idempotence relie
+// l'exécution de l'historique à sa planification
+func (s *Scheduler) trigger(schedule Schedule) {
+	ctx := logging.WithAttrs(context.Background(), "schedule_id", schedule.ID)
+	rec, err := s.jobs.Start(ctx, scripts.ExecutionRequest{
+		UserID:         schedule.UserID,
+		Script:         schedule.Script,
+		Operator:       schedule.Operator,
+		IdempotencyKey: fmt.Sprintf("schedule:%s:%d", schedule.ID, schedule.NextRun.Unix()),
+	}, jobs.SourceSchedule)
+	if err != nil {
+		s.logger.WarnContext(ctx, "scheduled execution rejected", logging.KeyCategory, logging.CategorySchedule,
+			logging.KeyScript, schedule.Script, logging.KeyUserID, schedule.UserID, "error", err)
+	} else {
+		s.logger.InfoContext(ctx, "scheduled execution started", logging.KeyCategory, logging.CategorySchedule,
+			logging.KeyScript, schedule.Script, logging.KeyUserID, schedule.UserID, "execution_id", rec.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.store.Get(schedule.ID)
+	if !ok {
+		return
+	}
+	current.LastExecutionID = rec.ID
+	current.LastError = ""
+	if err != nil {
+		current.LastError = err.Error()
+	}
+	s.save(current)
+}
+
+// update applique une modification à une planification non terminée
+func (s *Scheduler) update(id string, apply func(*Schedule)) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.store.Get(id)
+	if !ok {
+		return Schedule{}, ErrNotFound
+	}
+	if schedule.Status == StatusCompleted {
+		return Schedule{}, ErrCompleted
+	}
+
+	apply(&schedule)
+	if err := s.store.Save(schedule); err != nil {
+		return Schedule{}, err
+	}
+	s.notify()
+	return schedule, nil
+}
+
+// save persiste la planification sans interrompre les déclenchements en cas d'échec
+func (s *Scheduler) save(schedule Schedule) {
+	if err := s.store.Save(schedule); err != nil {
+		s.logger.Error("failed to save schedule", logging.KeyCategory, logging.CategorySchedule,
+			"schedule_id", schedule.ID, "error", err)
+	}
+}
+
+// nextOccurrence retourne l'occurrence suivant after d'une planification récurrente,
+// l'heure zéro pour une exécution unique
+func nextOccurrence(schedule Schedule, after time.Time) time.Time {
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return cron.Next(after)
+}
+
+// notify réveille la boucle pour recalculer la prochaine échéance
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// newID génère un identifiant de planification aléatoire
+func newID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,200 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-form-app/internal/history"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/jobs/jobstest"
+	"go-form-app/internal/logging"
+)
+
+// newTestScheduler crée un Scheduler avec un script bash temporaire grant.sh
+func newTestScheduler(t *testing.T, store *Store) (*Scheduler, *jobs.Manager) {
+	t.Helper()
+
+	manager := jobstest.NewManager(t, map[string]string{"grant.sh": "echo granted $1"})
+	return NewScheduler(manager, store, logging.New(os.Stdout, slog.LevelDebug)), manager
+}
+
+// newTestStore ouvre un store de planifications (en mémoire si path est vide)
+func newTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	return store
+}
+
+// waitForRuns attend que la planification ait été déclenchée runs fois
+func waitForRuns(t *testing.T, s *Scheduler, id string, runs int) Schedule {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		schedule, _ := s.Get(id)
+		if schedule.Runs >= runs && schedule.LastExecutionID != "" {
+			return schedule
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("schedule %s = %+v, want %d runs", id, schedule, runs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSchedulerCreateValidation(t *testing.T) {
+	store := newTestStore(t, "")
+	scheduler, _ := newTestScheduler(t, store)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		req     Request
+		wantErr string
+		wantIs  error
+	}{
+		{"one-off", Request{Script: "grant.sh", UserID: "test123", RunAt: future}, "", nil},
+		{"recurring", Request{Script: "grant.sh", UserID: "test123", Cron: "0 2 * * *"}, "", nil},
+		{"invalid user", Request{Script: "grant.sh", UserID: "bad", RunAt: future}, "invalid", ErrInvalid},
+		{"script not allowed", Request{Script: "other.sh", UserID: "test123", RunAt: future}, "whitelist", ErrInvalid},
+		{"no timing", Request{Script: "grant.sh", UserID: "test123"}, "required", ErrTimingRequired},
+		{"both timings", Request{Script: "grant.sh", UserID: "test123", RunAt: future, Cron: "@daily"}, "mutually exclusive", ErrTimingConflict},
+		{"past date", Request{Script: "grant.sh", UserID: "test123", RunAt: time.Now().Add(-time.Minute)}, "future", ErrRunAtPast},
+		{"invalid cron", Request{Script: "grant.sh", UserID: "test123", Cron: "* * *"}, "5 fields", ErrInvalidCron},
+		{"cron never matches", Request{Script: "grant.sh", UserID: "test123", Cron: "0 0 30 2 *"}, "never matches", ErrCronNeverMatches},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := scheduler.Create(context.Background(), tt.req)
+			if tt.wantErr == "" {
+				if err != nil || schedule.Status != StatusActive || schedule.NextRun.IsZero() {
+					t.Errorf("Create() = %+v, %v; want an active schedule", schedule, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) || !errors.Is(err, tt.wantIs) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Create() error = %v, want ErrInvalid wrapping %v and containing %q", err, tt.wantIs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedulerRunsOneOff(t *testing.T) {
+	store := newTestStore(t, "")
+	scheduler, manager := newTestScheduler(t, store)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	schedule, err := scheduler.Create(context.Background(), Request{
+		Script: "grant.sh", UserID: "test123", Operator: "jdupont", RunAt: time.Now().Add(100 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	schedule = waitForRuns(t, scheduler, schedule.ID, 1)
+	if schedule.Status != StatusCompleted || !schedule.NextRun.IsZero() || schedule.LastError != "" {
+		t.Errorf("schedule after its run = %+v, want completed", schedule)
+	}
+	manager.Wait()
+
+	rec, ok := manager.Store().Get(schedule.LastExecutionID)
+	if !ok || rec.Source != jobs.SourceSchedule || rec.Operator != "jdupont" || rec.Output != "granted test123\n" {
+		t.Errorf("history record = %+v, want a scheduled run by jdupont", rec)
+	}
+	if !strings.HasPrefix(rec.IdempotencyKey, "schedule:"+schedule.ID+":") {
+		t.Errorf("history record idempotency key = %q, want it linked to the schedule", rec.IdempotencyKey)
+	}
+
+	if _, err := scheduler.Pause(schedule.ID); !errors.Is(err, ErrCompleted) {
+		t.Errorf("Pause() on a completed schedule error = %v, want ErrCompleted", err)
+	}
+}
+
+func TestSchedulerPauseResumeDelete(t *testing.T) {
+	store := newTestStore(t, "")
+	scheduler, manager := newTestScheduler(t, store)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	schedule, _ := scheduler.Create(context.Background(), Request{Script: "grant.sh", UserID: "test123", RunAt: time.Now().Add(100 * time.Millisecond)})
+	if paused, err := scheduler.Pause(schedule.ID); err != nil || paused.Status != StatusPaused {
+		t.Fatalf("Pause() = %+v, %v", paused, err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if paused, _ := scheduler.Get(schedule.ID); paused.Runs != 0 {
+		t.Fatalf("paused schedule ran: %+v", paused)
+	}
+
+	// Une exécution unique échue pendant la pause part dès la reprise
+	if resumed, err := scheduler.Resume(schedule.ID); err != nil || resumed.Status != StatusActive {
+		t.Fatalf("Resume() = %+v, %v", resumed, err)
+	}
+	waitForRuns(t, scheduler, schedule.ID, 1)
+	manager.Wait()
+
+	recurring, _ := scheduler.Create(context.Background(), Request{Script: "grant.sh", UserID: "test123", Cron: "@yearly"})
+	if err := scheduler.Delete(recurring.ID); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := scheduler.Delete(recurring.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrNotFound", err)
+	}
+	if _, err := scheduler.Resume("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resume() unknown error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSchedulerCatchesUpMissedRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	store := newTestStore(t, path)
+	missed := time.Now().Add(-time.Hour)
+	store.Save(Schedule{ID: "nightly", Script: "grant.sh", UserID: "test123", Cron: "0 2 * * *", Status: StatusActive, NextRun: missed, CreatedAt: missed})
+	store.Save(Schedule{ID: "paused", Script: "grant.sh", UserID: "test456", RunAt: missed, Status: StatusPaused, NextRun: missed, CreatedAt: missed})
+
+	reloaded := newTestStore(t, path)
+	scheduler, manager := newTestScheduler(t, reloaded)
+	scheduler.Start()
+
+	nightly := waitForRuns(t, scheduler, "nightly", 1)
+	scheduler.Stop()
+	manager.Wait()
+
+	if nightly.Status != StatusActive || !nightly.NextRun.After(time.Now()) || nightly.NextRun.Hour() != 2 {
+		t.Errorf("recurring schedule after catch-up = %+v, want the next 02:00 occurrence", nightly)
+	}
+	if paused, _ := scheduler.Get("paused"); paused.Runs != 0 {
+		t.Errorf("paused schedule ran on startup: %+v", paused)
+	}
+	if records := manager.Store().List(history.Filter{}); len(records) != 1 {
+		t.Errorf("history has %d executions, want 1", len(records))
+	}
+}
+
+func TestSchedulerStopWithoutStart(t *testing.T) {
+	store := newTestStore(t, "")
+	scheduler, _ := newTestScheduler(t, store)
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() blocked on a scheduler that was never started")
+	}
+}
@@ -0,0 +1,164 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status représente l'état d'une planification
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusPaused    Status = "paused"
+	StatusCompleted Status = "completed"
+)
+
+// Schedule est une exécution planifiée d'un script pour un utilisateur, unique (RunAt)
+// ou récurrente (Cron)
+type Schedule struct {
+	ID       string    `json:"id"`
+	Script   string    `json:"script"`
+	UserID   string    `json:"userId"`
+	Operator string    `json:"operator"`
+	RunAt    time.Time `json:"runAt,omitempty"`
+	Cron     string    `json:"cron,omitempty"`
+	Status   Status    `json:"status"`
+	// NextRun est la prochaine échéance ; nulle une fois la planification terminée
+	NextRun         time.Time `json:"nextRun,omitempty"`
+	LastRun         time.Time `json:"lastRun,omitempty"`
+	LastExecutionID string    `json:"lastExecutionId,omitempty"`
+	LastError       string    `json:"lastError,omitempty"`
+	Runs            int       `json:"runs"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// Recurring indique si la planification suit une expression cron
+func (s Schedule) Recurring() bool {
+	return s.Cron != ""
+}
+
+// Store conserve les planifications en mémoire, avec persistance optionnelle
+// dans un fichier JSON réécrit à chaque modification
+type Store struct {
+	path string
+
+	mu        sync.RWMutex
+	schedules map[string]*Schedule
+}
+
+// NewStore crée le store ; si path est non vide, les planifications existantes y sont rechargées
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:      path,
+		schedules: make(map[string]*Schedule),
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("creating schedules directory: %w", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save insère ou met à jour une planification
+func (s *Store) Save(schedule Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedules[schedule.ID] = &schedule
+	return s.persist()
+}
+
+// Delete retire une planification
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.schedules, id)
+	return s.persist()
+}
+
+// Get retourne la planification correspondant à l'identifiant
+func (s *Store) Get(id string) (Schedule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.schedules[id]
+	if !ok {
+		return Schedule{}, false
+	}
+	return *schedule, true
+}
+
+// List retourne les planifications de la plus ancienne à la plus récente
+func (s *Store) List() []Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		result = append(result, *schedule)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// persist réécrit le fichier de manière atomique (mu doit être verrouillé)
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	schedules := make([]*Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("writing schedules file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// load relit le fichier des planifications
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading schedules file: %w", err)
+	}
+
+	var schedules []Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return fmt.Errorf("parsing schedules file: %w", err)
+	}
+	for i := range schedules {
+		s.schedules[schedules[i].ID] = &schedules[i]
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "schedules.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	store.Save(Schedule{ID: "b", Script: "grant.sh", UserID: "test123", Cron: "@daily", Status: StatusActive, CreatedAt: now.Add(time.Second)})
+	store.Save(Schedule{ID: "a", Script: "grant.sh", UserID: "test456", RunAt: now.Add(time.Hour), Status: StatusActive, CreatedAt: now})
+	store.Save(Schedule{ID: "c", Script: "grant.sh", UserID: "test789", RunAt: now.Add(time.Hour), Status: StatusActive, CreatedAt: now.Add(2 * time.Second)})
+	store.Delete("c")
+
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("NewStore() reload error = %v", err)
+	}
+	schedules := reloaded.List()
+	if len(schedules) != 2 || schedules[0].ID != "a" || schedules[1].ID != "b" {
+		t.Fatalf("reloaded schedules = %+v, want a then b", schedules)
+	}
+	if !schedules[0].RunAt.Equal(now.Add(time.Hour)) || schedules[1].Cron != "@daily" || schedules[1].Recurring() == schedules[0].Recurring() {
+		t.Errorf("reloaded schedules lost their timing: %+v", schedules)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestStoreInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	os.WriteFile(path, []byte("{not json"), 0o640)
+
+	if _, err := NewStore(path); err == nil {
+		t.Error("NewStore() with a corrupted file error = nil, want an error")
+	}
+}
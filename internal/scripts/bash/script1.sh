@@ -2,6 +2,7 @@
 # -*- coding: utf-8 -*-
 # Script 1 - Attribution de droits utilisateur (Bash)
 # Usage: bash script1.sh <user_id>
+# Avec DRY_RUN=1, le script annonce les droits sans les attribuer
 
 set -euo pipefail
 
@@ -33,6 +34,10 @@ grant_permissions() {
     log_info "Début attribution des droits pour l'utilisateur: $user_id"
     
     for permission in "${permissions[@]}"; do
+        if [[ "${DRY_RUN:-0}" == "1" ]]; then
+            log_info "[simulation] Le droit '$permission' serait attribué à $user_id"
+            continue
+        fi
         log_info "Attribution du droit '$permission' à $user_id"
         sleep 0.1
     done
@@ -54,6 +59,10 @@ main() {
     grant_permissions "$user_id"
     
     log_info "Script exécuté avec succès"
+    if [[ "${DRY_RUN:-0}" == "1" ]]; then
+        echo "SUCCESS: Simulation terminée, aucun droit attribué à l'utilisateur $user_id (Bash)"
+        return
+    fi
     echo "SUCCESS: Droits attribués à l'utilisateur $user_id (Bash)"
 }
 
@@ -0,0 +1,89 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-form-app/internal/logging"
+)
+
+// processWaitDelay borne l'attente des sorties d'un processus tué dont les
+// descendants garderaient les pipes ouverts
+const processWaitDelay = 2 * time.Second
+
+// ErrShuttingDown est retournée lorsque l'executor n'accepte plus de nouvelles exécutions
+var ErrShuttingDown = errors.New("executor is shutting down")
+
+// runningExecution décrit une exécution en cours, annulable lors de l'arrêt
+type runningExecution struct {
+	ctx       context.Context
+	req       ExecutionRequest
+	startedAt time.Time
+	cancel    context.CancelCauseFunc
+	// workDir est le répertoire de travail, créé au démarrage du script (protégé par workDirMu)
+	workDir string
+}
+
+// InterruptedExecution décrit une exécution annulée faute d'avoir terminé à temps
+type InterruptedExecution struct {
+	Request   ExecutionRequest
+	StartedAt time.Time
+}
+
+// track enregistre une exécution en cours ; retourne false si l'executor s'arrête
+func (e *Executor) track(run *runningExecution) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.draining {
+		return false
+	}
+	e.running[run] = struct{}{}
+	e.inFlight.Add(1)
+	return true
+}
+
+// untrack retire une exécution terminée
+func (e *Executor) untrack(run *runningExecution) {
+	e.mu.Lock()
+	delete(e.running, run)
+	e.mu.Unlock()
+	e.inFlight.Done()
+}
+
+// Drain refuse toute nouvelle exécution, arrête la purge périodique, attend la fin des
+// exécutions en cours jusqu'à l'échéance de ctx, puis annule celles qui restent et les retourne
+func (e *Executor) Drain(ctx context.Context) []InterruptedExecution {
+	e.mu.Lock()
+	e.draining = true
+	e.mu.Unlock()
+	e.stopPurge()
+
+	done := make(chan struct{})
+	go func() {
+		e.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	var interrupted []InterruptedExecution
+
+	e.mu.Lock()
+	for run := range e.running {
+		e.logger.WarnContext(run.ctx, "interrupting running script", logging.KeyCategory, logging.CategoryShutdown,
+			logging.KeyScript, run.req.Script, logging.KeyUserID, run.req.UserID, logging.KeyOperator, run.req.Operator,
+			"running_ms", time.Since(run.startedAt).Milliseconds())
+		run.cancel(ErrShuttingDown)
+		interrupted = append(interrupted, InterruptedExecution{Request: run.req, StartedAt: run.startedAt})
+	}
+	e.mu.Unlock()
+
+	<-done
+	return interrupted
+}
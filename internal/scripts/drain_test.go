@@ -0,0 +1,104 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+func newDrainTestExecutor(t *testing.T, body string) *Executor {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "drain.sh"), []byte(body), 0o755)
+
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	return NewExecutor(config.ScriptsConfig{Dir: tempDir, AllowedScripts: []string{"drain.sh"}, MaxExecutionTime: 30 * time.Second}, logger)
+}
+
+func startExecution(t *testing.T, executor *Executor) <-chan *ExecutionResult {
+	t.Helper()
+
+	results := make(chan *ExecutionResult, 1)
+	go func() {
+		result, _ := executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: "drain.sh"})
+		results <- result
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		executor.mu.Lock()
+		started := len(executor.running) > 0
+		executor.mu.Unlock()
+		if started {
+			return results
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("execution did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDrainWaitsForRunningExecutions(t *testing.T) {
+	executor := newDrainTestExecutor(t, "sleep 0.3; echo done")
+	results := startExecution(t, executor)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if interrupted := executor.Drain(ctx); len(interrupted) != 0 {
+		t.Errorf("Drain() interrupted %d executions, want 0", len(interrupted))
+	}
+
+	result := <-results
+	if !result.Success {
+		t.Errorf("execution result = %+v, want success", result)
+	}
+}
+
+func TestDrainInterruptsAfterDeadline(t *testing.T) {
+	executor := newDrainTestExecutor(t, "exec sleep 10")
+	results := startExecution(t, executor)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	interrupted := executor.Drain(ctx)
+	if len(interrupted) != 1 {
+		t.Fatalf("Drain() interrupted %d executions, want 1", len(interrupted))
+	}
+	if interrupted[0].Request.Script != "drain.sh" || interrupted[0].Request.UserID != "test123" {
+		t.Errorf("interrupted request = %+v", interrupted[0].Request)
+	}
+
+	result := <-results
+	if result.Success {
+		t.Error("interrupted execution reported success")
+	}
+	if !strings.Contains(result.Error, "shutting down") {
+		t.Errorf("result.Error = %q, want shutdown interruption", result.Error)
+	}
+}
+
+func TestExecuteRejectedWhileDraining(t *testing.T) {
+	executor := newDrainTestExecutor(t, "echo ok")
+	executor.Drain(context.Background())
+
+	result, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: "drain.sh"})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Execute() error = %v, want ErrShuttingDown", err)
+	}
+	if result == nil || result.Success {
+		t.Errorf("Execute() result = %+v, want failed result", result)
+	}
+}
@@ -0,0 +1,189 @@
+package scripts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventPrefix précède les lignes du protocole sur la sortie d'un script déclaré "prefix"
+const EventPrefix = "::event::"
+
+// eventsFD est le descripteur sur lequel écrit un script déclaré "fd" (fourni dans EVENTS_FD)
+const eventsFD = 3
+
+// maxEvents borne le nombre d'événements conservés pour une exécution
+const maxEvents = 1000
+
+// maxEventLine borne la longueur d'une ligne du protocole
+const maxEventLine = 64 * 1024
+
+// Types d'événements du protocole
+const (
+	EventProgress = "progress"
+	// EventStep annonce le début d'une étape nommée, qui termine la précédente
+	EventStep = "step"
+	// EventResult porte le résultat typé du script ; il n'est pas conservé dans les événements
+	EventResult = "result"
+)
+
+// Event est un événement structuré émis par un script pendant son exécution
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Pct est l'avancement en pourcentage (0 à 100)
+	Pct     *float64 `json:"pct,omitempty"`
+	Step    string   `json:"step,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// eventCollector accumule les événements et le résultat émis par un script
+type eventCollector struct {
+	mu      sync.Mutex
+	events  []Event
+	result  json.RawMessage
+	onEvent func(Event)
+	// redactor masque les secrets du script avant conservation et diffusion
+	redactor *redactor
+}
+
+// handle analyse une ligne du protocole ; une ligne invalide est ignorée et retourne false
+func (c *eventCollector) handle(line []byte) bool {
+	event, result, err := parseEvent(bytes.TrimSpace(line), time.Now())
+	if err != nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if event.Type == EventResult {
+		c.result = c.redactor.result(result)
+		return true
+	}
+	if len(c.events) >= maxEvents {
+		return true
+	}
+	event = c.redactor.event(event)
+	c.events = append(c.events, event)
+	if c.onEvent != nil {
+		c.onEvent(event)
+	}
+	return true
+}
+
+// read analyse les lignes lues sur le descripteur dédié jusqu'à sa fermeture ; une
+// ligne trop longue est ignorée sans interrompre la lecture, pour que le script ne
+// reste jamais bloqué sur un tube plein
+func (c *eventCollector) read(r io.Reader) {
+	reader := bufio.NewReader(r)
+	var line []byte
+	skipping := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !skipping {
+			line = append(line, chunk...)
+			if len(line) > maxEventLine {
+				line, skipping = line[:0], true
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		if !skipping && len(line) > 0 {
+			c.handle(bytes.TrimRight(line, "\r\n"))
+		}
+		line, skipping = line[:0], false
+		if err != nil {
+			return
+		}
+	}
+}
+
+// collected retourne les événements et le résultat reçus
+func (c *eventCollector) collected() ([]Event, json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.events, c.result
+}
+
+// parseEvent décode une ligne JSON du protocole ; pour un résultat, l'objet est
+// retourné sans son champ type
+func parseEvent(line []byte, at time.Time) (Event, json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return Event{}, nil, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(line, &event); err != nil {
+		return Event{}, nil, err
+	}
+	if event.Type == "" {
+		return Event{}, nil, errors.New("event type is required")
+	}
+	if event.Type == EventStep && event.Step == "" {
+		return Event{}, nil, errors.New("step event without a step name")
+	}
+	event.Time = at
+	if event.Pct != nil {
+		pct := min(max(*event.Pct, 0), 100)
+		event.Pct = &pct
+	}
+
+	if event.Type != EventResult {
+		return event, nil, nil
+	}
+	delete(fields, "type")
+	result, err := json.Marshal(fields)
+	return event, result, err
+}
+
+// prefixWriter reçoit la sortie d'un script déclaré "prefix" : les lignes du protocole
+// sont transmises au collecteur, les autres forment la sortie affichée
+type prefixWriter struct {
+	output    bytes.Buffer
+	pending   []byte
+	collector *eventCollector
+}
+
+// Write découpe la sortie en lignes ; une ligne trop longue est conservée telle quelle
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(w.pending[:i+1])
+		n := copy(w.pending, w.pending[i+1:])
+		w.pending = w.pending[:n]
+	}
+	if len(w.pending) > maxEventLine {
+		w.output.Write(w.pending)
+		w.pending = w.pending[:0]
+	}
+	return len(p), nil
+}
+
+// flush traite la dernière ligne, non terminée par un retour à la ligne
+func (w *prefixWriter) flush() []byte {
+	if len(w.pending) > 0 {
+		w.line(w.pending)
+		w.pending = nil
+	}
+	return w.output.Bytes()
+}
+
+// line traite une ligne complète
+func (w *prefixWriter) line(line []byte) {
+	if rest, ok := bytes.CutPrefix(line, []byte(EventPrefix)); ok && w.collector.handle(rest) {
+		return
+	}
+	w.output.Write(line)
+}
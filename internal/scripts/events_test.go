@@ -0,0 +1,95 @@
+package scripts
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEvent(t *testing.T) {
+	at := time.Now()
+
+	tests := []struct {
+		name       string
+		line       string
+		wantType   string
+		wantPct    float64
+		wantResult string
+		wantErr    bool
+	}{
+		{"progress", `{"type":"progress","pct":40,"step":"check_prerequisites"}`, EventProgress, 40, "", false},
+		{"percentage clamped", `{"type":"progress","pct":150}`, EventProgress, 100, "", false},
+		{"result without its type", `{"type":"result","granted":["read_access"],"success":true}`, EventResult, -1, `{"granted":["read_access"],"success":true}`, false},
+		{"step", `{"type":"step","step":"configure_advanced_access"}`, EventStep, -1, "", false},
+		{"step without name", `{"type":"step"}`, "", 0, "", true},
+		{"custom type", `{"type":"log","message":"hello"}`, "log", -1, "", false},
+		{"missing type", `{"pct":40}`, "", 0, "", true},
+		{"not an object", `["progress"]`, "", 0, "", true},
+		{"invalid percentage", `{"type":"progress","pct":"40"}`, "", 0, "", true},
+		{"invalid JSON", `{"type":`, "", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, result, err := parseEvent([]byte(tt.line), at)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseEvent(%s) error = nil, want an error", tt.line)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEvent(%s) error = %v", tt.line, err)
+			}
+			if event.Type != tt.wantType || !event.Time.Equal(at) {
+				t.Errorf("event = %+v, want type %s at %v", event, tt.wantType, at)
+			}
+			if tt.wantPct >= 0 && (event.Pct == nil || *event.Pct != tt.wantPct) {
+				t.Errorf("event.Pct = %v, want %v", event.Pct, tt.wantPct)
+			}
+			if string(result) != tt.wantResult {
+				t.Errorf("result = %s, want %s", result, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	collector := &eventCollector{}
+	writer := &prefixWriter{collector: collector}
+
+	// Les lignes arrivent découpées de façon arbitraire
+	output := "checking\n" + EventPrefix + `{"type":"progress","pct":50}` + "\n" +
+		EventPrefix + "not json\n" +
+		EventPrefix + `{"type":"result","granted":["read_access"]}` + "\n" +
+		"done"
+	for _, chunk := range strings.SplitAfter(output, "e") {
+		writer.Write([]byte(chunk))
+	}
+
+	if got := string(writer.flush()); got != "checking\n"+EventPrefix+"not json\ndone" {
+		t.Errorf("output = %q, want protocol lines removed", got)
+	}
+	events, result := collector.collected()
+	if len(events) != 1 || events[0].Type != EventProgress || string(result) != `{"granted":["read_access"]}` {
+		t.Errorf("events = %+v, result = %s", events, result)
+	}
+}
+
+func TestEventCollectorRead(t *testing.T) {
+	collector := &eventCollector{}
+	input := `{"type":"progress","pct":10}` + "\n" +
+		`{"type":"progress","message":"` + strings.Repeat("x", maxEventLine) + `"}` + "\n" +
+		`{"type":"progress","pct":50}` + "\r\n" +
+		`{"type":"result","granted":["read_access"]}`
+
+	collector.read(strings.NewReader(input))
+
+	events, result := collector.collected()
+	if len(events) != 2 || *events[0].Pct != 10 || *events[1].Pct != 50 {
+		t.Errorf("events = %+v, want the oversized line skipped and reading resumed", events)
+	}
+	if string(result) != `{"granted":["read_access"]}` {
+		t.Errorf("result = %s, want the last unterminated line read", result)
+	}
+}
@@ -1,15 +1,24 @@
 package scripts
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
 )
 
 // ScriptType représente le type d'un script
@@ -26,6 +35,22 @@ type ExecutionRequest struct {
 	UserID    string
 	Script    string
 	Arguments []string
+	Operator  string
+	// ID identifie l'exécution dans la file d'attente (facultatif)
+	ID string
+	// IdempotencyKey dédoublonne les demandes répétées (gérée par le gestionnaire d'exécutions)
+	IdempotencyKey string
+	// IdempotencyScope restreint la clé à un client, l'IP d'un client anonyme par exemple ;
+	// vide, la clé est propre à l'opérateur
+	IdempotencyScope string
+	// DryRun demande une simulation, réservée aux scripts qui la déclarent
+	DryRun bool
+	// OnQueued est appelé avec la position dans la file lorsque l'exécution attend un worker
+	OnQueued func(position int)
+	// OnStarted est appelé lorsqu'un worker est attribué, juste avant le lancement du script
+	OnStarted func()
+	// OnEvent est appelé pour chaque événement émis par un script déclarant un protocole
+	OnEvent func(Event)
 }
 
 // ExecutionResult représente le résultat d'une exécution
@@ -36,6 +61,30 @@ type ExecutionResult struct {
 	ExitCode   int
 	Duration   time.Duration
 	ExecutedAt time.Time
+	// Cancelled indique une exécution interrompue (déconnexion du client ou arrêt du serveur)
+	Cancelled bool
+	// DryRun indique une simulation : le script n'a appliqué aucune modification
+	DryRun bool
+	// Events et Result sont émis par un script déclarant un protocole de résultat structuré
+	Events []Event
+	Result json.RawMessage
+	// Redactions compte les valeurs masquées dans la sortie, les événements et le résultat
+	Redactions int
+	// Artifacts liste les fichiers déposés dans ARTIFACTS_DIR, conservés dans ArtifactsDir
+	// jusqu'à l'expiration de la rétention (vides si la rétention est désactivée)
+	Artifacts    []Artifact
+	ArtifactsDir string
+}
+
+// ErrDryRunUnsupported est retournée pour une simulation demandée à un script qui ne la déclare pas
+var ErrDryRunUnsupported = errors.New("script does not support dry run")
+
+// ErrSecretUnavailable est retournée lorsqu'un secret déclaré pour le script ne peut pas être lu
+var ErrSecretUnavailable = errors.New("script secret unavailable")
+
+// SecretStore fournit les secrets référencés par "store:nom" dans les réglages des scripts
+type SecretStore interface {
+	Get(name string) (string, error)
 }
 
 // Executor gère l'exécution sécurisée des scripts Python, Bash et Zsh
@@ -43,27 +92,81 @@ type Executor struct {
 	scriptsDir       string
 	maxExecutionTime time.Duration
 	allowedScripts   []string
-	logger           *log.Logger
+	logger           *slog.Logger
 	userIDPattern    *regexp.Regexp
+	scripts          config.ScriptsConfig
+	pool             *pool
+	userLocks        *userLocks
+	secrets          SecretStore
+	redactPatterns   []*regexp.Regexp
+	// workDir accueille un répertoire de travail par exécution, conservé artifactRetention
+	workDir           string
+	artifactRetention time.Duration
+	// workDirMu sérialise la création des répertoires de travail et la purge des répertoires expirés
+	workDirMu sync.Mutex
+	// purgeInterval espace les purges périodiques lancées par StartPurge
+	purgeInterval time.Duration
+	purgeStart    sync.Once
+	purgeStopOnce sync.Once
+	purgeStop     chan struct{}
+	purgeDone     chan struct{}
+
+	mu       sync.Mutex
+	running  map[*runningExecution]struct{}
+	draining bool
+	inFlight sync.WaitGroup
 }
 
-// NewExecutor crée une nouvelle instance de l'executor sécurisé
-func NewExecutor(scriptsDir string, maxExecutionTime time.Duration, allowedScripts []string, logger *log.Logger) *Executor {
+// NewExecutor crée une nouvelle instance de l'executor sécurisé.
+// Un motif d'identifiant vide ou un nombre de workers non positif reprend la valeur par défaut.
+func NewExecutor(cfg config.ScriptsConfig, logger *slog.Logger) *Executor {
+	pattern := cfg.UserIDPattern
+	if pattern == "" {
+		pattern = config.DefaultUserIDPattern
+	}
+	if cfg.Workers < 1 {
+		cfg.Workers = config.Default().Scripts.Workers
+	}
+	if cfg.QueueSize < 0 {
+		cfg.QueueSize = 0
+	}
+	if cfg.WorkDir == "" {
+		cfg.WorkDir = DefaultWorkDir()
+	}
+
 	return &Executor{
-		scriptsDir:       scriptsDir,
-		maxExecutionTime: maxExecutionTime,
-		allowedScripts:   allowedScripts,
+		scriptsDir:       cfg.Dir,
+		maxExecutionTime: cfg.MaxExecutionTime,
+		allowedScripts:   cfg.AllowedScripts,
 		logger:           logger,
-		userIDPattern:    regexp.MustCompile(`^[a-zA-Z0-9]{7,12}$`),
+		userIDPattern:    regexp.MustCompile(pattern),
+		scripts:          cfg,
+		pool: newPool(cfg.Workers, cfg.QueueSize, func(script string) int {
+			return cfg.SettingsFor(script).MaxConcurrency
+		}),
+		userLocks:         newUserLocks(),
+		redactPatterns:    compilePatterns(cfg.Redact.Patterns),
+		workDir:           cfg.WorkDir,
+		artifactRetention: cfg.ArtifactRetention,
+		purgeInterval:     purgeIntervalFor(cfg.ArtifactRetention),
+		purgeStop:         make(chan struct{}),
+		purgeDone:         make(chan struct{}),
+		running:           make(map[*runningExecution]struct{}),
 	}
 }
 
-// Execute exécute un script Python de manière sécurisée
+// Execute exécute un script de manière sécurisée. L'annulation de ctx n'interrompt
+// le script que si sa politique de déconnexion est "cancel".
 func (e *Executor) Execute(ctx context.Context, req ExecutionRequest) (*ExecutionResult, error) {
 	startTime := time.Now()
+	logger := e.logger.With(logging.KeyScript, req.Script, logging.KeyUserID, req.UserID, logging.KeyOperator, req.Operator)
+	if req.DryRun {
+		logger = logger.With("dry_run", true)
+	}
 
 	if err := e.validateRequest(req); err != nil {
-		e.logger.Printf("SECURITY: Request validation failed: %v", err)
+		executionsTotal.Inc(req.Script, resultRejected)
+		logger.WarnContext(ctx, "request validation failed", logging.KeyCategory, logging.CategorySecurity, "error", err)
 		return &ExecutionResult{
 			Success:    false,
 			Error:      "Invalid request: " + err.Error(),
@@ -78,7 +181,8 @@ func (e *Executor) Execute(ctx context.Context, req ExecutionRequest) (*Executio
 
 	if !e.isScriptPathSafe(scriptPath) {
 		err := fmt.Errorf("script path is not safe: %s", scriptPath)
-		e.logger.Printf("SECURITY: %v", err)
+		executionsTotal.Inc(req.Script, resultRejected)
+		logger.WarnContext(ctx, "script path rejected", logging.KeyCategory, logging.CategorySecurity, "error", err)
 		return &ExecutionResult{
 			Success:    false,
 			Error:      "Script path validation failed",
@@ -86,42 +190,389 @@ func (e *Executor) Execute(ctx context.Context, req ExecutionRequest) (*Executio
 			Duration:   time.Since(startTime),
 		}, err
 	}
+	// Le script s'exécute dans son répertoire de travail : son chemin doit être absolu
+	if absPath, err := filepath.Abs(scriptPath); err == nil {
+		scriptPath = absPath
+	}
+
+	settings := e.scripts.SettingsFor(req.Script)
+	scriptEnv, secretValues, err := e.scriptEnvironment(settings)
+	if err == nil {
+		var known []string
+		known, err = e.knownSecrets()
+		secretValues = append(secretValues, known...)
+	}
+	if err != nil {
+		executionsTotal.Inc(req.Script, resultRejected)
+		logger.ErrorContext(ctx, "script secrets unavailable", logging.KeyCategory, logging.CategorySecurity, "error", err)
+		return &ExecutionResult{
+			Success:    false,
+			Error:      "Script secrets unavailable",
+			ExecutedAt: startTime,
+			Duration:   time.Since(startTime),
+		}, err
+	}
+	redactor := newRedactor(secretValues, e.redactPatterns)
+
+	if settings.OnDisconnect != config.DisconnectCancel {
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	runCtx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
+
+	run := &runningExecution{ctx: runCtx, req: req, startedAt: startTime, cancel: cancelRun}
+	if !e.track(run) {
+		executionsTotal.Inc(req.Script, resultRejected)
+		logger.WarnContext(ctx, "execution rejected", logging.KeyCategory, logging.CategoryExecution, "error", ErrShuttingDown)
+		return &ExecutionResult{
+			Success:    false,
+			Error:      ErrShuttingDown.Error(),
+			ExecutedAt: startTime,
+			Duration:   time.Since(startTime),
+		}, ErrShuttingDown
+	}
+	defer e.untrack(run)
 
-	execCtx, cancel := context.WithTimeout(ctx, e.maxExecutionTime)
+	// Le verrou de l'utilisateur cible est pris avant le worker pour qu'une opération
+	// en conflit échoue sans occuper de place dans la file
+	unlock, err := e.userLocks.acquire(runCtx, req.UserID, settings.UserLock != config.UserLockShared, e.scripts.UserLockTimeout)
+	if err != nil {
+		return e.rejectWaiting(ctx, runCtx, logger, req, startTime, err)
+	}
+	defer unlock()
+
+	release, err := e.pool.acquire(runCtx, req.ID, req.Script, func(position int) {
+		logger.InfoContext(ctx, "execution queued", logging.KeyCategory, logging.CategoryExecution, "queue_position", position)
+		if req.OnQueued != nil {
+			req.OnQueued(position)
+		}
+	})
+	if err != nil {
+		return e.rejectWaiting(ctx, runCtx, logger, req, startTime, err)
+	}
+	defer release()
+
+	// Le répertoire de travail n'est créé qu'une fois le worker obtenu : une exécution
+	// en attente n'en a pas
+	workDir, err := e.createWorkDir(run)
+	if err != nil {
+		executionsTotal.Inc(req.Script, resultRejected)
+		logger.ErrorContext(ctx, "working directory unavailable", logging.KeyCategory, logging.CategoryExecution, "error", err)
+		return &ExecutionResult{
+			Success:    false,
+			Error:      "Working directory unavailable",
+			ExecutedAt: startTime,
+			Duration:   time.Since(startTime),
+		}, err
+	}
+	keepWorkDir := false
+	defer func() {
+		if !keepWorkDir {
+			os.RemoveAll(workDir)
+		}
+	}()
+
+	// La durée mesurée est celle du script, hors attente dans la file
+	startTime = time.Now()
+
+	if req.OnStarted != nil {
+		req.OnStarted()
+	}
+
+	execCtx, cancel := context.WithTimeout(runCtx, e.maxExecutionTime)
 	defer cancel()
 
+	executionsInFlight.Inc(req.Script)
+	defer executionsInFlight.Dec(req.Script)
+
 	args := e.prepareScriptArgs(scriptType, scriptPath, req.UserID)
 	args = append(args, req.Arguments...)
 
-	e.logger.Printf("EXECUTION: Starting %s script %s for user %s", scriptType, req.Script, req.UserID)
+	logger.InfoContext(ctx, "execution started", logging.KeyCategory, logging.CategoryExecution, "script_type", scriptType)
 
 	cmd := exec.CommandContext(execCtx, interpreter, args...)
-	cmd.Env = e.buildSecureEnvironment()
+	cmd.Dir = workDir
+	// HOME et TMPDIR pointent vers le répertoire de l'exécution ; pour une variable en
+	// double, exec retient la dernière valeur
+	cmd.Env = append(e.buildSecureEnvironment(), scriptEnv...)
+	cmd.Env = append(cmd.Env, "HOME="+workDir, "TMPDIR="+workDir, "ARTIFACTS_DIR="+filepath.Join(workDir, artifactsSubDir))
+	if req.DryRun {
+		cmd.Env = append(cmd.Env, "DRY_RUN=1")
+	}
+	cmd.WaitDelay = processWaitDelay
 
-	output, err := cmd.CombinedOutput()
+	collector := &eventCollector{onEvent: req.OnEvent, redactor: redactor}
+	output, err := e.runCommand(cmd, settings.Protocol, collector)
 
 	duration := time.Since(startTime)
 	exitCode := cmd.ProcessState.ExitCode()
 
 	result := &ExecutionResult{
 		Success:    err == nil && exitCode == 0,
-		Output:     e.decodeUTF8Output(output),
+		Output:     redactor.text(e.decodeUTF8Output(output)),
 		ExitCode:   exitCode,
 		Duration:   duration,
 		ExecutedAt: startTime,
+		DryRun:     req.DryRun,
+	}
+	result.Events, result.Result = collector.collected()
+	result.Redactions = redactor.redactions()
+
+	artifacts, artifactsDir, keep, artifactsErr := e.collectArtifacts(workDir)
+	switch {
+	case artifactsErr != nil:
+		logger.WarnContext(ctx, "artifacts unavailable", logging.KeyCategory, logging.CategoryExecution, "error", artifactsErr)
+	case keep:
+		result.Artifacts, result.ArtifactsDir = artifacts, artifactsDir
+		keepWorkDir = true
+	case len(artifacts) > 0:
+		logger.WarnContext(ctx, "artifacts discarded: retention disabled", logging.KeyCategory, logging.CategoryExecution, "artifacts", len(artifacts))
+	}
+	if result.Success && reportsFailure(result.Result) {
+		result.Success = false
+		result.Error = "script reported failure in its result"
 	}
 
 	if err != nil {
 		result.Error = err.Error()
-		e.logger.Printf("EXECUTION: Script %s failed for user %s: %v", req.Script, req.UserID, err)
-	} else {
-		e.logger.Printf("EXECUTION: Script %s completed successfully for user %s (duration: %v)",
-			req.Script, req.UserID, duration)
+		switch {
+		case errors.Is(context.Cause(runCtx), ErrShuttingDown):
+			result.Cancelled = true
+			result.Error = "execution interrupted: server shutting down"
+		case errors.Is(runCtx.Err(), context.Canceled):
+			result.Cancelled = true
+			result.Error = "execution cancelled: client disconnected"
+		}
 	}
 
+	switch {
+	case result.Cancelled:
+		logger.WarnContext(ctx, "execution cancelled", logging.KeyCategory, logging.CategoryExecution,
+			"reason", result.Error, "duration_ms", duration.Milliseconds())
+	case err != nil:
+		logger.WarnContext(ctx, "execution failed", logging.KeyCategory, logging.CategoryExecution,
+			"error", err, "exit_code", exitCode, "duration_ms", duration.Milliseconds(), "redactions", result.Redactions)
+	default:
+		logger.InfoContext(ctx, "execution completed", logging.KeyCategory, logging.CategoryExecution,
+			"exit_code", exitCode, "duration_ms", duration.Milliseconds(), "redactions", result.Redactions)
+	}
+
+	e.recordMetrics(execCtx, req.Script, result)
+
 	return result, nil
 }
 
+// runCommand lance le script et retourne sa sortie combinée ; selon le protocole
+// déclaré, les lignes d'événements sont extraites de la sortie ou lues sur un descripteur dédié
+func (e *Executor) runCommand(cmd *exec.Cmd, protocol string, collector *eventCollector) ([]byte, error) {
+	switch protocol {
+	case config.ProtocolPrefix:
+		writer := &prefixWriter{collector: collector}
+		cmd.Stdout = writer
+		cmd.Stderr = writer
+		cmd.Env = append(cmd.Env, "EVENTS_PREFIX="+EventPrefix)
+		err := cmd.Run()
+		return writer.flush(), err
+
+	case config.ProtocolFD:
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("creating events pipe: %w", err)
+		}
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		cmd.ExtraFiles = []*os.File{writer}
+		cmd.Env = append(cmd.Env, fmt.Sprintf("EVENTS_FD=%d", eventsFD))
+
+		err = cmd.Start()
+		writer.Close()
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			collector.read(reader)
+		}()
+		err = cmd.Wait()
+		// Un processus enfant détaché peut garder le descripteur ouvert après la fin du script
+		select {
+		case <-done:
+		case <-time.After(processWaitDelay):
+		}
+		reader.Close()
+		<-done
+		return output.Bytes(), err
+
+	default:
+		return cmd.CombinedOutput()
+	}
+}
+
+// reportsFailure indique si le résultat structuré déclare explicitement un échec ("success": false)
+func reportsFailure(result json.RawMessage) bool {
+	if result == nil {
+		return false
+	}
+	var status struct {
+		Success *bool `json:"success"`
+	}
+	return json.Unmarshal(result, &status) == nil && status.Success != nil && !*status.Success
+}
+
+// rejectWaiting construit le résultat d'une exécution qui n'a pas obtenu le verrou de
+// l'utilisateur ou un worker : conflit, file pleine, ou annulation pendant l'attente
+func (e *Executor) rejectWaiting(ctx, runCtx context.Context, logger *slog.Logger, req ExecutionRequest, startTime time.Time, err error) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		Success:    false,
+		Error:      err.Error(),
+		ExecutedAt: startTime,
+		Duration:   time.Since(startTime),
+	}
+
+	switch {
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrUserBusy):
+		executionsTotal.Inc(req.Script, resultRejected)
+		logger.WarnContext(ctx, "execution rejected", logging.KeyCategory, logging.CategoryExecution, "error", err)
+		return result, err
+	case errors.Is(context.Cause(runCtx), ErrShuttingDown):
+		result.Error = "execution interrupted: server shutting down"
+	default:
+		result.Error = "execution cancelled: client disconnected"
+	}
+
+	result.Cancelled = true
+	executionsTotal.Inc(req.Script, resultCancelled)
+	logger.WarnContext(ctx, "execution cancelled", logging.KeyCategory, logging.CategoryExecution,
+		"reason", result.Error, "queued_ms", result.Duration.Milliseconds())
+	return result, nil
+}
+
+// UseSecretStore branche le coffre qui fournit les secrets "store:nom"
+func (e *Executor) UseSecretStore(store SecretStore) {
+	e.secrets = store
+}
+
+// CheckSecrets vérifie que les secrets déclarés pour le script et les valeurs à masquer sont lisibles
+func (e *Executor) CheckSecrets(scriptName string) error {
+	if _, _, err := e.scriptEnvironment(e.scripts.SettingsFor(scriptName)); err != nil {
+		return err
+	}
+	_, err := e.knownSecrets()
+	return err
+}
+
+// knownSecrets lit les valeurs à masquer dans la sortie de tous les scripts
+func (e *Executor) knownSecrets() ([]string, error) {
+	values := make([]string, 0, len(e.scripts.Redact.Values))
+	for _, ref := range e.scripts.Redact.Values {
+		value, err := e.resolveSecret(ref)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrSecretUnavailable, ref, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// scriptEnvironment retourne les variables déclarées pour le script, secrets compris,
+// ainsi que les valeurs des secrets à masquer ; les secrets sont relus à chaque exécution
+func (e *Executor) scriptEnvironment(settings config.ScriptSettings) ([]string, []string, error) {
+	env := make([]string, 0, len(settings.Env)+len(settings.Secrets))
+	for name, value := range settings.Env {
+		env = append(env, name+"="+value)
+	}
+
+	values := make([]string, 0, len(settings.Secrets))
+	for name, ref := range settings.Secrets {
+		value, err := e.resolveSecret(ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %v", ErrSecretUnavailable, name, err)
+		}
+		env = append(env, name+"="+value)
+		values = append(values, value)
+	}
+	sort.Strings(env)
+	return env, values, nil
+}
+
+// resolveSecret lit la valeur d'une référence "file:/chemin" ou "store:nom"
+func (e *Executor) resolveSecret(ref string) (string, error) {
+	if path, ok := strings.CutPrefix(ref, config.SecretFilePrefix); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	name, _ := strings.CutPrefix(ref, config.SecretStorePrefix)
+	if e.secrets == nil {
+		return "", errors.New("no secrets store configured")
+	}
+	return e.secrets.Get(name)
+}
+
+// QueuePosition retourne la position d'une exécution dans la file d'attente, 0 si elle n'attend pas
+func (e *Executor) QueuePosition(id string) int {
+	return e.pool.position(id)
+}
+
+// Validate vérifie une demande d'exécution sans lancer le script
+func (e *Executor) Validate(req ExecutionRequest) error {
+	return e.validateRequest(req)
+}
+
+// AllowedScripts retourne la liste des scripts autorisés
+func (e *Executor) AllowedScripts() []string {
+	return e.allowedScripts
+}
+
+// SupportsDryRun indique si le script déclare savoir simuler son exécution
+func (e *Executor) SupportsDryRun(scriptName string) bool {
+	return e.scripts.SettingsFor(scriptName).DryRun
+}
+
+// ScriptType retourne le type d'un script d'après son extension
+func (e *Executor) ScriptType(scriptName string) ScriptType {
+	return e.detectScriptType(scriptName)
+}
+
+// ScriptPath retourne le chemin du script dans le répertoire des scripts
+func (e *Executor) ScriptPath(scriptName string) string {
+	return e.getScriptPath(scriptName, e.detectScriptType(scriptName))
+}
+
+// Interpreter retourne la commande d'interpréteur utilisée pour le script
+func (e *Executor) Interpreter(scriptName string) string {
+	return e.getInterpreterCommand(e.detectScriptType(scriptName))
+}
+
+// ScriptsDir retourne le répertoire racine des scripts
+func (e *Executor) ScriptsDir() string {
+	return e.scriptsDir
+}
+
+// recordMetrics met à jour les métriques d'exécution du script
+func (e *Executor) recordMetrics(execCtx context.Context, script string, result *ExecutionResult) {
+	outcome := resultFailure
+	switch {
+	case result.Success:
+		outcome = resultSuccess
+	case errors.Is(execCtx.Err(), context.DeadlineExceeded):
+		outcome = resultTimeout
+	case errors.Is(execCtx.Err(), context.Canceled):
+		outcome = resultCancelled
+	}
+
+	executionsTotal.Inc(script, outcome)
+	executionDuration.Observe(result.Duration.Seconds(), script)
+	exitCodesTotal.Inc(script, strconv.Itoa(result.ExitCode))
+}
+
 // validateRequest valide la demande d'exécution
 func (e *Executor) validateRequest(req ExecutionRequest) error {
 	if !e.userIDPattern.MatchString(req.UserID) {
@@ -152,6 +603,10 @@ func (e *Executor) validateRequest(req ExecutionRequest) error {
 		}
 	}
 
+	if req.DryRun && !e.SupportsDryRun(req.Script) {
+		return fmt.Errorf("%w: %s", ErrDryRunUnsupported, req.Script)
+	}
+
 	return nil
 }
 
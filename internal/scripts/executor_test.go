@@ -2,20 +2,25 @@ package scripts
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
 )
 
 func TestNewExecutor(t *testing.T) {
 	scriptsDir := "/test/scripts"
 	maxTime := 30 * time.Second
 	allowedScripts := []string{"test.py", "test.sh"}
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
 
-	executor := NewExecutor(scriptsDir, maxTime, allowedScripts, logger)
+	executor := NewExecutor(config.ScriptsConfig{Dir: scriptsDir, AllowedScripts: allowedScripts, MaxExecutionTime: maxTime}, logger)
 
 	if executor.scriptsDir != scriptsDir {
 		t.Errorf("NewExecutor() scriptsDir = %s, want %s", executor.scriptsDir, scriptsDir)
@@ -32,7 +37,7 @@ func TestNewExecutor(t *testing.T) {
 }
 
 func TestValidateRequest(t *testing.T) {
-	executor := NewExecutor("test", 30*time.Second, []string{"script1.py", "script1.sh"}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: "test", AllowedScripts: []string{"script1.py", "script1.sh"}, MaxExecutionTime: 30 * time.Second}, nil)
 
 	tests := []struct {
 		name        string
@@ -144,7 +149,7 @@ func TestValidateRequest(t *testing.T) {
 }
 
 func TestDetectScriptType(t *testing.T) {
-	executor := NewExecutor("test", 30*time.Second, []string{}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: "test", MaxExecutionTime: 30 * time.Second}, nil)
 
 	tests := []struct {
 		name       string
@@ -169,7 +174,7 @@ func TestDetectScriptType(t *testing.T) {
 }
 
 func TestGetInterpreterCommand(t *testing.T) {
-	executor := NewExecutor("test", 30*time.Second, []string{}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: "test", MaxExecutionTime: 30 * time.Second}, nil)
 
 	tests := []struct {
 		name       string
@@ -193,7 +198,7 @@ func TestGetInterpreterCommand(t *testing.T) {
 
 func TestGetScriptPath(t *testing.T) {
 	scriptsDir := "/test/scripts"
-	executor := NewExecutor(scriptsDir, 30*time.Second, []string{}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: scriptsDir, MaxExecutionTime: 30 * time.Second}, nil)
 
 	tests := []struct {
 		name       string
@@ -217,7 +222,7 @@ func TestGetScriptPath(t *testing.T) {
 }
 
 func TestPrepareScriptArgs(t *testing.T) {
-	executor := NewExecutor("test", 30*time.Second, []string{}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: "test", MaxExecutionTime: 30 * time.Second}, nil)
 	scriptPath := "/test/path/script.py"
 	userID := "user123"
 
@@ -248,7 +253,7 @@ func TestPrepareScriptArgs(t *testing.T) {
 }
 
 func TestContainsDangerousPatterns(t *testing.T) {
-	executor := NewExecutor("test", 30*time.Second, []string{}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: "test", MaxExecutionTime: 30 * time.Second}, nil)
 
 	tests := []struct {
 		name      string
@@ -279,7 +284,7 @@ func TestContainsDangerousPatterns(t *testing.T) {
 }
 
 func TestBuildSecureEnvironment(t *testing.T) {
-	executor := NewExecutor("test", 30*time.Second, []string{}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: "test", MaxExecutionTime: 30 * time.Second}, nil)
 
 	env := executor.buildSecureEnvironment()
 
@@ -311,8 +316,8 @@ func TestExecuteWithInvalidScript(t *testing.T) {
 	tempDir := t.TempDir()
 
 	// Create logger for the test
-	logger := log.New(os.Stdout, "TEST: ", log.LstdFlags)
-	executor := NewExecutor(tempDir, 5*time.Second, []string{"nonexistent.py"}, logger)
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	executor := NewExecutor(config.ScriptsConfig{Dir: tempDir, AllowedScripts: []string{"nonexistent.py"}, MaxExecutionTime: 5 * time.Second}, logger)
 
 	req := ExecutionRequest{
 		UserID: "test123",
@@ -337,8 +342,38 @@ func TestExecuteWithInvalidScript(t *testing.T) {
 	}
 }
 
+func TestExecuteRecordsMetrics(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "metrics.sh"), []byte("exit 4"), 0o755)
+
+	logger := logging.New(os.Stdout, slog.LevelDebug)
+	executor := NewExecutor(config.ScriptsConfig{Dir: tempDir, AllowedScripts: []string{"metrics.sh"}, MaxExecutionTime: 5 * time.Second}, logger)
+
+	failuresBefore := executionsTotal.Value("metrics.sh", resultFailure)
+	exitCodesBefore := exitCodesTotal.Value("metrics.sh", "4")
+	durationsBefore := executionDuration.Count("metrics.sh")
+
+	if _, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: "metrics.sh"}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if got := executionsTotal.Value("metrics.sh", resultFailure) - failuresBefore; got != 1 {
+		t.Errorf("failure executions counted = %v, want 1", got)
+	}
+	if got := exitCodesTotal.Value("metrics.sh", "4") - exitCodesBefore; got != 1 {
+		t.Errorf("exit code 4 counted = %v, want 1", got)
+	}
+	if got := executionDuration.Count("metrics.sh") - durationsBefore; got != 1 {
+		t.Errorf("durations observed = %v, want 1", got)
+	}
+	if got := executionsInFlight.Value("metrics.sh"); got != 0 {
+		t.Errorf("in-flight executions = %v, want 0 after completion", got)
+	}
+}
+
 func BenchmarkValidateRequest(b *testing.B) {
-	executor := NewExecutor("test", 30*time.Second, []string{"script1.py"}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: "test", AllowedScripts: []string{"script1.py"}, MaxExecutionTime: 30 * time.Second}, nil)
 	req := ExecutionRequest{
 		UserID: "test123",
 		Script: "script1.py",
@@ -350,9 +385,270 @@ func BenchmarkValidateRequest(b *testing.B) {
 }
 
 func BenchmarkDetectScriptType(b *testing.B) {
-	executor := NewExecutor("test", 30*time.Second, []string{}, nil)
+	executor := NewExecutor(config.ScriptsConfig{Dir: "test", MaxExecutionTime: 30 * time.Second}, nil)
 
 	for i := 0; i < b.N; i++ {
 		executor.detectScriptType("test.py")
 	}
 }
+
+func TestExecuteDisconnectPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		body          string
+		wantCancelled bool
+	}{
+		{"default runs to completion", "", "sleep 0.3; echo done", false},
+		{"complete ignores disconnect", config.DisconnectComplete, "sleep 0.3; echo done", false},
+		{"cancel stops the script", config.DisconnectCancel, "exec sleep 10", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+			os.WriteFile(filepath.Join(tempDir, "bash", "policy.sh"), []byte(tt.body), 0o755)
+
+			cfg := config.ScriptsConfig{
+				Dir:              tempDir,
+				AllowedScripts:   []string{"policy.sh"},
+				MaxExecutionTime: 30 * time.Second,
+			}
+			if tt.policy != "" {
+				cfg.Settings = map[string]config.ScriptSettings{"policy.sh": {OnDisconnect: tt.policy}}
+			}
+			executor := NewExecutor(cfg, logging.New(os.Stdout, slog.LevelDebug))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+
+			started := time.Now()
+			result, err := executor.Execute(ctx, ExecutionRequest{UserID: "test123", Script: "policy.sh"})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if result.Cancelled != tt.wantCancelled {
+				t.Errorf("result.Cancelled = %v, want %v (error: %s)", result.Cancelled, tt.wantCancelled, result.Error)
+			}
+			if tt.wantCancelled {
+				if result.Success || !strings.Contains(result.Error, "client disconnected") {
+					t.Errorf("cancelled result = %+v", result)
+				}
+				if time.Since(started) > 5*time.Second {
+					t.Errorf("cancelled execution took %v", time.Since(started))
+				}
+			} else if !result.Success || result.Output != "done\n" {
+				t.Errorf("completed result = %+v, want success", result)
+			}
+		})
+	}
+}
+
+func TestExecuteStructuredProtocol(t *testing.T) {
+	tests := []struct {
+		name        string
+		protocol    string
+		body        string
+		wantSuccess bool
+		wantOutput  string
+		wantResult  string
+		wantEvents  int
+	}{
+		{"prefix", config.ProtocolPrefix,
+			`echo checking; echo "${EVENTS_PREFIX}"'{"type":"progress","pct":40}'; echo "${EVENTS_PREFIX}"'{"type":"result","granted":["read_access"]}'`,
+			true, "checking\n", `{"granted":["read_access"]}`, 1},
+		{"file descriptor", config.ProtocolFD,
+			`echo checking; echo '{"type":"progress","pct":40}' >&$EVENTS_FD; echo '{"type":"result","granted":["read_access"]}' >&$EVENTS_FD`,
+			true, "checking\n", `{"granted":["read_access"]}`, 1},
+		{"failure reported in result", config.ProtocolFD,
+			`echo '{"type":"result","success":false,"reason":"quota"}' >&$EVENTS_FD`,
+			false, "", `{"reason":"quota","success":false}`, 0},
+		{"protocol not declared", "",
+			`echo '::event::{"type":"result","granted":[]}'`,
+			true, "::event::{\"type\":\"result\",\"granted\":[]}\n", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+			os.WriteFile(filepath.Join(tempDir, "bash", "events.sh"), []byte(tt.body), 0o755)
+
+			cfg := config.ScriptsConfig{
+				Dir:              tempDir,
+				AllowedScripts:   []string{"events.sh"},
+				MaxExecutionTime: 30 * time.Second,
+				Settings:         map[string]config.ScriptSettings{"events.sh": {Protocol: tt.protocol}},
+			}
+			executor := NewExecutor(cfg, logging.New(os.Stdout, slog.LevelDebug))
+
+			var live []Event
+			result, err := executor.Execute(context.Background(), ExecutionRequest{
+				UserID:  "test123",
+				Script:  "events.sh",
+				OnEvent: func(event Event) { live = append(live, event) },
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if result.Success != tt.wantSuccess || result.Output != tt.wantOutput || string(result.Result) != tt.wantResult {
+				t.Errorf("result = %+v (result %s), want success %v, output %q, result %s",
+					result, result.Result, tt.wantSuccess, tt.wantOutput, tt.wantResult)
+			}
+			if len(result.Events) != tt.wantEvents || len(live) != tt.wantEvents {
+				t.Errorf("events = %+v, live = %+v, want %d", result.Events, live, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestExecuteDryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+	body := []byte(`echo "dry_run=${DRY_RUN:-0} $1"`)
+	os.WriteFile(filepath.Join(tempDir, "bash", "simulated.sh"), body, 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "plain.sh"), body, 0o755)
+
+	executor := NewExecutor(config.ScriptsConfig{
+		Dir:              tempDir,
+		AllowedScripts:   []string{"simulated.sh", "plain.sh"},
+		MaxExecutionTime: 30 * time.Second,
+		Settings:         map[string]config.ScriptSettings{"simulated.sh": {DryRun: true}},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+
+	if !executor.SupportsDryRun("simulated.sh") || executor.SupportsDryRun("plain.sh") {
+		t.Error("SupportsDryRun() should only report scripts declaring dry_run")
+	}
+
+	tests := []struct {
+		name       string
+		script     string
+		dryRun     bool
+		wantErr    error
+		wantOutput string
+	}{
+		{"simulation", "simulated.sh", true, nil, "dry_run=1 test123\n"},
+		{"real run of a simulating script", "simulated.sh", false, nil, "dry_run=0 test123\n"},
+		{"simulation not supported", "plain.sh", true, ErrDryRunUnsupported, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: tt.script, DryRun: tt.dryRun})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if result.Output != tt.wantOutput || result.DryRun != tt.dryRun {
+				t.Errorf("result = %+v, want output %q and dry run %v", result, tt.wantOutput, tt.dryRun)
+			}
+		})
+	}
+}
+
+// fakeSecretStore fournit des secrets en mémoire
+type fakeSecretStore map[string]string
+
+func (s fakeSecretStore) Get(name string) (string, error) {
+	value, ok := s[name]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return value, nil
+}
+
+func TestExecuteScriptEnvironment(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "env.sh"), []byte(
+		`echo "url=$IDM_URL token=$IDM_TOKEN password=$IDM_PASSWORD"; echo "::event::{\"type\":\"progress\",\"message\":\"using $IDM_TOKEN\"}"; echo "::event::{\"type\":\"result\",\"token\":\"$IDM_PASSWORD\"}"`), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "other.sh"), []byte(`echo "token=${IDM_TOKEN:-none}"`), 0o755)
+	secretFile := filepath.Join(tempDir, "idm_token")
+	os.WriteFile(secretFile, []byte("file-s3cr3t\n"), 0o600)
+
+	settings := config.ScriptSettings{
+		Protocol: config.ProtocolPrefix,
+		Env:      map[string]string{"IDM_URL": "https://idm.example"},
+		Secrets: map[string]string{
+			"IDM_TOKEN":    config.SecretFilePrefix + secretFile,
+			"IDM_PASSWORD": config.SecretStorePrefix + "idm_password",
+		},
+	}
+	executor := NewExecutor(config.ScriptsConfig{
+		Dir:              tempDir,
+		AllowedScripts:   []string{"env.sh", "other.sh"},
+		MaxExecutionTime: 30 * time.Second,
+		Settings:         map[string]config.ScriptSettings{"env.sh": settings},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+
+	if err := executor.CheckSecrets("env.sh"); !errors.Is(err, ErrSecretUnavailable) {
+		t.Errorf("CheckSecrets() without store error = %v, want ErrSecretUnavailable", err)
+	}
+	executor.UseSecretStore(fakeSecretStore{"idm_password": "store-s3cr3t"})
+	if err := executor.CheckSecrets("env.sh"); err != nil {
+		t.Fatalf("CheckSecrets() error = %v", err)
+	}
+
+	result, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: "env.sh"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Output != "url=https://idm.example token=[REDACTED] password=[REDACTED]\n" {
+		t.Errorf("output = %q, want secrets injected and redacted", result.Output)
+	}
+	if len(result.Events) != 1 || result.Events[0].Message != "using [REDACTED]" || string(result.Result) != `{"token":"[REDACTED]"}` {
+		t.Errorf("events = %+v, result = %s; want secrets redacted", result.Events, result.Result)
+	}
+	if result.Redactions != 4 {
+		t.Errorf("redactions = %d, want 4", result.Redactions)
+	}
+
+	result, _ = executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: "other.sh"})
+	if result.Output != "token=none\n" {
+		t.Errorf("other script output = %q, want no secret injected", result.Output)
+	}
+
+	os.Remove(secretFile)
+	result, err = executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: "env.sh"})
+	if !errors.Is(err, ErrSecretUnavailable) || result.Success {
+		t.Errorf("Execute() with a missing secret = %+v, %v; want ErrSecretUnavailable", result, err)
+	}
+}
+
+func TestExecuteRedactionRules(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "leak.sh"), []byte(
+		`echo "connecting with password=hunter22"; echo "api key ak-0042-known" >&2; echo done`), 0o755)
+	knownFile := filepath.Join(tempDir, "known")
+	os.WriteFile(knownFile, []byte("ak-0042-known\n"), 0o600)
+
+	executor := NewExecutor(config.ScriptsConfig{
+		Dir:              tempDir,
+		AllowedScripts:   []string{"leak.sh"},
+		MaxExecutionTime: 30 * time.Second,
+		Redact: config.RedactConfig{
+			Patterns: []string{`password=(\S+)`},
+			Values:   []string{config.SecretFilePrefix + knownFile},
+		},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+
+	result, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: "leak.sh"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := "connecting with password=[REDACTED]\napi key [REDACTED]\ndone\n"
+	if result.Output != want || result.Redactions != 2 {
+		t.Errorf("result = %q (%d redactions), want %q (2)", result.Output, result.Redactions, want)
+	}
+
+	os.Remove(knownFile)
+	if err := executor.CheckSecrets("leak.sh"); !errors.Is(err, ErrSecretUnavailable) {
+		t.Errorf("CheckSecrets() with a missing known value error = %v, want ErrSecretUnavailable", err)
+	}
+}
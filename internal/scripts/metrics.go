@@ -0,0 +1,40 @@
+package scripts
+
+import "go-form-app/internal/metrics"
+
+// Résultats d'exécution utilisés comme label des métriques
+const (
+	resultSuccess   = "success"
+	resultFailure   = "failure"
+	resultTimeout   = "timeout"
+	resultCancelled = "cancelled"
+	resultRejected  = "rejected"
+)
+
+var (
+	executionsTotal = metrics.Default.NewCounterVec(
+		"goformapp_script_executions_total",
+		"Nombre d'exécutions de scripts par script et résultat.",
+		"script", "result")
+
+	executionDuration = metrics.Default.NewHistogramVec(
+		"goformapp_script_execution_duration_seconds",
+		"Durée d'exécution des scripts en secondes.",
+		metrics.DefaultDurationBuckets,
+		"script")
+
+	exitCodesTotal = metrics.Default.NewCounterVec(
+		"goformapp_script_exit_codes_total",
+		"Codes de sortie des scripts.",
+		"script", "exit_code")
+
+	executionsInFlight = metrics.Default.NewGaugeVec(
+		"goformapp_script_executions_in_flight",
+		"Nombre d'exécutions de scripts en cours.",
+		"script")
+
+	executionsQueued = metrics.Default.NewGaugeVec(
+		"goformapp_script_executions_queued",
+		"Nombre d'exécutions de scripts en attente d'un worker.",
+		"script")
+)
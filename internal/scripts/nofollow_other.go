@@ -0,0 +1,6 @@
+//go:build !unix
+
+package scripts
+
+// openNoFollow n'a pas d'équivalent hors Unix ; seul le contrôle du fichier ouvert s'applique
+const openNoFollow = 0
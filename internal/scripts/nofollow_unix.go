@@ -0,0 +1,8 @@
+//go:build unix
+
+package scripts
+
+import "syscall"
+
+// openNoFollow fait échouer l'ouverture d'un artefact remplacé par un lien symbolique
+const openNoFollow = syscall.O_NOFOLLOW
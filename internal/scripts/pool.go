@@ -0,0 +1,154 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull est retournée lorsque tous les workers sont occupés et que la file d'attente est pleine
+var ErrQueueFull = errors.New("execution queue is full")
+
+// waiter est une exécution en attente d'un worker
+type waiter struct {
+	id     string
+	script string
+	ready  chan struct{}
+}
+
+// pool borne le nombre d'exécutions simultanées, globalement et par script,
+// et fait patienter les demandes excédentaires dans une file FIFO bornée
+type pool struct {
+	workers   int
+	queueSize int
+	limit     func(script string) int
+
+	mu        sync.Mutex
+	running   int
+	perScript map[string]int
+	queue     []*waiter
+}
+
+// newPool crée un pool ; limit retourne la concurrence maximale d'un script (0 : pas de limite propre)
+func newPool(workers, queueSize int, limit func(script string) int) *pool {
+	return &pool{
+		workers:   workers,
+		queueSize: queueSize,
+		limit:     limit,
+		perScript: make(map[string]int),
+	}
+}
+
+// acquire réserve un worker pour le script, en patientant dans la file si nécessaire.
+// onQueued reçoit la position dans la file (à partir de 1) lorsque la demande doit attendre.
+// La fonction retournée libère le worker.
+func (p *pool) acquire(ctx context.Context, id, script string, onQueued func(position int)) (func(), error) {
+	release := func() { p.release(script) }
+
+	p.mu.Lock()
+	if p.available(script) {
+		p.take(script)
+		p.mu.Unlock()
+		return release, nil
+	}
+	if len(p.queue) >= p.queueSize {
+		p.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	w := &waiter{id: id, script: script, ready: make(chan struct{})}
+	p.queue = append(p.queue, w)
+	position := len(p.queue)
+	executionsQueued.Inc(script)
+	p.mu.Unlock()
+
+	if onQueued != nil {
+		onQueued(position)
+	}
+
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	select {
+	case <-w.ready:
+		// Le worker a été attribué en même temps que l'annulation : il est rendu aussitôt
+		p.mu.Unlock()
+		release()
+	default:
+		p.remove(w)
+		p.mu.Unlock()
+	}
+	return nil, context.Cause(ctx)
+}
+
+// position retourne la position d'une exécution dans la file, 0 si elle n'y est pas
+func (p *pool) position(id string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, w := range p.queue {
+		if w.id == id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// release libère le worker d'un script et le confie aux demandes en attente
+func (p *pool) release(script string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.running--
+	p.perScript[script]--
+	p.dispatch()
+}
+
+// dispatch attribue les workers libres aux premières demandes de la file dont le
+// script n'a pas atteint sa limite ; une demande bloquée ne retient pas les suivantes
+func (p *pool) dispatch() {
+	remaining := p.queue[:0]
+	for _, w := range p.queue {
+		if p.available(w.script) {
+			p.take(w.script)
+			executionsQueued.Dec(w.script)
+			close(w.ready)
+			continue
+		}
+		remaining = append(remaining, w)
+	}
+	for i := len(remaining); i < len(p.queue); i++ {
+		p.queue[i] = nil
+	}
+	p.queue = remaining
+}
+
+// remove retire une demande annulée de la file
+func (p *pool) remove(target *waiter) {
+	for i, w := range p.queue {
+		if w == target {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			executionsQueued.Dec(w.script)
+			return
+		}
+	}
+}
+
+// available indique si un worker peut être attribué au script
+func (p *pool) available(script string) bool {
+	if p.running >= p.workers {
+		return false
+	}
+	limit := p.limit(script)
+	return limit <= 0 || p.perScript[script] < limit
+}
+
+// take attribue un worker au script
+func (p *pool) take(script string) {
+	p.running++
+	p.perScript[script]++
+}
@@ -0,0 +1,180 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+// acquireAsync réserve un worker en arrière-plan et retourne la fonction de libération obtenue
+func acquireAsync(ctx context.Context, p *pool, id, script string) <-chan func() {
+	acquired := make(chan func(), 1)
+	go func() {
+		release, err := p.acquire(ctx, id, script, nil)
+		if err != nil {
+			close(acquired)
+			return
+		}
+		acquired <- release
+	}()
+	return acquired
+}
+
+// waitForPosition attend qu'une demande atteigne la position attendue dans la file
+func waitForPosition(t *testing.T, p *pool, id string, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.position(id) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("position(%s) = %d, want %d", id, p.position(id), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPoolQueuesBeyondWorkers(t *testing.T) {
+	p := newPool(2, 2, func(string) int { return 0 })
+
+	releaseA, _ := p.acquire(context.Background(), "a", "script1.sh", nil)
+	releaseB, _ := p.acquire(context.Background(), "b", "script1.sh", nil)
+
+	var positions []int
+	c := make(chan func(), 1)
+	go func() {
+		release, _ := p.acquire(context.Background(), "c", "script1.sh", func(position int) { positions = append(positions, position) })
+		c <- release
+	}()
+	waitForPosition(t, p, "c", 1)
+	d := acquireAsync(context.Background(), p, "d", "script1.py")
+	waitForPosition(t, p, "d", 2)
+
+	if _, err := p.acquire(context.Background(), "e", "script1.sh", nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("acquire() with a full queue error = %v, want ErrQueueFull", err)
+	}
+
+	releaseA()
+	releaseC := <-c
+	if len(positions) != 1 || positions[0] != 1 {
+		t.Errorf("onQueued positions = %v, want [1]", positions)
+	}
+	waitForPosition(t, p, "d", 1)
+
+	releaseB()
+	releaseD := <-d
+	releaseC()
+	releaseD()
+
+	if p.running != 0 || len(p.queue) != 0 {
+		t.Errorf("pool after releases: running = %d, queued = %d", p.running, len(p.queue))
+	}
+}
+
+func TestPoolPerScriptLimit(t *testing.T) {
+	p := newPool(4, 4, func(script string) int {
+		if script == "script2.py" {
+			return 1
+		}
+		return 0
+	})
+
+	releaseSerial, _ := p.acquire(context.Background(), "a", "script2.py", nil)
+	serial := acquireAsync(context.Background(), p, "b", "script2.py")
+	waitForPosition(t, p, "b", 1)
+
+	// Une demande bloquée par la limite de son script ne retient pas les autres scripts
+	other := acquireAsync(context.Background(), p, "c", "script1.sh")
+	select {
+	case release := <-other:
+		release()
+	case <-time.After(2 * time.Second):
+		t.Fatal("script1.sh waited behind a serial script")
+	}
+
+	releaseSerial()
+	(<-serial)()
+}
+
+func TestPoolCancelWhileQueued(t *testing.T) {
+	p := newPool(1, 2, func(string) int { return 0 })
+	release, _ := p.acquire(context.Background(), "a", "script1.sh", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.acquire(ctx, "b", "script1.sh", nil)
+		done <- err
+	}()
+	waitForPosition(t, p, "b", 1)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("acquire() after cancel error = %v, want context.Canceled", err)
+	}
+	if p.position("b") != 0 {
+		t.Error("cancelled request is still queued")
+	}
+
+	release()
+	if p.running != 0 {
+		t.Errorf("running = %d after release, want 0", p.running)
+	}
+}
+
+func TestExecuteQueueFull(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "serial.sh"), []byte("sleep 0.3; echo done"), 0o755)
+
+	executor := NewExecutor(config.ScriptsConfig{
+		Dir:              tempDir,
+		AllowedScripts:   []string{"serial.sh"},
+		MaxExecutionTime: 5 * time.Second,
+		QueueSize:        1,
+		Settings:         map[string]config.ScriptSettings{"serial.sh": {MaxConcurrency: 1}},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+
+	results := make(chan *ExecutionResult, 2)
+	started := make(chan string, 2)
+	for _, id := range []string{"user0001", "user0002"} {
+		id := id
+		go func() {
+			result, _ := executor.Execute(context.Background(), ExecutionRequest{
+				ID: id, UserID: id, Script: "serial.sh",
+				OnStarted: func() { started <- id },
+			})
+			results <- result
+		}()
+		if id == "user0001" {
+			<-started
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for executor.QueuePosition("user0002") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("second execution was not queued")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	result, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "user0003", Script: "serial.sh"})
+	if !errors.Is(err, ErrQueueFull) || result.Success {
+		t.Errorf("Execute() with a full queue = %+v, %v; want ErrQueueFull", result, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if result := <-results; !result.Success {
+			t.Errorf("queued execution result = %+v, want success", result)
+		}
+	}
+	if id := <-started; id != "user0002" {
+		t.Errorf("started = %s, want user0002", id)
+	}
+}
@@ -0,0 +1,147 @@
+package scripts
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// redactedMarker remplace une valeur masquée dans la sortie d'un script
+const redactedMarker = "[REDACTED]"
+
+// minSecretLength évite de masquer des valeurs trop courtes, qui défigureraient la sortie
+const minSecretLength = 4
+
+// redactor masque les secrets connus et les motifs configurés dans la sortie d'un
+// script, et compte les remplacements effectués
+type redactor struct {
+	// rules sont appliquées dans l'ordre : valeurs connues, puis motifs
+	rules []*regexp.Regexp
+	count atomic.Int64
+}
+
+// newRedactor prépare le masquage des valeurs et des motifs donnés ; les valeurs les
+// plus longues sont cherchées en premier pour qu'un secret contenant un autre soit masqué en entier
+func newRedactor(values []string, patterns []*regexp.Regexp) *redactor {
+	var secrets []string
+	for _, value := range values {
+		if len(value) >= minSecretLength {
+			secrets = append(secrets, value)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	r := &redactor{}
+	if len(secrets) > 0 {
+		quoted := make([]string, 0, len(secrets))
+		for _, secret := range secrets {
+			quoted = append(quoted, regexp.QuoteMeta(secret))
+		}
+		r.rules = append(r.rules, regexp.MustCompile(strings.Join(quoted, "|")))
+	}
+	r.rules = append(r.rules, patterns...)
+	return r
+}
+
+// compilePatterns compile les motifs de masquage (validés par la configuration)
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		compiled = append(compiled, regexp.MustCompile(pattern))
+	}
+	return compiled
+}
+
+// redactions retourne le nombre de valeurs masquées depuis la création du redactor
+func (r *redactor) redactions() int {
+	if r == nil {
+		return 0
+	}
+	return int(r.count.Load())
+}
+
+// text masque les secrets d'un texte ; si un motif contient un groupe, seul le premier
+// groupe est masqué. Une portion déjà masquée n'est pas comptée deux fois.
+func (r *redactor) text(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rule := range r.rules {
+		s = r.apply(rule, s)
+	}
+	return s
+}
+
+// apply remplace les correspondances d'une règle
+func (r *redactor) apply(rule *regexp.Regexp, s string) string {
+	matches := rule.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var out []byte
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if len(match) >= 4 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		if start == end || s[start:end] == redactedMarker {
+			continue
+		}
+		out = append(out, s[last:start]...)
+		out = append(out, redactedMarker...)
+		last = end
+		r.count.Add(1)
+	}
+	if out == nil {
+		return s
+	}
+	return string(append(out, s[last:]...))
+}
+
+// event masque les secrets des champs texte d'un événement
+func (r *redactor) event(event Event) Event {
+	event.Step = r.text(event.Step)
+	event.Message = r.text(event.Message)
+	return event
+}
+
+// result masque les secrets des chaînes (clés et valeurs) d'un résultat structuré
+func (r *redactor) result(raw json.RawMessage) json.RawMessage {
+	if r == nil || len(r.rules) == 0 || raw == nil {
+		return raw
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return raw
+	}
+	redacted, err := json.Marshal(r.value(value))
+	if err != nil {
+		return raw
+	}
+	return redacted
+}
+
+// value parcourt une valeur JSON décodée
+func (r *redactor) value(value any) any {
+	switch v := value.(type) {
+	case string:
+		return r.text(v)
+	case []any:
+		for i := range v {
+			v[i] = r.value(v[i])
+		}
+		return v
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, item := range v {
+			redacted[r.text(key)] = r.value(item)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
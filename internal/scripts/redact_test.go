@@ -0,0 +1,46 @@
+package scripts
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	patterns := compilePatterns([]string{`(?i)password[=:]\s*(\S+)`, `gfa_[0-9a-f]{16}_[0-9a-f]+`})
+
+	tests := []struct {
+		name      string
+		in        string
+		want      string
+		wantCount int
+	}{
+		{"secret", "token=s3cr3t", "token=[REDACTED]", 1},
+		{"longest secret first", "token=s3cr3t-long", "token=[REDACTED]", 1},
+		{"short values kept", "abc", "abc", 0},
+		{"pattern group", "Password: hunter22 ok", "Password: [REDACTED] ok", 1},
+		{"whole pattern", "Bearer gfa_0123456789abcdef_cafe", "Bearer [REDACTED]", 1},
+		{"secret matched by a pattern counted once", "password=s3cr3t", "password=[REDACTED]", 1},
+		{"several values", "s3cr3t s3cr3t", "[REDACTED] [REDACTED]", 2},
+		{"nothing to redact", "granted", "granted", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRedactor([]string{"s3cr3t", "s3cr3t-long", "abc"}, patterns)
+			if got := r.text(tt.in); got != tt.want || r.redactions() != tt.wantCount {
+				t.Errorf("text(%q) = %q (%d redactions), want %q (%d)", tt.in, got, r.redactions(), tt.want, tt.wantCount)
+			}
+		})
+	}
+
+	r := newRedactor([]string{"s3cr3t"}, nil)
+	result := r.result(json.RawMessage(`{"token":"s3cr3t","items":["a","x s3cr3t"],"count":2}`))
+	if string(result) != `{"count":2,"items":["a","x [REDACTED]"],"token":"[REDACTED]"}` || r.redactions() != 2 {
+		t.Errorf("result() = %s (%d redactions)", result, r.redactions())
+	}
+
+	var none *redactor
+	if got := none.text("s3cr3t"); got != "s3cr3t" || none.redactions() != 0 {
+		t.Errorf("nil redactor text() = %q", got)
+	}
+}
@@ -0,0 +1,90 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUserBusy est retournée lorsqu'une opération en conflit est déjà en cours pour l'utilisateur cible
+var ErrUserBusy = errors.New("another operation is in progress for this user")
+
+// userLockState décrit les verrous détenus sur un utilisateur
+type userLockState struct {
+	exclusive bool
+	shared    int
+	// released est fermé puis remplacé à chaque libération pour réveiller les demandes en attente
+	released chan struct{}
+}
+
+// userLocks sérialise les exécutions visant un même utilisateur : un verrou exclusif
+// exclut toute autre exécution, les verrous partagés se cumulent entre eux
+type userLocks struct {
+	mu    sync.Mutex
+	users map[string]*userLockState
+}
+
+func newUserLocks() *userLocks {
+	return &userLocks{users: make(map[string]*userLockState)}
+}
+
+// acquire prend le verrou de l'utilisateur ; avec un timeout nul la demande échoue aussitôt
+// si le verrou est pris, sinon elle attend au plus timeout. La fonction retournée libère le verrou.
+func (l *userLocks) acquire(ctx context.Context, userID string, exclusive bool, timeout time.Duration) (func(), error) {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		l.mu.Lock()
+		state, ok := l.users[userID]
+		if !ok {
+			state = &userLockState{released: make(chan struct{})}
+			l.users[userID] = state
+		}
+		if !state.exclusive && (!exclusive || state.shared == 0) {
+			if exclusive {
+				state.exclusive = true
+			} else {
+				state.shared++
+			}
+			l.mu.Unlock()
+			return func() { l.release(userID, exclusive) }, nil
+		}
+		released := state.released
+		l.mu.Unlock()
+
+		if timeout <= 0 {
+			return nil, ErrUserBusy
+		}
+		select {
+		case <-released:
+		case <-deadline:
+			return nil, ErrUserBusy
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+}
+
+// release libère un verrou et réveille les demandes en attente
+func (l *userLocks) release(userID string, exclusive bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.users[userID]
+	if exclusive {
+		state.exclusive = false
+	} else {
+		state.shared--
+	}
+	close(state.released)
+	state.released = make(chan struct{})
+	if !state.exclusive && state.shared == 0 {
+		delete(l.users, userID)
+	}
+}
@@ -0,0 +1,119 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+func TestUserLocksFailFast(t *testing.T) {
+	tests := []struct {
+		name      string
+		held      bool
+		requested bool
+		wantErr   error
+	}{
+		{"exclusive blocks exclusive", true, true, ErrUserBusy},
+		{"exclusive blocks shared", true, false, ErrUserBusy},
+		{"shared blocks exclusive", false, true, ErrUserBusy},
+		{"shared allows shared", false, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locks := newUserLocks()
+			release, err := locks.acquire(context.Background(), "test123", tt.held, 0)
+			if err != nil {
+				t.Fatalf("first acquire() error = %v", err)
+			}
+
+			second, err := locks.acquire(context.Background(), "test123", tt.requested, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("second acquire() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				second()
+			}
+
+			other, err := locks.acquire(context.Background(), "test456", true, 0)
+			if err != nil {
+				t.Errorf("acquire() for another user error = %v", err)
+			} else {
+				other()
+			}
+
+			release()
+			if len(locks.users) != 0 {
+				t.Errorf("locks still held after release: %v", locks.users)
+			}
+		})
+	}
+}
+
+func TestUserLocksWait(t *testing.T) {
+	locks := newUserLocks()
+	release, _ := locks.acquire(context.Background(), "test123", true, 0)
+
+	if _, err := locks.acquire(context.Background(), "test123", true, 50*time.Millisecond); !errors.Is(err, ErrUserBusy) {
+		t.Errorf("acquire() after timeout error = %v, want ErrUserBusy", err)
+	}
+
+	time.AfterFunc(50*time.Millisecond, release)
+	second, err := locks.acquire(context.Background(), "test123", true, 2*time.Second)
+	if err != nil {
+		t.Fatalf("acquire() while waiting error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := locks.acquire(ctx, "test123", false, 2*time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("acquire() with a cancelled context error = %v, want context.Canceled", err)
+	}
+	second()
+}
+
+func TestExecuteUserBusy(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "bash"), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "grant.sh"), []byte("sleep 0.3; echo granted"), 0o755)
+	os.WriteFile(filepath.Join(tempDir, "bash", "audit.sh"), []byte("echo audited"), 0o755)
+
+	executor := NewExecutor(config.ScriptsConfig{
+		Dir:              tempDir,
+		AllowedScripts:   []string{"grant.sh", "audit.sh"},
+		MaxExecutionTime: 5 * time.Second,
+		Settings:         map[string]config.ScriptSettings{"audit.sh": {UserLock: config.UserLockShared}},
+	}, logging.New(os.Stdout, slog.LevelDebug))
+
+	started := make(chan struct{})
+	done := make(chan *ExecutionResult, 1)
+	go func() {
+		result, _ := executor.Execute(context.Background(), ExecutionRequest{
+			UserID: "test123", Script: "grant.sh",
+			OnStarted: func() { close(started) },
+		})
+		done <- result
+	}()
+	<-started
+
+	for _, script := range []string{"grant.sh", "audit.sh"} {
+		result, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: script})
+		if !errors.Is(err, ErrUserBusy) || result.Success || result.Error != ErrUserBusy.Error() {
+			t.Errorf("Execute(%s) for a busy user = %+v, %v; want ErrUserBusy", script, result, err)
+		}
+	}
+	if result, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "test456", Script: "grant.sh"}); err != nil || !result.Success {
+		t.Errorf("Execute() for another user = %+v, %v; want success", result, err)
+	}
+
+	if result := <-done; !result.Success {
+		t.Errorf("first execution = %+v, want success", result)
+	}
+}
@@ -0,0 +1,193 @@
+package scripts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go-form-app/internal/logging"
+)
+
+// workDirPrefix distingue les répertoires d'exécution des autres entrées du répertoire de base
+const workDirPrefix = "run-"
+
+// artifactsSubDir est le sous-répertoire (fourni dans ARTIFACTS_DIR) où un script dépose ses artefacts
+const artifactsSubDir = "artifacts"
+
+// maxArtifacts borne le nombre d'artefacts conservés pour une exécution
+const maxArtifacts = 100
+
+// maxPurgeInterval borne l'intervalle de la purge périodique des répertoires expirés
+const maxPurgeInterval = time.Hour
+
+// ErrArtifactNotFound est retournée pour un artefact absent ou supprimé à l'expiration de la rétention
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// Artifact est un fichier déposé par un script dans son répertoire d'artefacts
+type Artifact struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// DefaultWorkDir est le répertoire de base des exécutions si la configuration n'en fixe pas
+func DefaultWorkDir() string {
+	return filepath.Join(os.TempDir(), "go-form-app")
+}
+
+// createWorkDir crée le répertoire de travail d'une exécution suivie et son répertoire
+// d'artefacts ; les répertoires conservés dont la rétention a expiré sont supprimés au passage
+func (e *Executor) createWorkDir(run *runningExecution) (string, error) {
+	e.workDirMu.Lock()
+	defer e.workDirMu.Unlock()
+
+	if err := os.MkdirAll(e.workDir, 0o700); err != nil {
+		return "", fmt.Errorf("creating working directory: %w", err)
+	}
+	e.purgeExpiredWorkDirs()
+
+	dir, err := os.MkdirTemp(e.workDir, workDirPrefix)
+	if err != nil {
+		return "", fmt.Errorf("creating working directory: %w", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, artifactsSubDir), 0o700); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("creating artifacts directory: %w", err)
+	}
+	run.workDir = dir
+	return dir, nil
+}
+
+// collectArtifacts liste les artefacts d'une exécution terminée ; sans rétention, le
+// répertoire n'est pas conservé (keep vaut false) et les artefacts sont seulement comptés
+func (e *Executor) collectArtifacts(dir string) (artifacts []Artifact, artifactsDir string, keep bool, err error) {
+	artifactsDir = filepath.Join(dir, artifactsSubDir)
+	entries, err := os.ReadDir(artifactsDir)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	for _, entry := range entries {
+		// Seuls les fichiers ordinaires sont exposés : ni sous-répertoire ni lien symbolique
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		artifacts = append(artifacts, Artifact{Name: entry.Name(), Size: info.Size()})
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Name < artifacts[j].Name })
+	if len(artifacts) > maxArtifacts {
+		artifacts = artifacts[:maxArtifacts]
+	}
+
+	if e.artifactRetention <= 0 {
+		return artifacts, "", false, nil
+	}
+	// La rétention court à partir de la fin de l'exécution
+	now := time.Now()
+	os.Chtimes(dir, now, now)
+	return artifacts, artifactsDir, true, nil
+}
+
+// purgeExpiredWorkDirs supprime les répertoires d'exécution dont la rétention a expiré ;
+// ceux des exécutions suivies ne sont jamais touchés. La durée maximale d'exécution
+// s'ajoute pour épargner les exécutions d'un autre processus partageant le répertoire.
+// L'appelant détient workDirMu.
+func (e *Executor) purgeExpiredWorkDirs() {
+	entries, err := os.ReadDir(e.workDir)
+	if err != nil {
+		return
+	}
+
+	live := make(map[string]bool)
+	e.mu.Lock()
+	for run := range e.running {
+		if run.workDir != "" {
+			live[filepath.Base(run.workDir)] = true
+		}
+	}
+	e.mu.Unlock()
+
+	cutoff := time.Now().Add(-e.artifactRetention - e.maxExecutionTime)
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), workDirPrefix) || live[entry.Name()] {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+			os.RemoveAll(filepath.Join(e.workDir, entry.Name()))
+		}
+	}
+}
+
+// StartPurge lance la purge périodique des répertoires expirés, pour qu'un serveur
+// inactif ne conserve pas les artefacts au-delà de la rétention ; Drain l'arrête
+func (e *Executor) StartPurge() {
+	if e.artifactRetention <= 0 {
+		return
+	}
+	e.purgeStart.Do(func() {
+		e.logger.Info("work directory purge started", logging.KeyCategory, logging.CategoryExecution,
+			"interval", e.purgeInterval)
+		go e.purgeLoop()
+	})
+}
+
+// stopPurge arrête la purge périodique et attend sa fin
+func (e *Executor) stopPurge() {
+	// Une purge jamais démarrée n'a pas de boucle à attendre
+	e.purgeStart.Do(func() { close(e.purgeDone) })
+	e.purgeStopOnce.Do(func() { close(e.purgeStop) })
+	<-e.purgeDone
+}
+
+// purgeLoop supprime les répertoires expirés à chaque intervalle jusqu'à l'arrêt
+func (e *Executor) purgeLoop() {
+	defer close(e.purgeDone)
+
+	ticker := time.NewTicker(e.purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.purgeStop:
+			return
+		case <-ticker.C:
+			e.workDirMu.Lock()
+			e.purgeExpiredWorkDirs()
+			e.workDirMu.Unlock()
+		}
+	}
+}
+
+// purgeIntervalFor retourne l'intervalle de purge d'une rétention : la rétention elle-même,
+// bornée par maxPurgeInterval
+func purgeIntervalFor(retention time.Duration) time.Duration {
+	if retention <= 0 || retention > maxPurgeInterval {
+		return maxPurgeInterval
+	}
+	return retention
+}
+
+// OpenArtifact ouvre un artefact conservé ; name doit être un nom de fichier simple.
+// Le fichier est ouvert sans suivre de lien symbolique et vérifié une fois ouvert, pour
+// qu'un artefact remplacé entre la vérification et l'ouverture ne soit jamais servi.
+func OpenArtifact(artifactsDir, name string) (*os.File, os.FileInfo, error) {
+	if artifactsDir == "" || name == "" || name != filepath.Base(name) {
+		return nil, nil, ErrArtifactNotFound
+	}
+	file, err := os.OpenFile(filepath.Join(artifactsDir, name), os.O_RDONLY|openNoFollow, 0)
+	if err != nil {
+		return nil, nil, ErrArtifactNotFound
+	}
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, ErrArtifactNotFound
+	}
+	return file, info, nil
+}
@@ -0,0 +1,204 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
+)
+
+// newWorkDirExecutor crée un executor dont le script report.sh dépose un artefact
+func newWorkDirExecutor(t *testing.T, retention time.Duration) (*Executor, string) {
+	t.Helper()
+
+	scriptsDir := t.TempDir()
+	os.MkdirAll(filepath.Join(scriptsDir, "bash"), 0o755)
+	os.WriteFile(filepath.Join(scriptsDir, "bash", "report.sh"), []byte(
+		`echo "pwd=$(pwd) home=$HOME"; echo "user,status" > "$ARTIFACTS_DIR/report.csv"; echo scratch > notes.txt; mkdir "$ARTIFACTS_DIR/nested"`), 0o755)
+
+	workDir := t.TempDir()
+	executor := NewExecutor(config.ScriptsConfig{
+		Dir:               scriptsDir,
+		AllowedScripts:    []string{"report.sh"},
+		MaxExecutionTime:  5 * time.Second,
+		WorkDir:           workDir,
+		ArtifactRetention: retention,
+	}, logging.New(os.Stdout, slog.LevelDebug))
+	return executor, workDir
+}
+
+func TestExecuteWorkDir(t *testing.T) {
+	tests := []struct {
+		name          string
+		retention     time.Duration
+		wantArtifacts int
+		wantKept      bool
+	}{
+		{"removed without retention", 0, 0, false},
+		{"kept with retention", time.Hour, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, workDir := newWorkDirExecutor(t, tt.retention)
+
+			result, err := executor.Execute(context.Background(), ExecutionRequest{UserID: "test123", Script: "report.sh"})
+			if err != nil || !result.Success {
+				t.Fatalf("Execute() = %+v, %v", result, err)
+			}
+
+			entries, _ := os.ReadDir(workDir)
+			if len(entries) != len(result.Artifacts) {
+				t.Errorf("work dir entries = %d, want the run directory kept: %v", len(entries), tt.wantKept)
+			}
+			if len(entries) == 1 {
+				runDir := filepath.Join(workDir, entries[0].Name())
+				if result.Output != "pwd="+runDir+" home="+runDir+"\n" || result.ArtifactsDir != filepath.Join(runDir, artifactsSubDir) {
+					t.Errorf("result = %+v, want the script run in %s", result, runDir)
+				}
+			}
+			if len(result.Artifacts) != tt.wantArtifacts {
+				t.Fatalf("artifacts = %+v, want %d", result.Artifacts, tt.wantArtifacts)
+			}
+			if tt.wantArtifacts > 0 && (result.Artifacts[0].Name != "report.csv" || result.Artifacts[0].Size != int64(len("user,status\n"))) {
+				t.Errorf("artifact = %+v, want report.csv only", result.Artifacts[0])
+			}
+		})
+	}
+}
+
+func TestExecuteWorkDirCreatedWhenStarted(t *testing.T) {
+	executor, workDir := newWorkDirExecutor(t, 0)
+	executor.pool = newPool(1, 1, func(string) int { return 0 })
+
+	release, err := executor.pool.acquire(context.Background(), "busy", "report.sh", nil)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	queued := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := executor.Execute(context.Background(), ExecutionRequest{
+			UserID: "test123", Script: "report.sh", OnQueued: func(int) { close(queued) },
+		})
+		done <- err
+	}()
+
+	<-queued
+	if entries, _ := os.ReadDir(workDir); len(entries) != 0 {
+		t.Errorf("work dir entries while queued = %d, want none", len(entries))
+	}
+	release()
+	if err := <-done; err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+}
+
+func TestPurgeExpiredWorkDirs(t *testing.T) {
+	executor, workDir := newWorkDirExecutor(t, time.Hour)
+
+	expired := filepath.Join(workDir, workDirPrefix+"expired")
+	recent := filepath.Join(workDir, workDirPrefix+"recent")
+	running := filepath.Join(workDir, workDirPrefix+"running")
+	other := filepath.Join(workDir, "other")
+	old := time.Now().Add(-2 * time.Hour)
+	for _, dir := range []string{expired, recent, running, other} {
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if dir != recent {
+			os.Chtimes(dir, old, old)
+		}
+	}
+
+	// Une exécution suivie garde son répertoire, quelle que soit sa date de modification
+	run := &runningExecution{workDir: running}
+	if !executor.track(run) {
+		t.Fatal("track() refused the execution")
+	}
+	defer executor.untrack(run)
+
+	executor.purgeExpiredWorkDirs()
+
+	for dir, want := range map[string]bool{expired: false, recent: true, running: true, other: true} {
+		if _, err := os.Stat(dir); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(dir), err == nil, want)
+		}
+	}
+}
+
+func TestStartPurge(t *testing.T) {
+	executor, workDir := newWorkDirExecutor(t, time.Hour)
+	executor.purgeInterval = 10 * time.Millisecond
+
+	expired := filepath.Join(workDir, workDirPrefix+"expired")
+	if err := os.Mkdir(expired, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(expired, old, old)
+
+	// Sans aucune exécution, la purge périodique supprime le répertoire expiré
+	executor.StartPurge()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(expired); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired work dir not purged by the periodic purge")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if interrupted := executor.Drain(context.Background()); len(interrupted) != 0 {
+		t.Errorf("Drain() interrupted = %v, want none", interrupted)
+	}
+}
+
+func TestPurgeIntervalFor(t *testing.T) {
+	tests := []struct {
+		retention time.Duration
+		want      time.Duration
+	}{
+		{0, maxPurgeInterval},
+		{10 * time.Minute, 10 * time.Minute},
+		{72 * time.Hour, maxPurgeInterval},
+	}
+
+	for _, tt := range tests {
+		if got := purgeIntervalFor(tt.retention); got != tt.want {
+			t.Errorf("purgeIntervalFor(%v) = %v, want %v", tt.retention, got, tt.want)
+		}
+	}
+}
+
+func TestOpenArtifact(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "report.csv"), []byte("user,status\n"), 0o600)
+	os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.txt"), []byte("secret"), 0o600)
+	os.Symlink("/etc/passwd", filepath.Join(dir, "link"))
+	os.Mkdir(filepath.Join(dir, "nested"), 0o700)
+
+	file, info, err := OpenArtifact(dir, "report.csv")
+	if err != nil || info.Size() != 12 {
+		t.Fatalf("OpenArtifact(report.csv) = %v, %v", info, err)
+	}
+	file.Close()
+
+	for _, name := range []string{"", "..", "../secret.txt", "link", "nested", "missing.csv"} {
+		if _, _, err := OpenArtifact(dir, name); !errors.Is(err, ErrArtifactNotFound) {
+			t.Errorf("OpenArtifact(%q) error = %v, want ErrArtifactNotFound", name, err)
+		}
+	}
+	if _, _, err := OpenArtifact("", "report.csv"); !errors.Is(err, ErrArtifactNotFound) {
+		t.Errorf("OpenArtifact() without directory error = %v, want ErrArtifactNotFound", err)
+	}
+}
@@ -0,0 +1,237 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// keySize est la taille de la clé AES-256 du coffre
+const keySize = 32
+
+var (
+	// ErrNotFound est retournée pour un secret absent du coffre
+	ErrNotFound = errors.New("secret not found")
+	// ErrInvalidKey est retournée pour une clé de chiffrement mal formée
+	ErrInvalidKey = errors.New("secrets key must be 32 bytes encoded in base64")
+)
+
+// namePattern borne les noms de secrets du coffre
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Store est un coffre local de secrets : un fichier chiffré en AES-256-GCM, relu
+// lorsqu'il est modifié par la CLI d'administration
+type Store struct {
+	path string
+	aead cipher.AEAD
+
+	mu      sync.RWMutex
+	values  map[string]string
+	modTime int64
+}
+
+// NewStore ouvre (ou prépare) le coffre avec la clé lue dans keyFile
+func NewStore(path, keyFile string) (*Store, error) {
+	key, err := ReadKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{path: path, aead: aead, values: make(map[string]string)}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating secrets directory: %w", err)
+	}
+	if err := s.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GenerateKey retourne une nouvelle clé aléatoire encodée en base64
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ReadKey lit une clé encodée en base64 dans un fichier
+func ReadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// Get retourne la valeur d'un secret
+func (s *Store) Get(name string) (string, error) {
+	if err := s.reloadIfChanged(); err != nil {
+		return "", err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return value, nil
+}
+
+// Names retourne les noms des secrets par ordre alphabétique, sans leurs valeurs
+func (s *Store) Names() ([]string, error) {
+	if err := s.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Set crée ou remplace un secret
+func (s *Store) Set(name, value string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q", name)
+	}
+	if value == "" {
+		return errors.New("secret value must not be empty")
+	}
+	if err := s.reloadIfChanged(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[name] = value
+	return s.persistLocked()
+}
+
+// Delete supprime un secret
+func (s *Store) Delete(name string) error {
+	if err := s.reloadIfChanged(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(s.values, name)
+	return s.persistLocked()
+}
+
+// reloadIfChanged relit le fichier si sa date de modification a changé
+func (s *Store) reloadIfChanged() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading secrets file: %w", err)
+	}
+
+	s.mu.RLock()
+	unchanged := info.ModTime().UnixNano() == s.modTime
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("reading secrets file: %w", err)
+	}
+	values, err := s.decrypt(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.values = values
+	s.modTime = info.ModTime().UnixNano()
+	s.mu.Unlock()
+	return nil
+}
+
+// decrypt déchiffre le contenu du fichier : nonce suivi du JSON chiffré
+func (s *Store) decrypt(data []byte) (map[string]string, error) {
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, errors.New("secrets file is corrupted")
+	}
+	plain, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, errors.New("secrets file cannot be decrypted with this key")
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("decoding secrets file: %w", err)
+	}
+	return values, nil
+}
+
+// persistLocked chiffre et écrit le coffre de manière atomique ; l'appelant détient le verrou
+func (s *Store) persistLocked() error {
+	plain, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data := s.aead.Seal(nonce, nonce, plain, nil)
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".secrets-*")
+	if err != nil {
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime().UnixNano()
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestStore crée un coffre et sa clé dans un répertoire temporaire
+func newTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	keyFile := filepath.Join(dir, "secrets.key")
+	os.WriteFile(keyFile, []byte(key+"\n"), 0o600)
+
+	store, err := NewStore(filepath.Join(dir, "secrets.enc"), keyFile)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	return store, keyFile
+}
+
+func TestStoreSetGetDelete(t *testing.T) {
+	store, keyFile := newTestStore(t)
+
+	if err := store.Set("idm_token", "s3cr3t-value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if value, err := store.Get("idm_token"); err != nil || value != "s3cr3t-value" {
+		t.Errorf("Get() = %q, %v; want the stored value", value, err)
+	}
+
+	data, _ := os.ReadFile(store.path)
+	if strings.Contains(string(data), "s3cr3t-value") || strings.Contains(string(data), "idm_token") {
+		t.Error("secrets file contains plaintext data")
+	}
+
+	// Un second processus (CLI) voit la valeur écrite par le premier
+	reopened, err := NewStore(store.path, keyFile)
+	if err != nil {
+		t.Fatalf("NewStore() reopen error = %v", err)
+	}
+	if names, _ := reopened.Names(); len(names) != 1 || names[0] != "idm_token" {
+		t.Errorf("Names() = %v, want [idm_token]", names)
+	}
+
+	if err := reopened.Delete("idm_token"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := reopened.Get("idm_token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after delete error = %v, want ErrNotFound", err)
+	}
+	if err := reopened.Delete("idm_token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() missing secret error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	store, _ := newTestStore(t)
+	store.Set("idm_token", "s3cr3t-value")
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"invalid name", func() error { return store.Set("idm token", "value") }},
+		{"empty value", func() error { return store.Set("idm_token", "") }},
+		{"missing key file", func() error {
+			_, err := NewStore(store.path, filepath.Join(t.TempDir(), "missing.key"))
+			return err
+		}},
+		{"malformed key", func() error {
+			keyFile := filepath.Join(t.TempDir(), "short.key")
+			os.WriteFile(keyFile, []byte("c2hvcnQ="), 0o600)
+			_, err := NewStore(store.path, keyFile)
+			return err
+		}},
+		{"wrong key", func() error {
+			key, _ := GenerateKey()
+			keyFile := filepath.Join(t.TempDir(), "other.key")
+			os.WriteFile(keyFile, []byte(key), 0o600)
+			_, err := NewStore(store.path, keyFile)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
@@ -1,36 +1,36 @@
 package utils
 
 import (
+	"fmt"
 	"math/rand"
 	"net"
-	"os"
 	"strconv"
 )
 
 // FindAvailablePort trouve un port disponible selon la logique de l'application :
-// 1. Utilise la variable d'environnement PORT si définie
-// 2. Essaie le port 8001 par défaut
-// 3. Si occupé, cherche un port libre dans la plage 8001-8015
-func FindAvailablePort() (string, error) {
-	port := os.Getenv("PORT")
+// 1. Utilise le port configuré s'il est défini
+// 2. Essaie le premier port de la plage
+// 3. Si occupé, cherche un port libre dans la plage rangeStart-rangeEnd
+func FindAvailablePort(port string, rangeStart, rangeEnd int) (string, error) {
 	if port != "" {
 		return port, nil
 	}
 
-	port = "8001"
+	port = strconv.Itoa(rangeStart)
 	if isPortAvailable(port) {
 		return port, nil
 	}
 
-	for i := 0; i < 15; i++ {
-		tryPort := 8001 + rand.Intn(15)
+	size := rangeEnd - rangeStart + 1
+	for i := 0; i < size; i++ {
+		tryPort := rangeStart + rand.Intn(size)
 		portStr := strconv.Itoa(tryPort)
 		if isPortAvailable(portStr) {
 			return portStr, nil
 		}
 	}
 
-	return "", ErrNoPortAvailable
+	return "", fmt.Errorf("%w dans la plage %d-%d", ErrNoPortAvailable, rangeStart, rangeEnd)
 }
 
 func isPortAvailable(port string) bool {
@@ -44,7 +44,7 @@ func isPortAvailable(port string) bool {
 }
 
 // ErrNoPortAvailable est l'erreur retournée quand aucun port n'est disponible
-var ErrNoPortAvailable = &PortError{message: "aucun port disponible dans la plage 8001-8015"}
+var ErrNoPortAvailable = &PortError{message: "aucun port disponible"}
 
 // PortError représente une erreur liée à la gestion des ports
 type PortError struct {
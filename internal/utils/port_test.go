@@ -1,8 +1,8 @@
 package utils
 
 import (
+	"errors"
 	"net"
-	"os"
 	"strconv"
 	"testing"
 )
@@ -10,34 +10,27 @@ import (
 func TestFindAvailablePort(t *testing.T) {
 	tests := []struct {
 		name        string
-		envPort     string
+		port        string
 		expectError bool
 		setup       func() func() // setup function that returns cleanup function
 	}{
 		{
-			name:        "should use environment PORT when set",
-			envPort:     "9999",
+			name:        "should use configured port when set",
+			port:        "9999",
 			expectError: false,
-			setup: func() func() {
-				os.Setenv("PORT", "9999")
-				return func() { os.Unsetenv("PORT") }
-			},
+			setup:       func() func() { return func() {} },
 		},
 		{
 			name:        "should find default port 8001 when available",
-			envPort:     "",
+			port:        "",
 			expectError: false,
-			setup: func() func() {
-				os.Unsetenv("PORT")
-				return func() {}
-			},
+			setup:       func() func() { return func() {} },
 		},
 		{
 			name:        "should find alternative port when 8001 is busy",
-			envPort:     "",
+			port:        "",
 			expectError: false,
 			setup: func() func() {
-				os.Unsetenv("PORT")
 				// Occupy port 8001
 				ln, err := net.Listen("tcp", ":8001")
 				if err != nil {
@@ -54,7 +47,7 @@ func TestFindAvailablePort(t *testing.T) {
 			cleanup := tt.setup()
 			defer cleanup()
 
-			port, err := FindAvailablePort()
+			port, err := FindAvailablePort(tt.port, 8001, 8015)
 
 			if tt.expectError && err == nil {
 				t.Errorf("FindAvailablePort() expected error but got none")
@@ -74,15 +67,28 @@ func TestFindAvailablePort(t *testing.T) {
 					t.Errorf("FindAvailablePort() returned non-numeric port: %s", port)
 				}
 
-				// If environment variable was set, should return that value
-				if tt.envPort != "" && port != tt.envPort {
-					t.Errorf("FindAvailablePort() = %s, want %s", port, tt.envPort)
+				// If a port was configured, should return that value
+				if tt.port != "" && port != tt.port {
+					t.Errorf("FindAvailablePort() = %s, want %s", port, tt.port)
 				}
 			}
 		})
 	}
 }
 
+func TestFindAvailablePortExhaustedRange(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Skip("Could not occupy a test port")
+	}
+	defer ln.Close()
+
+	busy := ln.Addr().(*net.TCPAddr).Port
+	if _, err := FindAvailablePort("", busy, busy); !errors.Is(err, ErrNoPortAvailable) {
+		t.Errorf("FindAvailablePort() error = %v, want ErrNoPortAvailable when the whole range is busy", err)
+	}
+}
+
 func TestIsPortAvailable(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -139,10 +145,8 @@ func TestPortError(t *testing.T) {
 }
 
 func BenchmarkFindAvailablePort(b *testing.B) {
-	os.Unsetenv("PORT")
-
 	for i := 0; i < b.N; i++ {
-		_, err := FindAvailablePort()
+		_, err := FindAvailablePort("", 8001, 8015)
 		if err != nil {
 			b.Fatalf("FindAvailablePort() failed: %v", err)
 		}
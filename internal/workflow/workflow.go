@@ -0,0 +1,233 @@
+package workflow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/logging"
+	"go-form-app/internal/scripts"
+)
+
+// ErrNotFound est retournée pour un workflow absent de la configuration
+var ErrNotFound = errors.New("workflow not found")
+
+// Runner exécute les workflows configurés : les étapes s'enchaînent une à une pour
+// l'utilisateur cible, et l'exécution complète est conservée dans un seul enregistrement
+// d'historique qui référence l'exécution de chaque étape
+type Runner struct {
+	jobs      *jobs.Manager
+	workflows map[string]config.WorkflowConfig
+	logger    *slog.Logger
+	wg        sync.WaitGroup
+}
+
+// NewRunner crée un Runner pour les workflows donnés (validés par la configuration)
+func NewRunner(manager *jobs.Manager, workflows map[string]config.WorkflowConfig, logger *slog.Logger) *Runner {
+	return &Runner{
+		jobs:      manager,
+		workflows: workflows,
+		logger:    logger,
+	}
+}
+
+// Names retourne les noms des workflows par ordre alphabétique
+func (r *Runner) Names() []string {
+	names := make([]string, 0, len(r.workflows))
+	for name := range r.workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get retourne la définition d'un workflow
+func (r *Runner) Get(name string) (config.WorkflowConfig, bool) {
+	workflow, ok := r.workflows[name]
+	return workflow, ok
+}
+
+// Start valide la demande pour chaque étape puis exécute le workflow en arrière-plan ;
+// l'exécution conserve les valeurs de ctx (identifiant de requête) mais pas son annulation
+func (r *Runner) Start(ctx context.Context, name, userID, operator string) (history.Record, error) {
+	workflow, ok := r.workflows[name]
+	if !ok {
+		return history.Record{}, ErrNotFound
+	}
+	for _, step := range workflow.Steps {
+		if err := r.jobs.Validate(scripts.ExecutionRequest{UserID: userID, Script: step.Script}); err != nil {
+			return history.Record{}, err
+		}
+	}
+
+	now := time.Now()
+	rec := history.Record{
+		ID:        newID(),
+		RequestID: logging.RequestID(ctx),
+		Workflow:  name,
+		UserID:    userID,
+		Operator:  operator,
+		Source:    jobs.SourceWorkflow,
+		Status:    history.StatusRunning,
+		CreatedAt: now,
+		StartedAt: now,
+		Steps:     make([]history.StepResult, 0, len(workflow.Steps)),
+	}
+	for _, step := range workflow.Steps {
+		rec.Steps = append(rec.Steps, history.StepResult{
+			Name:           step.Name,
+			Script:         step.Script,
+			Status:         history.StatusQueued,
+			RollbackScript: step.Rollback,
+		})
+	}
+	r.save(ctx, rec)
+
+	ctx = logging.WithAttrs(context.WithoutCancel(ctx), "workflow_execution_id", rec.ID)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(ctx, workflow, rec)
+	}()
+
+	return rec, nil
+}
+
+// Wait attend la fin des workflows en cours d'exécution
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// run exécute les étapes dans l'ordre de déclaration ; une étape dont une dépendance
+// n'a pas réussi est ignorée. En cas d'échec, les étapes réussies sont compensées.
+func (r *Runner) run(ctx context.Context, workflow config.WorkflowConfig, rec history.Record) {
+	r.logger.InfoContext(ctx, "workflow started", logging.KeyCategory, logging.CategoryExecution,
+		"workflow", rec.Workflow, logging.KeyUserID, rec.UserID, "steps", len(workflow.Steps))
+
+	statuses := make(map[string]history.Status)
+	failed := false
+	for i, step := range workflow.Steps {
+		result := rec.Steps[i]
+		if dependenciesSucceeded(workflow.Dependencies(i), statuses) {
+			result.Status = history.StatusRunning
+			rec = r.update(ctx, rec, i, result)
+
+			execution, err := r.jobs.Run(ctx, scripts.ExecutionRequest{
+				UserID:         rec.UserID,
+				Script:         step.Script,
+				Operator:       rec.Operator,
+				IdempotencyKey: fmt.Sprintf("workflow:%s:%s", rec.ID, step.Name),
+			}, jobs.SourceWorkflow)
+			result.ExecutionID = execution.ID
+			result.ExitCode = execution.ExitCode
+			result.Error = execution.Error
+			result.Duration = execution.Duration
+			result.Status = execution.Status
+			if !result.Status.Finished() {
+				result.Status = history.StatusFailed
+			}
+			if err != nil && result.Error == "" {
+				result.Error = err.Error()
+			}
+		} else {
+			result.Status = history.StatusSkipped
+		}
+
+		statuses[step.Name] = result.Status
+		if result.Status != history.StatusSucceeded && result.Status != history.StatusSkipped {
+			failed = true
+			if rec.Error == "" {
+				rec.Error = fmt.Sprintf("step %s %s", step.Name, result.Status)
+			}
+		}
+		rec = r.update(ctx, rec, i, result)
+	}
+
+	if failed {
+		rec = r.rollback(ctx, rec)
+	}
+
+	rec.FinishedAt = time.Now()
+	rec.Duration = rec.FinishedAt.Sub(rec.StartedAt)
+	rec.Success = !failed
+	rec.Status = history.StatusSucceeded
+	if failed {
+		rec.Status = history.StatusFailed
+	}
+	r.save(ctx, rec)
+
+	r.logger.InfoContext(ctx, "workflow completed", logging.KeyCategory, logging.CategoryExecution,
+		"workflow", rec.Workflow, logging.KeyUserID, rec.UserID, "status", rec.Status, "duration_ms", rec.Duration.Milliseconds())
+}
+
+// rollback lance, en ordre inverse, le script de compensation des étapes réussies
+func (r *Runner) rollback(ctx context.Context, rec history.Record) history.Record {
+	for i := len(rec.Steps) - 1; i >= 0; i-- {
+		result := rec.Steps[i]
+		if result.Status != history.StatusSucceeded || result.RollbackScript == "" {
+			continue
+		}
+
+		r.logger.WarnContext(ctx, "rolling back workflow step", logging.KeyCategory, logging.CategoryExecution,
+			"workflow", rec.Workflow, "step", result.Name, logging.KeyScript, result.RollbackScript)
+		execution, _ := r.jobs.Run(ctx, scripts.ExecutionRequest{
+			UserID:         rec.UserID,
+			Script:         result.RollbackScript,
+			Operator:       rec.Operator,
+			IdempotencyKey: fmt.Sprintf("workflow:%s:%s:rollback", rec.ID, result.Name),
+		}, jobs.SourceWorkflow)
+		result.RollbackExecutionID = execution.ID
+		result.RollbackStatus = history.StatusFailed
+		if execution.Status == history.StatusSucceeded {
+			result.RollbackStatus = history.StatusSucceeded
+		}
+		rec = r.update(ctx, rec, i, result)
+	}
+	return rec
+}
+
+// update enregistre le résultat d'une étape ; les étapes sont copiées pour que les
+// lecteurs de l'historique ne voient jamais une version en cours de modification
+func (r *Runner) update(ctx context.Context, rec history.Record, i int, result history.StepResult) history.Record {
+	steps := append([]history.StepResult(nil), rec.Steps...)
+	steps[i] = result
+	rec.Steps = steps
+	r.save(ctx, rec)
+	return rec
+}
+
+// save persiste l'enregistrement sans interrompre le workflow en cas d'échec
+func (r *Runner) save(ctx context.Context, rec history.Record) {
+	if err := r.jobs.Store().Save(rec); err != nil {
+		r.logger.ErrorContext(ctx, "failed to save workflow execution", logging.KeyCategory, logging.CategoryHistory,
+			"execution_id", rec.ID, "error", err)
+	}
+}
+
+// dependenciesSucceeded indique si toutes les étapes requises ont réussi
+func dependenciesSucceeded(needs []string, statuses map[string]history.Status) bool {
+	for _, need := range needs {
+		if statuses[need] != history.StatusSucceeded {
+			return false
+		}
+	}
+	return true
+}
+
+// newID génère un identifiant d'exécution de workflow aléatoire
+func newID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return hex.EncodeToString(bytes)
+}
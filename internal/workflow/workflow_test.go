@@ -0,0 +1,164 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/history"
+	"go-form-app/internal/jobs"
+	"go-form-app/internal/jobs/jobstest"
+	"go-form-app/internal/logging"
+)
+
+// newTestRunner crée un Runner avec des scripts bash temporaires : create.sh, grant.sh et
+// notify.sh réussissent, fail.sh échoue, undo.sh compense
+func newTestRunner(t *testing.T, workflows map[string]config.WorkflowConfig) (*Runner, *jobs.Manager) {
+	t.Helper()
+
+	manager := jobstest.NewManager(t, map[string]string{
+		"create.sh": "echo created $1",
+		"grant.sh":  "echo granted $1",
+		"notify.sh": "echo notified $1",
+		"fail.sh":   "echo failing $1 >&2; exit 1",
+		"undo.sh":   "echo undone $1",
+	})
+	return NewRunner(manager, workflows, logging.New(os.Stdout, slog.LevelDebug)), manager
+}
+
+// runWorkflow démarre un workflow et retourne son enregistrement final
+func runWorkflow(t *testing.T, runner *Runner, manager *jobs.Manager, name string) history.Record {
+	t.Helper()
+
+	started, err := runner.Start(context.Background(), name, "test123", "jdupont")
+	if err != nil {
+		t.Fatalf("Start(%s) error = %v", name, err)
+	}
+	if started.Status != history.StatusRunning || len(started.Steps) == 0 {
+		t.Fatalf("Start(%s) = %+v, want a running record with its steps", name, started)
+	}
+	runner.Wait()
+
+	rec, ok := manager.Store().Get(started.ID)
+	if !ok {
+		t.Fatalf("workflow execution %s missing from history", started.ID)
+	}
+	return rec
+}
+
+// stepStatuses résume le statut de chaque étape, et celui de sa compensation s'il y en a une
+func stepStatuses(rec history.Record) string {
+	statuses := make([]string, 0, len(rec.Steps))
+	for _, step := range rec.Steps {
+		status := step.Name + "=" + string(step.Status)
+		if step.RollbackStatus != "" {
+			status += "/rollback=" + string(step.RollbackStatus)
+		}
+		statuses = append(statuses, status)
+	}
+	return strings.Join(statuses, " ")
+}
+
+func TestRunnerWorkflows(t *testing.T) {
+	workflows := map[string]config.WorkflowConfig{
+		"onboarding": {Steps: []config.WorkflowStep{
+			{Name: "create", Script: "create.sh", Rollback: "undo.sh"},
+			{Name: "grant", Script: "grant.sh"},
+			{Name: "notify", Script: "notify.sh"},
+		}},
+		"failing": {Steps: []config.WorkflowStep{
+			{Name: "create", Script: "create.sh", Rollback: "undo.sh"},
+			{Name: "grant", Script: "grant.sh", Rollback: "undo.sh"},
+			{Name: "fail", Script: "fail.sh", Rollback: "undo.sh"},
+			{Name: "notify", Script: "notify.sh"},
+		}},
+		"branches": {Steps: []config.WorkflowStep{
+			{Name: "create", Script: "create.sh"},
+			{Name: "fail", Script: "fail.sh", Needs: []string{"create"}},
+			{Name: "grant", Script: "grant.sh", Needs: []string{"create"}},
+			{Name: "notify", Script: "notify.sh", Needs: []string{"fail", "grant"}},
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		workflow   string
+		wantStatus history.Status
+		wantSteps  string
+	}{
+		{"all steps succeed", "onboarding", history.StatusSucceeded,
+			"create=succeeded grant=succeeded notify=succeeded"},
+		{"failure skips the rest and rolls back", "failing", history.StatusFailed,
+			"create=succeeded/rollback=succeeded grant=succeeded/rollback=succeeded fail=failed notify=skipped"},
+		{"independent branch continues", "branches", history.StatusFailed,
+			"create=succeeded fail=failed grant=succeeded notify=skipped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, manager := newTestRunner(t, workflows)
+			rec := runWorkflow(t, runner, manager, tt.workflow)
+
+			if rec.Status != tt.wantStatus || rec.Workflow != tt.workflow || rec.Source != jobs.SourceWorkflow || rec.FinishedAt.IsZero() {
+				t.Errorf("workflow record = %+v, want %s", rec, tt.wantStatus)
+			}
+			if got := stepStatuses(rec); got != tt.wantSteps {
+				t.Errorf("steps = %s, want %s", got, tt.wantSteps)
+			}
+
+			for _, step := range rec.Steps {
+				if step.ExecutionID == "" {
+					continue
+				}
+				execution, ok := manager.Store().Get(step.ExecutionID)
+				if !ok || execution.Script != step.Script || execution.Operator != "jdupont" || execution.IdempotencyKey != "workflow:"+rec.ID+":"+step.Name {
+					t.Errorf("step %s execution = %+v, want it linked to the workflow", step.Name, execution)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerRollbackOrder(t *testing.T) {
+	runner, manager := newTestRunner(t, map[string]config.WorkflowConfig{
+		"failing": {Steps: []config.WorkflowStep{
+			{Name: "first", Script: "create.sh", Rollback: "undo.sh"},
+			{Name: "second", Script: "grant.sh", Rollback: "undo.sh"},
+			{Name: "last", Script: "fail.sh"},
+		}},
+	})
+	rec := runWorkflow(t, runner, manager, "failing")
+
+	first, _ := manager.Store().Get(rec.Steps[0].RollbackExecutionID)
+	second, _ := manager.Store().Get(rec.Steps[1].RollbackExecutionID)
+	if first.Script != "undo.sh" || second.Script != "undo.sh" || second.StartedAt.After(first.StartedAt) {
+		t.Errorf("rollbacks = %+v then %+v, want the second step compensated first", second, first)
+	}
+	if rec.Error != "step last failed" {
+		t.Errorf("workflow error = %q, want the failing step", rec.Error)
+	}
+}
+
+func TestRunnerStartValidation(t *testing.T) {
+	runner, _ := newTestRunner(t, map[string]config.WorkflowConfig{
+		"onboarding": {Steps: []config.WorkflowStep{{Name: "create", Script: "create.sh"}}},
+		"forbidden":  {Steps: []config.WorkflowStep{{Name: "other", Script: "other.sh"}}},
+	})
+
+	if _, err := runner.Start(context.Background(), "missing", "test123", "jdupont"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Start() unknown workflow error = %v, want ErrNotFound", err)
+	}
+	if _, err := runner.Start(context.Background(), "onboarding", "bad", "jdupont"); err == nil {
+		t.Error("Start() with an invalid user error = nil, want an error")
+	}
+	if _, err := runner.Start(context.Background(), "forbidden", "test123", "jdupont"); err == nil {
+		t.Error("Start() with a script outside the whitelist error = nil, want an error")
+	}
+	if names := runner.Names(); strings.Join(names, ",") != "forbidden,onboarding" {
+		t.Errorf("Names() = %v, want sorted names", names)
+	}
+}
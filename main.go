@@ -1,22 +1,78 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	httpserver "go-form-app/cmd/server/http"
+	"go-form-app/internal/config"
+	"go-form-app/internal/logging"
 	"go-form-app/internal/utils"
 )
 
 func main() {
-	port, err := utils.FindAvailablePort()
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		os.Exit(runTokenCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "secret" {
+		os.Exit(runSecretCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		log.Fatalf("Erreur lors de la recherche de port: %v", err)
+		log.Fatalf("Configuration error: %v", err)
 	}
 
-	server := httpserver.NewServer()
+	logger := logging.New(os.Stdout, cfg.Log.SlogLevel())
+	slog.SetDefault(logger)
+
+	port, err := utils.FindAvailablePort(cfg.Server.Port, cfg.Server.PortRangeStart, cfg.Server.PortRangeEnd)
+	if err != nil {
+		logger.Error("no available port", "error", err)
+		os.Exit(1)
+	}
+
+	server, err := httpserver.NewServer(cfg, logger)
+	if err != nil {
+		logger.Error("invalid server configuration", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("starting Go Form App", "port", port)
+		serverErr <- server.Start(port)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server failed to start", "error", err)
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		stop()
+		logger.Info("shutdown signal received, waiting for running scripts",
+			logging.KeyCategory, logging.CategoryShutdown, "timeout", cfg.Server.ShutdownTimeout.String())
 
-	log.Printf("Starting Go Form App on port %s", port)
-	if err := server.Start(port); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("shutdown failed", logging.KeyCategory, logging.CategoryShutdown, "error", err)
+		}
+		logger.Info("server stopped", logging.KeyCategory, logging.CategoryShutdown)
 	}
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go-form-app/internal/config"
+	"go-form-app/internal/secrets"
+)
+
+// runSecretCommand implémente la CLI d'administration du coffre de secrets ; le coffre et
+// sa clé sont ceux du serveur (secrets_file, secrets_key_file, variables d'environnement ou
+// options de configuration). La valeur d'un secret est lue sur l'entrée standard pour ne
+// pas apparaître dans l'historique du shell :
+//
+//	go-form-app secret keygen > /run/secrets/go-form-app.key
+//	printf '%s' "$TOKEN" | go-form-app secret set idm_token
+//	go-form-app secret -config /etc/go-form-app.yaml list
+//	go-form-app secret delete idm_token
+func runSecretCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, args, err := config.LoadCommand("go-form-app secret", args, os.Getenv, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Configuration error: %v\n", err)
+		return 2
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: go-form-app secret [config options] <keygen|set|list|delete> [name]")
+		return 2
+	}
+
+	if args[0] == "keygen" {
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, key)
+		return 0
+	}
+
+	if cfg.SecretsFile == "" {
+		fmt.Fprintln(stderr, "secrets_file (SECRETS_FILE) and secrets_key_file (SECRETS_KEY_FILE) must be set")
+		return 2
+	}
+
+	store, err := secrets.NewStore(cfg.SecretsFile, cfg.SecretsKeyFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "set":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: go-form-app secret set <name> < value")
+			return 2
+		}
+		value, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := store.Set(args[1], strings.TrimRight(value, "\r\n")); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Secret %s stored\n", args[1])
+		return 0
+	case "list":
+		names, err := store.Names()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		for _, name := range names {
+			fmt.Fprintln(stdout, name)
+		}
+		return 0
+	case "delete":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: go-form-app secret delete <name>")
+			return 2
+		}
+		if err := store.Delete(args[1]); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Secret %s deleted\n", args[1])
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown secret command %q\n", args[0])
+		return 2
+	}
+}
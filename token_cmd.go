@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-form-app/internal/auth"
+	"go-form-app/internal/config"
+)
+
+// runTokenCommand implémente la CLI d'administration des tokens d'API ; le fichier des
+// tokens est celui du serveur (api_tokens_file, API_TOKENS_FILE, options de configuration) :
+//
+//	go-form-app token issue -name ticketing -scripts script1.py -ttl 2160h
+//	go-form-app token -config /etc/go-form-app.yaml list
+//	go-form-app token revoke <id>
+func runTokenCommand(args []string, stdout, stderr io.Writer) int {
+	cfg, args, err := config.LoadCommand("go-form-app token", args, os.Getenv, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Configuration error: %v\n", err)
+		return 2
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: go-form-app token [config options] <issue|list|revoke> [options]")
+		return 2
+	}
+	if cfg.APITokensFile == "" {
+		fmt.Fprintln(stderr, "api_tokens_file (API_TOKENS_FILE) must be set")
+		return 2
+	}
+
+	store, err := auth.NewTokenStore(cfg.APITokensFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "issue":
+		return issueToken(store, args[1:], stdout, stderr)
+	case "list":
+		return listTokens(store, stdout)
+	case "revoke":
+		if len(args) != 2 {
+			fmt.Fprintln(stderr, "usage: go-form-app token revoke <id>")
+			return 2
+		}
+		if err := store.Revoke(args[1]); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Token %s revoked\n", args[1])
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown token command %q\n", args[0])
+		return 2
+	}
+}
+
+// issueToken crée un token et affiche le secret une seule fois
+func issueToken(store *auth.TokenStore, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("token issue", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	name := flags.String("name", "", "nom du token (ex: ticketing)")
+	kind := flags.String("kind", auth.KindService, "type de token: personal ou service")
+	owner := flags.String("owner", "", "propriétaire ou équipe responsable")
+	scripts := flags.String("scripts", "", "scripts autorisés séparés par des virgules (* pour tous)")
+	ttl := flags.Duration("ttl", 90*24*time.Hour, "durée de validité")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var scopes []string
+	for _, script := range strings.Split(*scripts, ",") {
+		if script = strings.TrimSpace(script); script != "" {
+			scopes = append(scopes, script)
+		}
+	}
+
+	secret, token, err := store.Issue(*name, *kind, *owner, scopes, *ttl)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Token %s issued for %s (expires %s)\n", token.ID, token.Name, token.ExpiresAt.Format(time.RFC3339))
+	fmt.Fprintf(stdout, "Secret (shown only once): %s\n", secret)
+	return 0
+}
+
+// listTokens affiche les tokens sans leur secret
+func listTokens(store *auth.TokenStore, stdout io.Writer) int {
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tKIND\tOWNER\tSCRIPTS\tEXPIRES\tSTATUS")
+
+	for _, token := range store.List() {
+		status := "active"
+		if token.RevokedAt != nil {
+			status = "revoked"
+		} else if time.Now().After(token.ExpiresAt) {
+			status = "expired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			token.ID, token.Name, token.Kind, token.Owner,
+			strings.Join(token.Scripts, ","), token.ExpiresAt.Format(time.RFC3339), status)
+	}
+
+	w.Flush()
+	return 0
+}